@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/remediate"
+	"github.com/spf13/cobra"
+)
+
+var dryRun string
+
+// applyFixCmd represents the apply-fix command
+var applyFixCmd = &cobra.Command{
+	Use:   "apply-fix <dir>",
+	Short: "Apply fixes written by ruleaudit --fix-out",
+	Long: `Applies the replacement manifests and JSON patches written by ruleaudit --fix-out to
+the cluster. By default the changes are only validated with a server-side dry run; use
+--dry-run=none to persist them.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if dryRun != "server" && dryRun != "none" {
+			log.Fatalf("Error: invalid --dry-run %q, must be server or none", dryRun)
+		}
+		fixes, err := remediate.ReadDir(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		client, err := k8s.NewClient(kubeconfig)
+		if err != nil {
+			log.Fatalf("Error: failed to create Kubernetes client: %v", err)
+		}
+
+		style := newTermStyle(os.Stdout)
+		failed := 0
+		for _, fix := range fixes {
+			target := fix.Kind + "/" + fix.Name
+			if fix.Namespace != "" {
+				target = fix.Kind + "/" + fix.Namespace + "/" + fix.Name
+			}
+			if fix.HasPlaceholder() {
+				fmt.Printf("%s%s: skipped, edit %s to replace %s first\n", style.icon("⏭️ "), target, fix.File, remediate.Placeholder)
+				continue
+			}
+
+			if fix.Type == remediate.TypeJSONPatch {
+				err = client.PatchRBACObject(cmd.Context(), fix.Kind, fix.Namespace, fix.Name, fix.Data, dryRun == "server")
+			} else {
+				err = client.ReplaceRBACObject(cmd.Context(), fix.Data, dryRun == "server")
+			}
+			switch {
+			case err != nil:
+				failed++
+				fmt.Printf("%s%s: %v\n", style.icon("❌"), target, err)
+			case dryRun == "server":
+				fmt.Printf("%s%s: accepted by the server (dry run)\n", style.icon("✅"), target)
+			default:
+				fmt.Printf("%s%s: applied\n", style.icon("✅"), target)
+			}
+		}
+		if failed > 0 {
+			log.Fatalf("Error: %d of %d fixes failed", failed, len(fixes))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyFixCmd)
+	applyFixCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	applyFixCmd.Flags().StringVar(&dryRun, "dry-run", "server", "server to validate the fixes without persisting them, or none to apply them")
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/flushthemoney/RBACLens/internal/compliance"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var frameworkID string
+
+// complianceCmd represents the compliance command
+var complianceCmd = &cobra.Command{
+	Use:   "compliance",
+	Short: "Report compliance with a security benchmark",
+	Long: `Audits RBAC resources and reports pass or fail for each control of a security
+benchmark, such as the RBAC section of the CIS Kubernetes Benchmark or the
+NSA/CISA Kubernetes Hardening Guide.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := resolveFormat(cmd, "table", "table", "json", "yaml")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		framework, err := compliance.Lookup(frameworkID)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		var resources *types.RBACResources
+		if inputFile != "" {
+			resources, err = loadSnapshot(inputFile)
+		} else {
+			resources, err = fetchSnapshot(cmd.Context(), kubeconfig, namespace, clusterName)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		result := compliance.Assess(framework, *resources, options)
+
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			if format == "table" {
+				printComplianceReport(w, result)
+				return nil
+			}
+			return encodeData(w, format, result)
+		})
+		if err != nil {
+			log.Fatalf("Failed to write compliance report: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(complianceCmd)
+	complianceCmd.Flags().StringVar(&frameworkID, "framework", "cis-1.9", "Framework to report on: cis-1.9 or nsa-cisa")
+	complianceCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	complianceCmd.Flags().StringVar(&namespace, "namespace", "", "Namespaces to audit (comma-separated)")
+	complianceCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	complianceCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table, json or yaml")
+	complianceCmd.Flags().StringVar(&inputFile, "input", "", "Path to a previously saved RBAC resources JSON file to audit")
+	complianceCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in the report (defaults to the kubeconfig current-context cluster)")
+	complianceCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in the assessment")
+	addCheckSelectionFlags(complianceCmd)
+}
+
+// printComplianceReport prints a per-control compliance report to the console
+func printComplianceReport(w io.Writer, report compliance.Report) {
+	style := newTermStyle(w)
+
+	fmt.Fprintf(w, "%s%s\n", style.icon("📜"), style.paint(ansiBold, report.Name))
+	if report.Metadata.ClusterName != "" {
+		fmt.Fprintf(w, "   Cluster: %s\n", report.Metadata.ClusterName)
+	}
+	if !report.Metadata.Timestamp.IsZero() {
+		fmt.Fprintf(w, "   Snapshot: %s\n", report.Metadata.Timestamp.Format("2006-01-02 15:04:05 MST"))
+	}
+	fmt.Fprintln(w)
+
+	for _, control := range report.Controls {
+		fmt.Fprintf(w, "%s  %-8s %s\n", controlStatus(style, control.Status), control.ID, control.Title)
+		if control.Note != "" {
+			fmt.Fprintf(w, "          └─ %s\n", control.Note)
+		}
+		for _, finding := range control.Findings {
+			name := finding.ResourceName
+			if finding.Namespace != "" {
+				name = finding.Namespace + "/" + name
+			}
+			fmt.Fprintf(w, "          └─ [%s] %s/%s: %s\n", style.risk(finding.Risk), finding.ResourceKind, name, finding.Reason)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%sPassed: %d  Failed: %d  Manual: %d  Skipped: %d\n", style.icon("📊"),
+		report.Summary.Passed, report.Summary.Failed, report.Summary.Manual, report.Summary.Skipped)
+}
+
+// controlStatus renders a control status for display, e.g. "✅ PASS"
+func controlStatus(style termStyle, status string) string {
+	switch status {
+	case compliance.StatusPass:
+		return style.paint(ansiGreen, style.icon("✅")+"PASS  ")
+	case compliance.StatusFail:
+		return style.paint(ansiRed, style.icon("❌")+"FAIL  ")
+	case compliance.StatusManual:
+		return style.paint(ansiYellow, style.icon("📝")+"MANUAL")
+	default:
+		return style.paint(ansiGray, style.icon("⏭️ ")+"SKIP  ")
+	}
+}
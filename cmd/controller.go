@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/controller"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/spf13/cobra"
+)
+
+var debounce time.Duration
+var resyncInterval time.Duration
+
+// controllerCmd represents the controller command
+var controllerCmd = &cobra.Command{
+	Use:   "controller",
+	Short: "Publish audit results as PolicyReports, re-auditing on every RBAC change",
+	Long: `Runs RBACLens as a controller, usually in-cluster. It watches Roles, ClusterRoles,
+RoleBindings and ClusterRoleBindings through informers, re-audits the changed objects after
+each burst of changes and publishes the findings as wgpolicyk8s.io/v1alpha2 reports: a PolicyReport named
+rbaclens in each namespace with findings, and a ClusterPolicyReport named rbaclens for
+cluster-scoped objects. Only reports whose findings changed are written.
+
+The PolicyReport CRDs must be installed in the cluster. ServiceAccounts and workloads, used
+to refine risk scores, are refreshed every --resync.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		client, err := k8s.NewClient(kubeconfig)
+		if err != nil {
+			log.Fatalf("Error: failed to create Kubernetes client: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		metadata := client.GetMetadata(ctx, clusterName)
+		if namespace != "" {
+			metadata.Namespaces = []string{namespace}
+		}
+		c, err := controller.New(client, controller.Options{
+			Namespace: namespace,
+			Audit:     options,
+			Metadata:  metadata,
+			Debounce:  debounce,
+			Resync:    resyncInterval,
+		})
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Watching RBAC objects")
+		if err := c.Run(ctx); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(controllerCmd)
+	controllerCmd.Flags().DurationVar(&debounce, "debounce", 5*time.Second, "How long to wait for further changes before re-auditing")
+	controllerCmd.Flags().DurationVar(&resyncInterval, "resync", 10*time.Minute, "How often to refresh ServiceAccounts and workloads and re-audit; 0 disables resyncing")
+	controllerCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file (in-cluster config is used if omitted)")
+	controllerCmd.Flags().StringVar(&namespace, "namespace", "", "Namespace to watch Roles and RoleBindings in (all if omitted)")
+	controllerCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in audits (defaults to the kubeconfig current-context cluster)")
+	controllerCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in audit results")
+	controllerCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(controllerCmd)
+}
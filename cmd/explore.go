@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/explore"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// exploreCmd represents the explore command
+var exploreCmd = &cobra.Command{
+	Use:   "explore",
+	Short: "Browse subjects, roles, bindings and findings interactively",
+	Long: `Opens an interactive terminal UI over an RBAC snapshot and its audit findings. Subjects,
+roles, bindings and findings each have their own view, with search and filters by namespace
+and severity. Selecting a subject shows its bindings, the roles they refer to, its effective
+permissions and its findings; related entries can be opened from the details pane.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resources *types.RBACResources
+		var err error
+		if inputFile != "" {
+			resources, err = loadSnapshot(inputFile)
+		} else {
+			resources, err = fetchSnapshot(cmd.Context(), kubeconfig, namespace, clusterName)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		report := audit.AuditRBACResourcesWithOptions(*resources, options)
+
+		if err := explore.Run(*resources, report, options); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exploreCmd)
+	exploreCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	exploreCmd.Flags().StringVar(&namespace, "namespace", "", "Namespaces to explore (comma-separated)")
+	exploreCmd.Flags().StringVar(&inputFile, "input", "", "Path to a previously saved RBAC resources JSON file to explore")
+	exploreCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in the report (defaults to the kubeconfig current-context cluster)")
+	exploreCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components")
+	exploreCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(exploreCmd)
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/metrics"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var metricsAddr string
+var auditInterval time.Duration
+var textfilePath string
+
+// exporterCmd represents the exporter command
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Export audit results as Prometheus metrics",
+	Long: `Audits the cluster every --interval and exposes the results as Prometheus metrics on
+/metrics, for alerting when new high-severity findings appear.
+
+Metrics:
+  rbaclens_findings{severity,rule,namespace}   Findings of the latest audit
+  rbaclens_rbac_objects{kind}                  RBAC objects in the latest snapshot
+  rbaclens_subject_risk_score{subject}         Risk score of each subject with findings
+  rbaclens_last_audit_timestamp_seconds        Time of the latest audited snapshot
+  rbaclens_fetch_duration_seconds              Time taken by each fetch
+  rbaclens_fetches_total                       Fetch attempts
+  rbaclens_fetch_errors_total                  Failed fetches
+
+With --textfile, the metrics are also written to a .prom file after every audit for the
+node-exporter textfile collector. Set --listen "" to only write the file, and --interval 0
+to audit once and exit.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if metricsAddr == "" && textfilePath == "" {
+			log.Fatalf("Error: --listen or --textfile is required")
+		}
+		exporter := metrics.NewExporter()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		refresh := func(ctx context.Context) error {
+			var resources *types.RBACResources
+			var err error
+			if inputFile != "" {
+				resources, err = loadSnapshot(inputFile)
+			} else {
+				start := time.Now()
+				resources, err = fetchSnapshot(ctx, kubeconfig, namespace, clusterName)
+				exporter.ObserveFetch(time.Since(start), err)
+			}
+			if err == nil {
+				exporter.Update(*resources, audit.AuditRBACResourcesWithOptions(*resources, options))
+			}
+			if textfilePath != "" {
+				if err := exporter.WriteTextfile(textfilePath); err != nil {
+					log.Printf("Writing %s failed: %v", textfilePath, err)
+				}
+			}
+			return err
+		}
+		if err := refresh(ctx); err != nil {
+			if auditInterval == 0 {
+				log.Fatalf("Error: %v", err)
+			}
+			log.Printf("Refresh failed: %v", err)
+		}
+		if auditInterval == 0 && metricsAddr == "" {
+			return
+		}
+		if auditInterval > 0 {
+			go refreshEvery(ctx, auditInterval, refresh)
+		}
+		if metricsAddr == "" {
+			<-ctx.Done()
+			return
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", exporter.Handler())
+		mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok\n"))
+		})
+		httpServer := &http.Server{Addr: metricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdown)
+		}()
+		log.Printf("Serving metrics on %s/metrics", metricsAddr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exporterCmd)
+	exporterCmd.Flags().StringVar(&metricsAddr, "listen", ":9847", "Address to serve /metrics on, or empty to only write --textfile")
+	exporterCmd.Flags().DurationVar(&auditInterval, "interval", 5*time.Minute, "How often to re-audit; 0 audits once")
+	exporterCmd.Flags().StringVar(&textfilePath, "textfile", "", "Also write the metrics to this file for the node-exporter textfile collector (must end in .prom)")
+	exporterCmd.Flags().StringVar(&inputFile, "input", "", "Path to a saved RBAC resources file to audit instead of fetching from the cluster; re-read every --interval")
+	exporterCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	exporterCmd.Flags().StringVar(&namespace, "namespace", "", "Namespaces to audit (comma-separated)")
+	exporterCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in snapshots (defaults to the kubeconfig current-context cluster)")
+	exporterCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in audit results")
+	exporterCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(exporterCmd)
+}
@@ -2,14 +2,11 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
-	"os"
 	"strings"
-	"time"
 
-	"github.com/flushthemoney/RBACLens/internal/k8s"
 	"github.com/flushthemoney/RBACLens/internal/types"
 	"github.com/spf13/cobra"
 )
@@ -23,12 +20,18 @@ var fetchCmd = &cobra.Command{
 	Use:   "fetch",
 	Short: "Fetch RBAC resources from a Kubernetes cluster.",
 	Long: `Fetches Roles, ClusterRoles, RoleBindings, and ClusterRoleBindings from a Kubernetes cluster.
-You can save the results to a JSON file for further analysis.`,
+You can save the results to a JSON or YAML file, or stream them to stdout, for further analysis.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := fetchRBAC(kubeconfig, namespaces, jsonOut)
+		if jsonOut {
+			applyLegacyJSONOut(cmd, "rbac_resources.json")
+		}
+		format, err := resolveFormat(cmd, "table", "table", "json", "yaml")
 		if err != nil {
 			log.Fatalf("Error: %v", err)
 		}
+		if err := fetchRBAC(cmd.Context(), kubeconfig, namespaces, format, outputPath); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
 	},
 }
 
@@ -36,44 +39,66 @@ func init() {
 	rootCmd.AddCommand(fetchCmd)
 	fetchCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
 	fetchCmd.Flags().StringVar(&namespaces, "namespace", "", "Namespaces to audit")
+	fetchCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	fetchCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table, json or yaml")
 	fetchCmd.Flags().BoolVar(&jsonOut, "json-out", false, "Check to save RBAC details to JSON")
+	fetchCmd.Flags().MarkDeprecated("json-out", "use --format json --output <file> instead")
+	fetchCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in the snapshot (defaults to the kubeconfig current-context cluster)")
+	fetchCmd.Flags().BoolVar(&fullSnapshot, "full", false, "Keep complete objects, including managedFields and the last applied configuration, rather than a compact snapshot")
+	fetchCmd.Flags().StringVar(&storeDir, "store", "", "History store directory to add the snapshot to")
 }
 
-func fetchRBAC(kubeconfig string, namespace string, jsonOut bool) error {
-	clientset, err := k8s.NewClient(kubeconfig)
-	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %w", err)
-	}
-
-	resources, err := clientset.GetRBACResources(context.Background(), namespace)
+func fetchRBAC(ctx context.Context, kubeconfig string, namespace string, format string, output string) error {
+	resources, err := fetchSnapshot(ctx, kubeconfig, namespace, clusterName)
 	if err != nil {
-		return fmt.Errorf("failed to get RBAC resources: %w", err)
+		return err
 	}
-
-	if jsonOut {
-		// Populate metadata
-		meta := types.Metadata{
-			ClusterName: "my-cluster", // Placeholder
-			Timestamp:   time.Now(),
-		}
-		if namespace != "" {
-			meta.Namespaces = strings.Split(namespace, ",")
+	if storeDir != "" {
+		if err := storeRun(storeDir, resources, nil); err != nil {
+			return err
 		}
+	}
 
-		resources.Metadata = meta
-
-		jsonData, err := json.MarshalIndent(resources, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal data to JSON: %w", err)
-		}
-		filename := "rbac_resources.json"
-		err = os.WriteFile(filename, jsonData, 0644)
-		if err != nil {
-			return fmt.Errorf("failed to write json file: %w", err)
+	return writeOutput(output, func(w io.Writer) error {
+		if format == "table" {
+			printSnapshotSummary(w, resources)
+			return nil
 		}
+		return encodeData(w, format, resources)
+	})
+}
 
-		fmt.Printf("Successfully wrote wrote JSON to %s\n", filename)
+// applyLegacyJSONOut maps the deprecated --json-out flag onto --format and --output
+func applyLegacyJSONOut(cmd *cobra.Command, filename string) {
+	if !cmd.Flags().Changed("format") {
+		cmd.Flags().Set("format", "json")
+	}
+	if !cmd.Flags().Changed("output") {
+		cmd.Flags().Set("output", filename)
 	}
+}
 
-	return nil
+// printSnapshotSummary prints where a snapshot came from and how many resources it holds
+func printSnapshotSummary(w io.Writer, resources *types.RBACResources) {
+	meta := resources.Metadata
+	fmt.Fprintf(w, "Cluster:  %s", meta.ClusterName)
+	if meta.ServerVersion != "" {
+		fmt.Fprintf(w, " (%s)", meta.ServerVersion)
+	}
+	fmt.Fprintln(w)
+	if meta.Server != "" {
+		fmt.Fprintf(w, "Server:   %s\n", meta.Server)
+	}
+	if meta.FetchedBy != nil {
+		fmt.Fprintf(w, "User:     %s\n", meta.FetchedBy.Username)
+	}
+	if len(meta.Namespaces) > 0 {
+		fmt.Fprintf(w, "Namespaces: %s\n", strings.Join(meta.Namespaces, ", "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "KIND                  COUNT\n")
+	fmt.Fprintf(w, "ClusterRoles          %d\n", len(resources.ClusterRoles))
+	fmt.Fprintf(w, "Roles                 %d\n", len(resources.Roles))
+	fmt.Fprintf(w, "ClusterRoleBindings   %d\n", len(resources.ClusterRoleBindings))
+	fmt.Fprintf(w, "RoleBindings          %d\n", len(resources.RoleBindings))
 }
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/graph"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var onlyRisky bool
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render subjects, bindings, roles and resources as a graph",
+	Long: `Renders the RBAC model as a directed graph of subjects, the bindings that name them,
+the roles those bindings grant and the resources the roles allow, with the verbs on each
+role to resource edge. Nodes are coloured by the severity of their audit findings.
+
+Output formats are Graphviz DOT, Mermaid flowcharts for Markdown documents and GraphML
+for graph tools such as yEd, Gephi or Cytoscape. For graph databases, cypher writes
+Cypher statements and neo4j-csv writes neo4j-admin import files to the --output
+directory, modelling subjects, bindings, roles, rules, namespaces and resources as nodes
+with audit findings as properties.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := resolveFormat(cmd, "dot", "dot", "mermaid", "graphml", "cypher", "neo4j-csv")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if format == "neo4j-csv" && (outputPath == "" || outputPath == "-") {
+			log.Fatalf("Error: --format neo4j-csv requires --output to name a directory")
+		}
+		if err := normalizeSubjectFilter(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		var resources *types.RBACResources
+		if inputFile != "" {
+			resources, err = loadSnapshot(inputFile)
+		} else {
+			resources, err = fetchSnapshot(cmd.Context(), kubeconfig, namespace, clusterName)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		report := audit.AuditRBACResourcesWithOptions(*resources, options)
+
+		graphOptions := graph.Options{
+			Subjects:      subjectFilter,
+			OnlyRisky:     onlyRisky,
+			IncludeSystem: includeSystem,
+		}
+		if namespace != "" {
+			graphOptions.Namespaces = strings.Split(namespace, ",")
+		}
+
+		if format == "neo4j-csv" {
+			files, err := graph.WriteNeo4jCSV(outputPath, graph.BuildPropertyGraph(*resources, report, graphOptions))
+			if err != nil {
+				log.Fatalf("Failed to write graph: %v", err)
+			}
+			printNeo4jImport(outputPath, files)
+			return
+		}
+
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			switch format {
+			case "mermaid":
+				return graph.WriteMermaid(w, graph.Build(*resources, report, graphOptions))
+			case "graphml":
+				return graph.WriteGraphML(w, graph.Build(*resources, report, graphOptions))
+			case "cypher":
+				return graph.WriteCypher(w, graph.BuildPropertyGraph(*resources, report, graphOptions))
+			}
+			return graph.WriteDOT(w, graph.Build(*resources, report, graphOptions))
+		})
+		if err != nil {
+			log.Fatalf("Failed to write graph: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	graphCmd.Flags().StringVar(&namespace, "namespace", "", "Only graph RoleBindings in these namespaces (comma-separated)")
+	graphCmd.Flags().StringVar(&inputFile, "input", "", "Path to a previously saved RBAC resources JSON file to graph")
+	graphCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in the report (defaults to the kubeconfig current-context cluster)")
+	graphCmd.Flags().StringSliceVar(&subjectFilter, "subject", nil, "Only graph these subjects, e.g. User:alice, ServiceAccount:ns/name or system:serviceaccount:ns:name")
+	graphCmd.Flags().BoolVar(&onlyRisky, "only-risky", false, "Only graph bindings where the binding or its role has findings")
+	graphCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system subjects and bindings")
+	graphCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(graphCmd)
+	graphCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	graphCmd.Flags().StringVar(&outputFormat, "format", "dot", "Output format: dot, mermaid, graphml, cypher or neo4j-csv")
+}
+
+// printNeo4jImport tells where the CSV files were written and how to import them
+func printNeo4jImport(dir string, files []string) {
+	fmt.Fprintf(os.Stderr, "Wrote %d files to %s. Import them into an empty database with:\n", len(files), dir)
+	fmt.Fprint(os.Stderr, "  neo4j-admin database import full")
+	for _, f := range files {
+		flag := "--nodes"
+		if strings.HasPrefix(f, "relationships-") {
+			flag = "--relationships"
+		}
+		fmt.Fprintf(os.Stderr, " \\\n    %s=%s", flag, filepath.Join(dir, f))
+	}
+	fmt.Fprintln(os.Stderr, " \\\n    neo4j")
+}
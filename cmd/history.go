@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/history"
+	"github.com/flushthemoney/RBACLens/internal/store"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var storeDir string
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show how audit findings changed over time",
+	Long: `Reads the history store written by fetch --store and ruleaudit --store and shows the
+number of findings by severity in each run, when each finding first appeared and was
+resolved, and the mean time to remediate.
+
+Runs stored by fetch have no audit report; they are audited with the current checks and
+options.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if storeDir == "" {
+			log.Fatalf("Error: --store is required")
+		}
+		format, err := resolveFormat(cmd, "table", "table", "json", "yaml")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		s, err := store.Open(storeDir)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		entries, err := s.Entries()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		var runs []history.Run
+		for _, entry := range entries {
+			if entry.Report == nil {
+				resources, err := s.LoadSnapshot(entry.ID)
+				if err != nil {
+					log.Fatalf("Error: %v", err)
+				}
+				report := audit.AuditRBACResourcesWithOptions(*resources, options)
+				entry.Report = &report
+			}
+			runs = append(runs, history.Run{ID: entry.ID, Time: entry.Time, Report: *entry.Report})
+		}
+		report := history.Build(runs)
+
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			if format == "table" {
+				printHistory(w, newTermStyle(w), report)
+				return nil
+			}
+			return encodeData(w, format, report)
+		})
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVar(&storeDir, "store", "", "History store directory (required)")
+	historyCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	historyCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table, json or yaml")
+	historyCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components when auditing runs stored without a report")
+	historyCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(historyCmd)
+}
+
+// storeRun adds a snapshot, and its audit report if not nil, to the history store in dir
+func storeRun(dir string, resources *types.RBACResources, report *audit.AuditReport) error {
+	s, err := store.Open(dir)
+	if err != nil {
+		return err
+	}
+	id, err := s.AddSnapshot(*resources)
+	if err != nil {
+		return err
+	}
+	if report != nil {
+		if _, err := s.AddReport(*report); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Stored run %s in %s\n", id, dir)
+	return nil
+}
+
+func printHistory(w io.Writer, style termStyle, report history.Report) {
+	if len(report.Runs) == 0 {
+		fmt.Fprintln(w, "No runs in the store yet. Add some with fetch --store or ruleaudit --store.")
+		return
+	}
+
+	fmt.Fprintf(w, "%sFindings by Run:\n", style.icon("📈"))
+	fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "   RUN\tTOTAL\tCRITICAL\tHIGH\tMEDIUM\tLOW\tINFO")
+	for _, run := range report.Runs {
+		fmt.Fprintf(tw, "   %s\t%d\t%d\t%d\t%d\t%d\t%d\n", run.Time.Format("2006-01-02 15:04"), run.Total, run.Critical, run.High, run.Medium, run.Low, run.Info)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%sFindings: %d open, %d resolved\n", style.icon("🔍"), report.Open, report.Resolved)
+	fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "   STATUS\tRISK\tRULE\tOBJECT\tFIRST SEEN\tRESOLVED")
+	for _, f := range report.Findings {
+		status, resolved := "open", "-"
+		if f.Resolved != nil {
+			status, resolved = "resolved", f.Resolved.Format(time.DateOnly)
+		}
+		object := f.ResourceKind + "/" + f.ResourceName
+		if f.Namespace != "" {
+			object = f.ResourceKind + "/" + f.Namespace + "/" + f.ResourceName
+		}
+		fmt.Fprintf(tw, "   %s\t%s\t%s\t%s\t%s\t%s\n", status, f.Risk, f.RuleID, object, f.FirstSeen.Format(time.DateOnly), resolved)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	if report.Resolved == 0 {
+		fmt.Fprintf(w, "%sMean time to remediate: no findings resolved yet\n", style.icon("⏱️"))
+		return
+	}
+	fmt.Fprintf(w, "%sMean time to remediate: %s\n", style.icon("⏱️"), formatDuration(time.Duration(report.MeanTimeToRemediate)))
+	for _, level := range audit.RiskLevels {
+		if d, ok := report.MeanTimeToRemediateBySeverity[level]; ok {
+			fmt.Fprintf(w, "   • %s: %s\n", style.risk(level), formatDuration(time.Duration(d)))
+		}
+	}
+}
+
+// formatDuration formats a duration in days and hours, or hours and minutes when under a day
+func formatDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, hours)
+	}
+	return fmt.Sprintf("%dh %dm", hours, int(d%time.Hour/time.Minute))
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var outputPath string
+var outputFormat string
+
+// resolveFormat returns the output format for cmd. When --format was not given explicitly,
+// it is inferred from the --output file extension, falling back to defaultFormat.
+func resolveFormat(cmd *cobra.Command, defaultFormat string, allowed ...string) (string, error) {
+	format := outputFormat
+	if !cmd.Flags().Changed("format") {
+		format = defaultFormat
+		switch strings.ToLower(filepath.Ext(outputPath)) {
+		case ".json":
+			format = "json"
+		case ".yaml", ".yml":
+			format = "yaml"
+		case ".html", ".htm":
+			if slices.Contains(allowed, "html") {
+				format = "html"
+			}
+		}
+	}
+	if !slices.Contains(allowed, format) {
+		return "", fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(allowed, ", "))
+	}
+	return format, nil
+}
+
+// writeOutput calls write with the file at path, or with stdout when path is "-" or empty.
+// The file is written to a temporary file next to it and renamed into place on success, so
+// a failed write leaves any previous file intact.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+	if info, err := os.Stat(path); err == nil && !info.Mode().IsRegular() {
+		// Devices and pipes such as /dev/null cannot be replaced
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open output file: %w", err)
+		}
+		defer f.Close()
+		if err := write(f); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Output written to %s\n", path)
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Output written to %s\n", path)
+	return nil
+}
+
+// encodeData writes v to w as indented JSON or YAML
+func encodeData(w io.Writer, format string, v any) error {
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(v, "", "  ")
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", format, err)
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -6,9 +6,12 @@ package cmd
 import (
 	"os"
 
+	"github.com/flushthemoney/RBACLens/internal/version"
 	"github.com/spf13/cobra"
 )
 
+var cfgFile string
+
 // rootCmd represents the base command when called without any subcommands
 // save them to a JSON file for further analysis.`,
 var rootCmd = &cobra.Command{
@@ -22,6 +25,7 @@ Features:
 - Audit RBAC resources for risky configurations using built-in rules.
 
 See the documentation for details on each command.`,
+	Version: version.Get(),
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -41,7 +45,9 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.rbaclens.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.rbaclens.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable coloured terminal output")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Disable emoji in terminal output")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -1,15 +1,19 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
 	"strings"
-	"time"
+	"text/tabwriter"
 
 	"github.com/flushthemoney/RBACLens/internal/audit"
-	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/config"
+	"github.com/flushthemoney/RBACLens/internal/htmlreport"
+	"github.com/flushthemoney/RBACLens/internal/policy"
+	"github.com/flushthemoney/RBACLens/internal/remediate"
 	"github.com/flushthemoney/RBACLens/internal/types"
 	"github.com/spf13/cobra"
 )
@@ -18,59 +22,87 @@ import (
 var namespace string
 var inputFile string
 var includeSystem bool
+var ruleFiles []string
+var policyPaths []string
+var policyInput string
+var enableChecks []string
+var disableChecks []string
+var fixOut string
 
 // ruleAuditCmd represents the ruleaudit command
 var ruleAuditCmd = &cobra.Command{
 	Use:   "ruleaudit",
 	Short: "Audit RBAC resources for risky configurations",
-	Long: `Audit RBAC resources for risky configurations using built-in rules.
+	Long: `Audit RBAC resources for risky configurations using built-in rules
+and any custom rules loaded with --rules.
 You can fetch live from a cluster or audit a previously saved JSON file.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var resources types.RBACResources
+		if jsonOut {
+			applyLegacyJSONOut(cmd, "rbac_audit_report.json")
+		}
+		format, err := resolveFormat(cmd, "table", "table", "json", "yaml", "html")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		var resources *types.RBACResources
 		if inputFile != "" {
-			data, err := os.ReadFile(inputFile)
-			if err != nil {
-				log.Fatalf("Failed to read input file: %v", err)
-			}
-			if err := json.Unmarshal(data, &resources); err != nil {
-				log.Fatalf("Failed to unmarshal input file: %v", err)
-			}
+			resources, err = loadSnapshot(inputFile)
 		} else {
-			clientset, err := k8s.NewClient(kubeconfig)
+			resources, err = fetchSnapshot(cmd.Context(), kubeconfig, namespace, clusterName)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		var engine *policy.Engine
+		if len(policyPaths) > 0 {
+			engine, err = policy.Load(cmd.Context(), policyPaths...)
 			if err != nil {
-				log.Fatalf("Failed to create Kubernetes client: %v", err)
+				log.Fatalf("Error: %v", err)
 			}
-			rsrcPtr, err := clientset.GetRBACResources(cmd.Context(), namespace)
+		}
+		var policyIDs []string
+		if engine != nil {
+			policyIDs = engine.RuleIDs()
+		}
+		options, err := auditOptions(policyIDs...)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		report := audit.AuditRBACResourcesWithOptions(*resources, options)
+		if engine != nil {
+			violations, err := engine.Evaluate(cmd.Context(), *resources, policyInput)
 			if err != nil {
-				log.Fatalf("Failed to get RBAC resources: %v", err)
-			}
-			resources = *rsrcPtr
-			meta := types.Metadata{
-				ClusterName: "my-cluster", // Placeholder
-				Timestamp:   time.Now(),
+				log.Fatalf("Error: %v", err)
 			}
-			if namespace != "" {
-				meta.Namespaces = strings.Split(namespace, ",")
-			}
-			resources.Metadata = meta
+			report.Merge(violations, options)
 		}
 
-		report := audit.AuditRBACResourcesWithOptions(resources, audit.AuditOptions{
-			IncludeSystemComponents: includeSystem,
-		})
+		if storeDir != "" {
+			if err := storeRun(storeDir, resources, &report); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
 
-		if jsonOut {
-			jsonData, err := json.MarshalIndent(report, "", "  ")
-			if err != nil {
-				log.Fatalf("Failed to marshal audit report: %v", err)
+		if fixOut != "" {
+			if err := writeFixes(fixOut, *resources, report); err != nil {
+				log.Fatalf("Error: %v", err)
 			}
-			filename := "rbac_audit_report.json"
-			if err := os.WriteFile(filename, jsonData, 0644); err != nil {
-				log.Fatalf("Failed to write audit report: %v", err)
+		}
+
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			switch format {
+			case "table":
+				printAuditReport(w, report)
+				return nil
+			case "html":
+				return htmlreport.Write(w, *resources, report)
 			}
-			fmt.Printf("Audit report written to %s\n", filename)
-		} else {
-			printAuditReport(report)
+			return encodeData(w, format, report)
+		})
+		if err != nil {
+			log.Fatalf("Failed to write audit report: %v", err)
 		}
 	},
 }
@@ -79,67 +111,219 @@ func init() {
 	rootCmd.AddCommand(ruleAuditCmd)
 	ruleAuditCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
 	ruleAuditCmd.Flags().StringVar(&namespace, "namespace", "", "Namespaces to audit (comma-separated)")
+	ruleAuditCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	ruleAuditCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table, json, yaml or html")
 	ruleAuditCmd.Flags().BoolVar(&jsonOut, "json-out", false, "Output audit results to JSON file")
+	ruleAuditCmd.Flags().MarkDeprecated("json-out", "use --format json --output <file> instead")
 	ruleAuditCmd.Flags().StringVar(&inputFile, "input", "", "Path to a previously saved RBAC resources JSON file to audit")
+	ruleAuditCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in the report (defaults to the kubeconfig current-context cluster)")
 	ruleAuditCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in audit results (may produce many findings)")
+	ruleAuditCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	ruleAuditCmd.Flags().StringSliceVar(&policyPaths, "policy", nil, "Rego policy files or directories to evaluate alongside the built-in checks")
+	ruleAuditCmd.Flags().StringVar(&policyInput, "policy-input", policy.InputSnapshot, "Input passed to Rego policies: snapshot (the whole RBAC snapshot) or object (each RBAC object)")
+	addCheckSelectionFlags(ruleAuditCmd)
+	ruleAuditCmd.Flags().StringVar(&storeDir, "store", "", "History store directory to add the snapshot and audit report to")
+	ruleAuditCmd.Flags().BoolVar(&fullSnapshot, "full", false, "Keep complete objects when fetching, for policies that use managedFields or the last applied configuration")
+	ruleAuditCmd.Flags().StringVar(&fixOut, "fix-out", "", "Directory to write proposed fixes to, as replacement manifests and JSON patches")
+}
+
+// auditOptions builds the audit options from the command line flags and the config file.
+// policyIDs are the rule IDs of loaded Rego policies, which may be disabled or overridden
+// like checks.
+func auditOptions(policyIDs ...string) (audit.AuditOptions, error) {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return audit.AuditOptions{}, err
+	}
+	checks, err := audit.LoadRuleFiles(ruleFiles...)
+	if err != nil {
+		return audit.AuditOptions{}, err
+	}
+
+	known := map[string]bool{}
+	for _, check := range slices.Concat(audit.BuiltinChecks(), checks) {
+		known[check.ID] = true
+	}
+	for _, id := range policyIDs {
+		known[id] = true
+	}
+	for id := range cfg.SeverityOverrides {
+		if !known[id] {
+			return audit.AuditOptions{}, fmt.Errorf("severity override for unknown check %q", id)
+		}
+	}
+
+	disabled, err := disabledChecks(cfg, known)
+	if err != nil {
+		return audit.AuditOptions{}, err
+	}
+
+	return audit.AuditOptions{
+		IncludeSystemComponents: includeSystem,
+		SeverityOverrides:       cfg.SeverityOverrides,
+		Checks:                  checks,
+		DisabledChecks:          disabled,
+		Suppressions:            cfg.Suppressions,
+	}, nil
+}
+
+// addCheckSelectionFlags adds the --enable and --disable flags to cmd
+func addCheckSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&enableChecks, "enable", nil, "Check IDs to run even if disabled in the config file")
+	cmd.Flags().StringSliceVar(&disableChecks, "disable", nil, "Check IDs to skip")
+}
+
+// disabledChecks combines the checks disabled in the config file and with --disable, minus
+// those re-enabled with --enable. Flag values must name known checks.
+func disabledChecks(cfg *config.Config, known map[string]bool) ([]string, error) {
+	for _, id := range slices.Concat(enableChecks, disableChecks) {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown check %q", id)
+		}
+	}
+	var disabled []string
+	for _, id := range slices.Concat(cfg.DisabledChecks, disableChecks) {
+		if !slices.Contains(enableChecks, id) && !slices.Contains(disabled, id) {
+			disabled = append(disabled, id)
+		}
+	}
+	return disabled, nil
+}
+
+// writeFixes writes the proposed fixes for the findings of a report to dir
+func writeFixes(dir string, resources types.RBACResources, report audit.AuditReport) error {
+	fixes, unfixed := remediate.Generate(resources, report.Findings)
+	if err := remediate.WriteDir(dir, fixes); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d fixes to %s\n", len(fixes), dir)
+	for _, fix := range fixes {
+		if fix.RequiresInput {
+			fmt.Fprintf(os.Stderr, "   %s needs editing: replace %s before applying\n", fix.File, remediate.Placeholder)
+		}
+	}
+	if len(unfixed) > 0 {
+		fmt.Fprintf(os.Stderr, "%d findings have no automatic fix; see their remediation advice\n", len(unfixed))
+	}
+	return nil
 }
 
 // printAuditReport prints a formatted audit report to the console
-func printAuditReport(report audit.AuditReport) {
-	fmt.Println("╭─────────────────────────────────────────────────────────────╮")
-	fmt.Println("│                    🔍 RBAC Security Audit                   │")
-	fmt.Println("╰─────────────────────────────────────────────────────────────╯")
-	fmt.Println()
+func printAuditReport(w io.Writer, report audit.AuditReport) {
+	style := newTermStyle(w)
+
+	fmt.Fprintln(w, "╭─────────────────────────────────────────────────────────────╮")
+	if style.emoji {
+		fmt.Fprintln(w, "│                    🔍 RBAC Security Audit                   │")
+	} else {
+		fmt.Fprintln(w, "│                      RBAC Security Audit                    │")
+	}
+	fmt.Fprintln(w, "╰─────────────────────────────────────────────────────────────╯")
+	fmt.Fprintln(w)
 
 	// Print summary statistics
-	fmt.Printf("📊 Audit Summary:\n")
-	fmt.Printf("   • ClusterRoles:        %d\n", report.Summary.TotalClusterRoles)
-	fmt.Printf("   • Roles:               %d\n", report.Summary.TotalRoles)
-	fmt.Printf("   • ClusterRoleBindings: %d\n", report.Summary.TotalClusterRoleBindings)
-	fmt.Printf("   • RoleBindings:        %d\n", report.Summary.TotalRoleBindings)
-	fmt.Printf("   • System resources skipped: %d\n", report.Summary.SystemResourcesSkipped)
-	fmt.Println()
+	fmt.Fprintf(w, "%sAudit Summary:\n", style.icon("📊"))
+	fmt.Fprintf(w, "   • ClusterRoles:        %d\n", report.Summary.TotalClusterRoles)
+	fmt.Fprintf(w, "   • Roles:               %d\n", report.Summary.TotalRoles)
+	fmt.Fprintf(w, "   • ClusterRoleBindings: %d\n", report.Summary.TotalClusterRoleBindings)
+	fmt.Fprintf(w, "   • RoleBindings:        %d\n", report.Summary.TotalRoleBindings)
+	fmt.Fprintf(w, "   • System resources skipped: %d\n", report.Summary.SystemResourcesSkipped)
+	if report.Summary.SuppressedFindings > 0 {
+		fmt.Fprintf(w, "   • Suppressed findings: %d\n", report.Summary.SuppressedFindings)
+	}
+	fmt.Fprintln(w)
 
 	if report.Summary.TotalFindings == 0 {
-		fmt.Println("✅ No security issues found!")
-		fmt.Println("   All RBAC configurations appear to follow security best practices.")
+		fmt.Fprintf(w, "%sNo security issues found!\n", style.icon("✅"))
+		fmt.Fprintln(w, "   All RBAC configurations appear to follow security best practices.")
 		if report.Summary.SystemResourcesSkipped > 0 {
-			fmt.Printf("   (Skipped %d system components - use --include-system to see them)\n", report.Summary.SystemResourcesSkipped)
+			fmt.Fprintf(w, "   (Skipped %d system components - use --include-system to see them)\n", report.Summary.SystemResourcesSkipped)
 		}
+		printRiskRanking(w, style, report)
 		return
 	}
 
 	// Print findings summary
-	fmt.Printf("⚠️  Security Issues Found: %d\n", report.Summary.TotalFindings)
-	if report.Summary.HighRiskFindings > 0 {
-		fmt.Printf("   🔴 High Risk:   %d\n", report.Summary.HighRiskFindings)
+	fmt.Fprintf(w, "%sSecurity Issues Found: %d\n", style.icon("⚠️ "), report.Summary.TotalFindings)
+	counts := map[audit.RiskLevel]int{
+		audit.RiskCritical: report.Summary.CriticalRiskFindings,
+		audit.RiskHigh:     report.Summary.HighRiskFindings,
+		audit.RiskMedium:   report.Summary.MediumRiskFindings,
+		audit.RiskLow:      report.Summary.LowRiskFindings,
+		audit.RiskInfo:     report.Summary.InfoRiskFindings,
 	}
-	if report.Summary.MediumRiskFindings > 0 {
-		fmt.Printf("   🟡 Medium Risk: %d\n", report.Summary.MediumRiskFindings)
-	}
-	if report.Summary.LowRiskFindings > 0 {
-		fmt.Printf("   🔵 Low Risk:    %d\n", report.Summary.LowRiskFindings)
+	for _, level := range audit.RiskLevels {
+		if counts[level] > 0 {
+			label := fmt.Sprintf("%s Risk:", style.risk(level))
+			padding := strings.Repeat(" ", max(1, len("critical")-len(level)+1))
+			fmt.Fprintf(w, "   %s%s%d\n", label, padding, counts[level])
+		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	// Print detailed findings
-	fmt.Println("📋 Detailed Findings:")
-	fmt.Println("────────────────────────────────────────────────────────────────")
+	fmt.Fprintf(w, "%sDetailed Findings:\n", style.icon("📋"))
+	fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
 
 	for i, finding := range report.Findings {
-		fmt.Printf("%d. [%s] %s/%s", i+1, finding.Risk, finding.ResourceKind, finding.ResourceName)
+		fmt.Fprintf(w, "%d. [%s] %s/%s", i+1, style.risk(finding.Risk), finding.ResourceKind, finding.ResourceName)
 		if finding.Namespace != "" {
-			fmt.Printf(" (namespace: %s)", finding.Namespace)
+			fmt.Fprintf(w, " (namespace: %s)", finding.Namespace)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "   └─ %s\n", finding.Reason)
+		if finding.Exposure != "" {
+			fmt.Fprintf(w, "      %s\n", finding.Exposure)
+		}
+		if finding.Remediation != "" {
+			fmt.Fprintf(w, "      %sFix: %s\n", style.icon("🔧"), finding.Remediation)
 		}
-		fmt.Println()
-		fmt.Printf("   └─ %s\n", finding.Reason)
 		if i < len(report.Findings)-1 {
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
 	}
 
-	fmt.Println()
+	printRiskRanking(w, style, report)
+
+	fmt.Fprintln(w)
 	if report.Summary.SystemResourcesSkipped > 0 {
-		fmt.Printf("💡 Tip: %d system resources were skipped. Use --include-system to include them.\n", report.Summary.SystemResourcesSkipped)
+		fmt.Fprintf(w, "%sTip: %d system resources were skipped. Use --include-system to include them.\n", style.icon("💡"), report.Summary.SystemResourcesSkipped)
+	}
+}
+
+// topRanked is the number of subjects and namespaces shown in the console ranking
+const topRanked = 10
+
+// printRiskRanking prints the riskiest identities and namespaces by effective permissions
+func printRiskRanking(w io.Writer, style termStyle, report audit.AuditReport) {
+	if len(report.SubjectScores) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%sTop Riskiest Identities:\n", style.icon("🎯"))
+	fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "   SCORE\tKIND\tSUBJECT\tRULES\tCAPABILITIES")
+	for _, s := range report.SubjectScores[:min(topRanked, len(report.SubjectScores))] {
+		name := s.Name
+		if s.Namespace != "" {
+			name = s.Namespace + "/" + s.Name
+		}
+		fmt.Fprintf(tw, "   %d\t%s\t%s\t%d\t%s\n", s.Score, s.Kind, name, s.Rules, strings.Join(s.Capabilities, ", "))
+	}
+	tw.Flush()
+
+	if len(report.NamespaceScores) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%sRiskiest Namespaces:\n", style.icon("📦"))
+	fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "   SCORE\tNAMESPACE\tSUBJECTS")
+	for _, n := range report.NamespaceScores[:min(topRanked, len(report.NamespaceScores))] {
+		fmt.Fprintf(tw, "   %d\t%s\t%d\n", n.Score, n.Namespace, n.Subjects)
 	}
+	tw.Flush()
 }
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/compliance"
+	"github.com/spf13/cobra"
+)
+
+// rulesCmd represents the rules command
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "List and explain the audit checks",
+	Long: `Lists the checks run by ruleaudit, built-in and custom, and explains what each
+of them looks for and how to fix its findings.`,
+}
+
+// rulesListCmd represents the rules list command
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the audit checks",
+	Long: `Lists the built-in checks and any custom rules loaded with --rules, with their
+severity and whether they are enabled. Severity overrides and disabled checks from
+the config file are taken into account, as are --enable and --disable.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checks, options, err := listChecks()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printChecks(os.Stdout, checks, options)
+	},
+}
+
+// rulesExplainCmd represents the rules explain command
+var rulesExplainCmd = &cobra.Command{
+	Use:   "explain <id>",
+	Short: "Explain an audit check",
+	Long: `Explains why a check matters, shows an example that triggers it and how to
+remediate its findings.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checks, options, err := listChecks()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		i := slices.IndexFunc(checks, func(check audit.Check) bool { return check.ID == args[0] })
+		if i < 0 {
+			log.Fatalf("Error: unknown check %q, run 'rbaclens rules list' to see the available checks", args[0])
+		}
+		printCheck(os.Stdout, checks[i], options)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesListCmd, rulesExplainCmd)
+	rulesCmd.PersistentFlags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to include")
+	addCheckSelectionFlags(rulesListCmd)
+	addCheckSelectionFlags(rulesExplainCmd)
+}
+
+// listChecks returns the built-in and custom checks along with the audit options that
+// decide their severity and whether they are enabled
+func listChecks() ([]audit.Check, audit.AuditOptions, error) {
+	options, err := auditOptions()
+	if err != nil {
+		return nil, audit.AuditOptions{}, err
+	}
+	return slices.Concat(audit.BuiltinChecks(), options.Checks), options, nil
+}
+
+// checkSeverity returns the severity findings of the check are reported with
+func checkSeverity(check audit.Check, options audit.AuditOptions) audit.RiskLevel {
+	if override, ok := options.SeverityOverrides[check.ID]; ok {
+		return override
+	}
+	return check.Severity
+}
+
+// printChecks prints the checks as a table
+func printChecks(w io.Writer, checks []audit.Check, options audit.AuditOptions) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSEVERITY\tSOURCE\tENABLED\tTITLE")
+	for _, check := range checks {
+		enabled := "yes"
+		if !options.CheckEnabled(check.ID) {
+			enabled = "no"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", check.ID, checkSeverity(check, options), check.Source, enabled, check.Title)
+	}
+	tw.Flush()
+}
+
+// printCheck prints the documentation of a single check
+func printCheck(w io.Writer, check audit.Check, options audit.AuditOptions) {
+	style := newTermStyle(w)
+	heading := func(emoji, title string) {
+		fmt.Fprintf(w, "\n%s\n", style.paint(ansiBold, style.icon(emoji)+title))
+	}
+
+	title := check.ID
+	if check.Title != "" {
+		title += ": " + check.Title
+	}
+	fmt.Fprintln(w, style.paint(ansiBold, title))
+	fmt.Fprintf(w, "   Severity: %s\n", style.risk(checkSeverity(check, options)))
+	fmt.Fprintf(w, "   Source:   %s\n", check.Source)
+	if options.CheckEnabled(check.ID) {
+		fmt.Fprintln(w, "   Enabled:  yes")
+	} else {
+		fmt.Fprintln(w, "   Enabled:  no")
+	}
+
+	if check.Rationale != "" {
+		heading("💡", "Why it matters")
+		fmt.Fprintln(w, indent(check.Rationale, "   "))
+	}
+	if check.Example != "" {
+		heading("📄", "Example")
+		fmt.Fprintln(w, indent(check.Example, "   "))
+	}
+	if check.Remediation != "" {
+		heading("🔧", "Remediation")
+		fmt.Fprintln(w, indent(check.Remediation, "   "))
+	}
+	if controls := compliance.ControlsFor(check.ID); len(controls) > 0 {
+		heading("📜", "Compliance")
+		for _, control := range controls {
+			fmt.Fprintf(w, "   - %s\n", control)
+		}
+	}
+	if len(check.References) > 0 {
+		heading("🔗", "References")
+		for _, ref := range check.References {
+			fmt.Fprintf(w, "   - %s\n", ref)
+		}
+	}
+}
+
+// indent prefixes every line of text with prefix
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
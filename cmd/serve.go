@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var listenAddr string
+var snapshotDir string
+var refreshInterval time.Duration
+var keepSnapshots int
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve snapshots and audit results over an HTTP API",
+	Long: `Runs an HTTP server with a JSON API over RBAC snapshots and their audit results, for
+portals and chat bots that query RBAC without running the CLI.
+
+With --snapshot-dir, the snapshots are the JSON and YAML files in the directory, rescanned
+every --interval. Otherwise the server fetches a snapshot from the cluster every --interval
+and keeps the latest --keep in memory.
+
+Endpoints:
+  GET /v1/snapshots                     List snapshots, oldest first
+  GET /v1/audit                         Audit report of a snapshot
+  GET /v1/who-can                       Subjects allowed a verb on a resource
+  GET /v1/subjects/{id}/permissions     Effective permissions of a subject
+  GET /v1/diff                          Changes between two snapshots
+
+All endpoints take ?snapshot=<id> (or ?from= and ?to= for diff) and default to the latest.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		srv := server.New(options)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		refresh := func(ctx context.Context) error {
+			if snapshotDir != "" {
+				return srv.LoadDir(snapshotDir, loadSnapshot)
+			}
+			resources, err := fetchSnapshot(ctx, kubeconfig, namespace, clusterName)
+			if err != nil {
+				return err
+			}
+			srv.Add(*resources, keepSnapshots)
+			return nil
+		}
+		if err := refresh(ctx); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Loaded %d snapshots", len(srv.Snapshots()))
+		if refreshInterval > 0 {
+			go refreshEvery(ctx, refreshInterval, refresh)
+		}
+
+		httpServer := &http.Server{Addr: listenAddr, Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdown)
+		}()
+		log.Printf("Listening on %s", listenAddr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&listenAddr, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&snapshotDir, "snapshot-dir", "", "Directory of saved snapshots to serve (fetched live from the cluster if omitted)")
+	serveCmd.Flags().DurationVar(&refreshInterval, "interval", 5*time.Minute, "How often to fetch a new snapshot or rescan --snapshot-dir; 0 disables refreshing")
+	serveCmd.Flags().IntVar(&keepSnapshots, "keep", 10, "Number of live snapshots to keep in memory")
+	serveCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	serveCmd.Flags().StringVar(&namespace, "namespace", "", "Namespaces to fetch (comma-separated)")
+	serveCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record in snapshots (defaults to the kubeconfig current-context cluster)")
+	serveCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in audit results")
+	serveCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(serveCmd)
+}
+
+// refreshEvery calls refresh every interval until ctx is done. Failures are logged and the
+// previous snapshots are kept.
+func refreshEvery(ctx context.Context, interval time.Duration, refresh func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := refresh(ctx); err != nil {
+				log.Printf("Refresh failed: %v", err)
+			}
+		}
+	}
+}
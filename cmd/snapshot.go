@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+var clusterName string
+var fullSnapshot bool
+
+// fetchSnapshot fetches RBAC resources from the cluster and fills in the snapshot metadata.
+// Object metadata is compacted unless --full was given.
+func fetchSnapshot(ctx context.Context, kubeconfig string, namespace string, clusterName string) (*types.RBACResources, error) {
+	clientset, err := k8s.NewClient(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	resources, err := clientset.GetRBACResources(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RBAC resources: %w", err)
+	}
+	if !fullSnapshot {
+		k8s.Compact(resources)
+	}
+
+	meta := clientset.GetMetadata(ctx, clusterName)
+	if namespace != "" {
+		meta.Namespaces = strings.Split(namespace, ",")
+	}
+	resources.Metadata = meta
+
+	return resources, nil
+}
+
+// loadSnapshot reads RBAC resources from a previously saved JSON or YAML file, or from stdin when path is "-"
+func loadSnapshot(path string) (*types.RBACResources, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+	var resources types.RBACResources
+	if err := yaml.Unmarshal(data, &resources); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal input file: %w", err)
+	}
+	if err := resources.CheckSchemaVersion(); err != nil {
+		return nil, err
+	}
+	return &resources, nil
+}
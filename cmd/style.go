@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+)
+
+var noColor bool
+var noEmoji bool
+
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiYellow  = "\033[33m"
+	ansiBlue    = "\033[34m"
+	ansiMagenta = "\033[35m"
+	ansiGray    = "\033[90m"
+)
+
+// termStyle decides how the terminal renderers decorate their output
+type termStyle struct {
+	color bool
+	emoji bool
+}
+
+// newTermStyle returns the style for writing to w. Colour is only used when w is a terminal
+// and neither --no-color nor the NO_COLOR environment variable is set.
+func newTermStyle(w io.Writer) termStyle {
+	style := termStyle{emoji: !noEmoji}
+	if f, ok := w.(*os.File); ok && !noColor && os.Getenv("NO_COLOR") == "" {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			style.color = true
+		}
+	}
+	return style
+}
+
+// icon returns the emoji followed by a space, or nothing when emoji are disabled
+func (s termStyle) icon(emoji string) string {
+	if !s.emoji {
+		return ""
+	}
+	return emoji + " "
+}
+
+// risk renders a risk level for display, e.g. "🔴 High"
+func (s termStyle) risk(level audit.RiskLevel) string {
+	var emoji, color string
+	switch level {
+	case audit.RiskCritical:
+		emoji, color = "🟣", ansiMagenta+ansiBold
+	case audit.RiskHigh:
+		emoji, color = "🔴", ansiRed
+	case audit.RiskMedium:
+		emoji, color = "🟡", ansiYellow
+	case audit.RiskLow:
+		emoji, color = "🔵", ansiBlue
+	default:
+		emoji, color = "⚪", ansiGray
+	}
+	label := string(level)
+	if label != "" {
+		label = strings.ToUpper(label[:1]) + label[1:]
+	}
+	return s.paint(color, s.icon(emoji)+label)
+}
+
+// paint wraps text in an ANSI colour sequence when colour is enabled
+func (s termStyle) paint(color string, text string) string {
+	if !s.color {
+		return text
+	}
+	return color + text + ansiReset
+}
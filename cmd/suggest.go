@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/auditlog"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/flushthemoney/RBACLens/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var auditLogs []string
+var since string
+var until string
+var subjectFilter []string
+
+// suggestCmd represents the suggest command
+var suggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Suggest least-privilege roles from API server audit logs",
+	Long: `Reads Kubernetes API server audit logs and generates, for each user and ServiceAccount,
+the minimal Roles, ClusterRoles and bindings that cover exactly the requests they made.
+Each suggestion is compared with the subject's current effective permissions from a
+snapshot, showing which rules it holds but never used.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := resolveFormat(cmd, "yaml", "yaml", "json", "table")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		index, usages, err := loadUsage(cmd)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		suggestions := []usage.Suggestion{}
+		for _, u := range usages {
+			if len(subjectFilter) == 0 && !includeSystem && audit.IsSystemSubject(u.Subject) {
+				continue
+			}
+			suggestions = append(suggestions, usage.Suggest(index, u))
+		}
+
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			switch format {
+			case "yaml":
+				return printSuggestionManifests(w, suggestions)
+			case "table":
+				printSuggestionTable(w, suggestions)
+				return nil
+			}
+			return encodeData(w, format, suggestions)
+		})
+		if err != nil {
+			log.Fatalf("Failed to write suggestions: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(suggestCmd)
+	addUsageFlags(suggestCmd)
+	suggestCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	suggestCmd.Flags().StringVar(&outputFormat, "format", "yaml", "Output format: yaml (manifests), json or table (comparison only)")
+}
+
+// addUsageFlags adds the flags shared by the commands that correlate audit logs with a snapshot
+func addUsageFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&auditLogs, "audit-log", nil, "API server audit log files in JSON lines format, or - for stdin")
+	cmd.MarkFlagRequired("audit-log")
+	cmd.Flags().StringVar(&since, "since", "", "Only use requests after this time (RFC 3339) or this long ago (e.g. 72h, 30d)")
+	cmd.Flags().StringVar(&until, "until", "", "Only use requests before this time (RFC 3339) or this long ago")
+	cmd.Flags().StringSliceVar(&subjectFilter, "subject", nil, "Only report these subjects, e.g. User:alice, ServiceAccount:ns/name or system:serviceaccount:ns:name")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespaces to fetch (comma-separated)")
+	cmd.Flags().StringVar(&inputFile, "input", "", "Path to a previously saved RBAC resources JSON file to compare against")
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name to record when fetching (defaults to the kubeconfig current-context cluster)")
+	cmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system users, ServiceAccounts in system namespaces and system bindings")
+}
+
+// loadUsage loads the snapshot and the audit logs named by the flags, returning the snapshot
+// index and the usage of each subject in the logs, or of the subjects selected with --subject.
+// System subjects are kept, as their requests may be allowed by non-system bindings.
+func loadUsage(cmd *cobra.Command) (*rbac.Index, []usage.Usage, error) {
+	if err := normalizeSubjectFilter(); err != nil {
+		return nil, nil, err
+	}
+	window, err := usageWindow(time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	requests, err := auditlog.ReadFiles(window, auditLogs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resources *types.RBACResources
+	if inputFile != "" {
+		resources, err = loadSnapshot(inputFile)
+	} else {
+		resources, err = fetchSnapshot(cmd.Context(), kubeconfig, namespace, clusterName)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usages := usage.BySubject(requests)
+	if len(subjectFilter) > 0 {
+		usages = slices.DeleteFunc(usages, func(u usage.Usage) bool {
+			return !slices.Contains(subjectFilter, u.Subject.String())
+		})
+	}
+	return rbac.NewIndex(*resources), usages, nil
+}
+
+// normalizeSubjectFilter parses the --subject values and rewrites them in the form subjects
+// are compared in, so ServiceAccount usernames match and invalid subjects are rejected
+// instead of matching nothing
+func normalizeSubjectFilter() error {
+	for i, value := range subjectFilter {
+		subject, err := auditlog.ParseSubject(value)
+		if err != nil {
+			return fmt.Errorf("invalid --subject: %w", err)
+		}
+		subjectFilter[i] = subject.String()
+	}
+	return nil
+}
+
+// usageWindow parses --since and --until
+func usageWindow(now time.Time) (auditlog.Window, error) {
+	var window auditlog.Window
+	var err error
+	if window.Since, err = parseTimeFlag("since", since, now); err != nil {
+		return window, err
+	}
+	if window.Until, err = parseTimeFlag("until", until, now); err != nil {
+		return window, err
+	}
+	return window, nil
+}
+
+// parseTimeFlag parses an RFC 3339 timestamp, or a duration before now. Durations accept a
+// "d" suffix for days in addition to the units of time.ParseDuration.
+func parseTimeFlag(name, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q: expected an RFC 3339 time or a duration such as 72h or 30d", name, value)
+	}
+	return now.Add(-d), nil
+}
+
+// printSuggestionManifests writes the suggested manifests as a multi-document YAML stream,
+// each subject preceded by a comment comparing the suggestion with its current permissions
+func printSuggestionManifests(w io.Writer, suggestions []usage.Suggestion) error {
+	for i, s := range suggestions {
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		c := s.Comparison
+		fmt.Fprintf(w, "# %s: %d requests\n", s.Subject, s.Requests)
+		fmt.Fprintf(w, "# Currently holds %d rules, suggested %d rules\n", c.GrantedRules, c.SuggestedRules)
+		if len(c.Unused) > 0 {
+			fmt.Fprintf(w, "# Held but not used (%d):\n", len(c.Unused))
+			for _, g := range c.Unused {
+				fmt.Fprintf(w, "#   %s: %s\n", describeBinding(g.Binding), rbac.DescribeRule(g.Rule))
+			}
+		}
+		if len(c.Uncovered) > 0 {
+			fmt.Fprintf(w, "# Used but not allowed by the snapshot (%d):\n", len(c.Uncovered))
+			for _, a := range c.Uncovered {
+				fmt.Fprintf(w, "#   %s\n", describeAccess(a))
+			}
+		}
+
+		var objects []any
+		for _, r := range s.ClusterRoles {
+			objects = append(objects, r)
+		}
+		for _, b := range s.ClusterRoleBindings {
+			objects = append(objects, b)
+		}
+		for _, r := range s.Roles {
+			objects = append(objects, r)
+		}
+		for _, b := range s.RoleBindings {
+			objects = append(objects, b)
+		}
+		for j, object := range objects {
+			if j > 0 {
+				fmt.Fprintln(w, "---")
+			}
+			data, err := k8s.ManifestYAML(object)
+			if err != nil {
+				return err
+			}
+			w.Write(data)
+		}
+	}
+	return nil
+}
+
+// printSuggestionTable prints how each suggestion compares with the current permissions
+func printSuggestionTable(w io.Writer, suggestions []usage.Suggestion) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUBJECT\tREQUESTS\tHELD RULES\tSUGGESTED RULES\tUNUSED\tUNCOVERED")
+	for _, s := range suggestions {
+		c := s.Comparison
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", s.Subject, s.Requests, c.GrantedRules, c.SuggestedRules, len(c.Unused), len(c.Uncovered))
+	}
+	tw.Flush()
+}
+
+// describeBinding returns a binding and its role as Kind/name -> Kind/name
+func describeBinding(b rbac.Binding) string {
+	return fmt.Sprintf("%s -> %s/%s", b, b.RoleRef.Kind, b.RoleRef.Name)
+}
+
+// describeAccess returns a request as e.g. "get secrets/db in team-a"
+func describeAccess(a rbac.Access) string {
+	s := a.Verb + " " + a.Resource
+	if a.APIGroup != "" {
+		s += "." + a.APIGroup
+	}
+	if a.Name != "" {
+		s += "/" + a.Name
+	}
+	if a.Namespace != "" {
+		s += " in " + a.Namespace
+	}
+	return s
+}
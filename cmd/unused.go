@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// unusedCmd represents the unused command
+var unusedCmd = &cobra.Command{
+	Use:   "unused",
+	Short: "Find unused bindings and rules from API server audit logs",
+	Long: `Correlates Kubernetes API server audit logs with an RBAC snapshot and reports, for each
+binding and each rule of its role, whether the permissions were exercised. Bindings with no
+observed usage and rules where only some verbs, resources or names were used are candidates
+for removal.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := resolveFormat(cmd, "table", "table", "json", "yaml")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		window, err := usageWindow(time.Now())
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		index, usages, err := loadUsage(cmd)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		report := usage.FindUnused(index, usages, window, func(b rbac.Binding) bool {
+			if len(subjectFilter) > 0 {
+				return !bindsSelectedSubject(b, usages)
+			}
+			return !includeSystem && audit.IsSystemBinding(b)
+		})
+
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			if format == "table" {
+				printUnusedReport(w, report)
+				return nil
+			}
+			return encodeData(w, format, report)
+		})
+		if err != nil {
+			log.Fatalf("Failed to write unused permissions report: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unusedCmd)
+	addUsageFlags(unusedCmd)
+	unusedCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Output file path, or - for stdout")
+	unusedCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table, json or yaml")
+}
+
+// bindsSelectedSubject reports whether a binding names one of the --subject subjects, or a
+// group they were seen with
+func bindsSelectedSubject(b rbac.Binding, usages []usage.Usage) bool {
+	for _, s := range b.Subjects {
+		if slices.Contains(subjectFilter, rbac.NewSubject(s, b.Namespace).String()) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(usages, func(u usage.Usage) bool { return usage.HoldsBinding(u, b) })
+}
+
+// printUnusedReport prints the unused and partially used bindings to the console
+func printUnusedReport(w io.Writer, report usage.UnusedReport) {
+	style := newTermStyle(w)
+
+	fmt.Fprintf(w, "%sObserved %d requests from %d subjects", style.icon("📊"), report.Requests, report.Subjects)
+	if report.Requests > 0 {
+		fmt.Fprintf(w, " between %s and %s", report.First.Format(time.RFC3339), report.Last.Format(time.RFC3339))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "   Bindings: %d used, %d partially used, %d unused", report.Summary.Used, report.Summary.Partial, report.Summary.Unused)
+	if report.Summary.MissingRole > 0 {
+		fmt.Fprintf(w, ", %d with a missing role", report.Summary.MissingRole)
+	}
+	fmt.Fprintln(w)
+
+	if report.Summary.Unused > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%sUnused Bindings (candidates for removal):\n", style.icon("🗑️ "))
+		fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "   BINDING\tROLE\tSUBJECTS")
+		for _, bu := range report.Bindings {
+			if bu.Status != usage.StatusUnused {
+				continue
+			}
+			b := bu.Binding
+			var subjects []string
+			for _, s := range b.Subjects {
+				subjects = append(subjects, rbac.NewSubject(s, b.Namespace).String())
+			}
+			fmt.Fprintf(tw, "   %s\t%s/%s\t%s\n", b.String(), b.RoleRef.Kind, b.RoleRef.Name, strings.Join(subjects, ", "))
+		}
+		tw.Flush()
+	}
+
+	if report.Summary.Partial > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%sPartially Used Bindings:\n", style.icon("✂️ "))
+		fmt.Fprintln(w, "────────────────────────────────────────────────────────────────")
+		for _, bu := range report.Bindings {
+			if bu.Status != usage.StatusPartial {
+				continue
+			}
+			fmt.Fprintln(w, describeBinding(bu.Binding))
+			for _, ru := range bu.Rules {
+				fmt.Fprintf(w, "   └─ %s: %s\n", rbac.DescribeRule(ru.Rule), describeRuleUsage(ru))
+			}
+		}
+	}
+
+	if report.Summary.MissingRole > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%sBindings to Missing Roles:\n", style.icon("❓"))
+		for _, bu := range report.Bindings {
+			if bu.Status == usage.StatusMissingRole {
+				fmt.Fprintf(w, "   %s\n", describeBinding(bu.Binding))
+			}
+		}
+	}
+}
+
+// describeRuleUsage summarises how much of a rule was used
+func describeRuleUsage(ru usage.RuleUsage) string {
+	switch ru.Status {
+	case usage.StatusUnused:
+		return "unused"
+	case usage.StatusUsed:
+		return "used"
+	}
+	var parts []string
+	if len(ru.UnusedVerbs) > 0 {
+		parts = append(parts, "unused verbs "+strings.Join(ru.UnusedVerbs, ","))
+	}
+	if len(ru.UnusedResources) > 0 {
+		parts = append(parts, "unused resources "+strings.Join(ru.UnusedResources, ","))
+	}
+	if len(ru.UnusedNames) > 0 {
+		parts = append(parts, "unused names "+strings.Join(ru.UnusedNames, ","))
+	}
+	if len(parts) == 0 {
+		return "wildcard, only partly used"
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookURL    string
+	watchDebounce time.Duration
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Report RBAC changes that introduce findings or widen permissions",
+	Long: `Watches Roles, ClusterRoles, RoleBindings and ClusterRoleBindings through informers and
+emits an event whenever a change introduces a new finding, makes a finding more severe or
+widens the effective permissions of a subject. Each event names the object, the field
+manager that last wrote it (from managedFields), a diff of its rules or subjects, the new
+findings and the rules each subject gained.
+
+Changes are collected until none has arrived for --debounce and then audited together,
+re-evaluating only the changed objects and those bound to them; each changed object still
+gets its own event.
+
+Events are written to stdout as JSON lines, or posted as JSON to --webhook-url. Failed
+webhook deliveries are retried with backoff; events that still cannot be delivered are
+logged, queued and retried with the next change or after a minute, and the watch keeps
+running. Objects already in the cluster when the watch starts produce no events.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		client, err := k8s.NewClient(kubeconfig)
+		if err != nil {
+			log.Fatalf("Error: failed to create Kubernetes client: %v", err)
+		}
+
+		emit := watch.JSONLines(os.Stdout)
+		if webhookURL != "" {
+			emit = watch.Webhook(webhookURL)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := watch.Run(ctx, client.NewRBACCache(namespace), options, watchDebounce, emit); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringVar(&webhookURL, "webhook-url", "", "Post events as JSON to this URL instead of writing them to stdout")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 5*time.Second, "How long to wait for further changes before auditing them")
+	watchCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	watchCmd.Flags().StringVar(&namespace, "namespace", "", "Namespace to watch Roles and RoleBindings in (all if omitted)")
+	watchCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in audit results and permission changes")
+	watchCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(watchCmd)
+}
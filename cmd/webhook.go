@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/admission"
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/cache"
+)
+
+var webhookAddr string
+var tlsCertFile string
+var tlsKeyFile string
+var denyLevel string
+var warnLevel string
+
+// webhookCmd represents the webhook command
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Serve a validating admission webhook that blocks risky RBAC changes",
+	Long: `Serves a ValidatingAdmissionWebhook for Roles, ClusterRoles, RoleBindings and
+ClusterRoleBindings on /validate. Each change is audited together with a cached view of
+the cluster's RBAC, using the same checks as ruleaudit. Changes that introduce findings at
+or above --deny are rejected; findings at or above --warn are returned as warnings.
+Findings that already exist, and findings matched by the suppressions in the config file,
+do not count.
+
+The API server only calls webhooks over HTTPS, so --tls-cert-file and --tls-key-file are
+required.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if tlsCertFile == "" || tlsKeyFile == "" {
+			log.Fatalf("Error: --tls-cert-file and --tls-key-file are required")
+		}
+		deny, err := parseThreshold(denyLevel)
+		if err != nil {
+			log.Fatalf("Error: invalid --deny: %v", err)
+		}
+		warn, err := parseThreshold(warnLevel)
+		if err != nil {
+			log.Fatalf("Error: invalid --warn: %v", err)
+		}
+		options, err := auditOptions()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		client, err := k8s.NewClient(kubeconfig)
+		if err != nil {
+			log.Fatalf("Error: failed to create Kubernetes client: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		rbacCache := client.NewRBACCache("")
+		validator := admission.NewValidator(rbacCache.Resources, admission.Options{Audit: options, Deny: deny, Warn: warn})
+		invalidate := func() { validator.Invalidate() }
+		err = rbacCache.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(any) { invalidate() },
+			UpdateFunc: func(any, any) { invalidate() },
+			DeleteFunc: func(any) { invalidate() },
+		})
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := rbacCache.Start(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Fatalf("Error: %v", err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("POST /validate", validator)
+		mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok\n"))
+		})
+		httpServer := &http.Server{Addr: webhookAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdown)
+		}()
+		log.Printf("Serving admission webhook on %s/validate", webhookAddr)
+		if err := httpServer.ListenAndServeTLS(tlsCertFile, tlsKeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(webhookCmd)
+	webhookCmd.Flags().StringVar(&webhookAddr, "listen", ":8443", "Address to listen on")
+	webhookCmd.Flags().StringVar(&tlsCertFile, "tls-cert-file", "", "TLS certificate file")
+	webhookCmd.Flags().StringVar(&tlsKeyFile, "tls-key-file", "", "TLS private key file")
+	webhookCmd.Flags().StringVar(&denyLevel, "deny", "high", "Deny changes introducing findings of this severity or higher: critical, high, medium, low, info or none")
+	webhookCmd.Flags().StringVar(&warnLevel, "warn", "low", "Warn about changes introducing findings of this severity or higher: critical, high, medium, low, info or none")
+	webhookCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file (in-cluster config is used if omitted)")
+	webhookCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system components in audit results")
+	webhookCmd.Flags().StringSliceVar(&ruleFiles, "rules", nil, "Custom rule files or directories of *.yaml files to run alongside the built-in checks")
+	addCheckSelectionFlags(webhookCmd)
+}
+
+// parseThreshold parses a severity threshold flag, where none disables the threshold
+func parseThreshold(s string) (audit.RiskLevel, error) {
+	if s == "none" || s == "" {
+		return "", nil
+	}
+	return audit.ParseRiskLevel(s)
+}
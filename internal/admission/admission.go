@@ -0,0 +1,170 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/diff"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Options configure a validator
+type Options struct {
+	Audit audit.AuditOptions
+	// Deny rejects changes introducing findings of this risk level or higher. Empty never denies.
+	Deny audit.RiskLevel
+	// Warn admits changes introducing findings of this risk level or higher with a warning.
+	// Empty never warns.
+	Warn audit.RiskLevel
+}
+
+// Validator decides on changes to RBAC objects by auditing the cluster with and without the
+// change. A change is judged by the findings it introduces or makes more severe, so existing
+// findings do not block unrelated changes.
+type Validator struct {
+	options   Options
+	resources func() types.RBACResources
+
+	mu sync.Mutex
+	// current is the audit of the cluster as it is, nil when the cluster changed since
+	current *audit.AuditReport
+}
+
+// NewValidator returns a validator auditing the RBAC objects returned by resources. Call
+// Invalidate whenever they change.
+func NewValidator(resources func() types.RBACResources, options Options) *Validator {
+	return &Validator{options: options, resources: resources}
+}
+
+// Invalidate discards the cached audit of the cluster
+func (v *Validator) Invalidate() {
+	v.mu.Lock()
+	v.current = nil
+	v.mu.Unlock()
+}
+
+// ServeHTTP answers admission.k8s.io/v1 AdmissionReview requests
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "expected an AdmissionReview request", http.StatusBadRequest)
+		return
+	}
+	response := v.Review(review.Request)
+	response.UID = review.Request.UID
+	review.Response = response
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// Review decides on a single admission request. Deletions and objects that cannot be decoded
+// are allowed; the API server rejects malformed objects itself.
+func (v *Validator) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed
+	}
+
+	before, resources := v.currentAudit()
+	if err := applyObject(&resources, req); err != nil {
+		allowed.Warnings = []string{fmt.Sprintf("RBACLens could not evaluate the object: %v", err)}
+		return allowed
+	}
+	after := audit.AuditRBACResourcesWithOptions(resources, v.options.Audit)
+
+	var denied, warned []audit.AuditResult
+	for _, f := range diff.Introduced(before, after) {
+		switch {
+		case atLeast(f.Risk, v.options.Deny):
+			denied = append(denied, f)
+		case atLeast(f.Risk, v.options.Warn):
+			warned = append(warned, f)
+		}
+	}
+
+	response := allowed
+	if len(denied) > 0 {
+		messages := make([]string, len(denied))
+		for i, f := range denied {
+			messages[i] = describe(f)
+		}
+		response = &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonForbidden,
+				Code:    http.StatusForbidden,
+				Message: "RBACLens denied the change: " + strings.Join(messages, "; "),
+			},
+		}
+	}
+	for _, f := range warned {
+		response.Warnings = append(response.Warnings, "RBACLens: "+describe(f))
+	}
+	return response
+}
+
+// currentAudit returns the audit of the cluster as it is, with the resources it was run on
+func (v *Validator) currentAudit() (audit.AuditReport, types.RBACResources) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	resources := v.resources()
+	if v.current == nil {
+		report := audit.AuditRBACResourcesWithOptions(resources, v.options.Audit)
+		v.current = &report
+	}
+	return *v.current, resources
+}
+
+// applyObject adds the object of a request to resources, replacing the object it updates
+func applyObject(resources *types.RBACResources, req *admissionv1.AdmissionRequest) error {
+	var object metav1.Object
+	switch req.Kind.Kind {
+	case "Role":
+		object = &rbacv1.Role{}
+	case "ClusterRole":
+		object = &rbacv1.ClusterRole{}
+	case "RoleBinding":
+		object = &rbacv1.RoleBinding{}
+	case "ClusterRoleBinding":
+		object = &rbacv1.ClusterRoleBinding{}
+	default:
+		return fmt.Errorf("unsupported kind %q", req.Kind.Kind)
+	}
+	if err := json.Unmarshal(req.Object.Raw, object); err != nil {
+		return err
+	}
+	if object.GetNamespace() == "" {
+		object.SetNamespace(req.Namespace)
+	}
+	// Objects created with generateName have no name yet
+	if object.GetName() == "" {
+		object.SetName(req.Name)
+	}
+	if object.GetName() == "" {
+		object.SetName(object.GetGenerateName())
+	}
+	return k8s.SetObject(resources, object)
+}
+
+// atLeast reports whether risk is at least threshold. No risk reaches an empty threshold.
+func atLeast(risk, threshold audit.RiskLevel) bool {
+	return threshold != "" && risk.Score() >= threshold.Score()
+}
+
+func describe(f audit.AuditResult) string {
+	object := f.ResourceKind + "/" + f.ResourceName
+	if f.Namespace != "" {
+		object = f.ResourceKind + "/" + f.Namespace + "/" + f.ResourceName
+	}
+	return fmt.Sprintf("[%s] %s on %s: %s", f.Risk, f.RuleID, object, f.Reason)
+}
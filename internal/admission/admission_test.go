@@ -0,0 +1,240 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	wildSecrets = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}
+	readSecrets = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}}
+	readEvents  = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"get"}}
+	wildAll     = rbacv1.PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}
+)
+
+// cluster holds a Role with '*' verbs on secrets, a high finding that already exists
+func cluster() types.RBACResources {
+	return types.RBACResources{Roles: []rbacv1.Role{role("team-a", "secrets-admin", wildSecrets)}}
+}
+
+func role(namespace, name string, rules ...rbacv1.PolicyRule) rbacv1.Role {
+	return rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Rules: rules}
+}
+
+// request returns an admission request for object, which is encoded as the API server does
+func request(op admissionv1.Operation, kind string, object any) *admissionv1.AdmissionRequest {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		panic(err)
+	}
+	req := &admissionv1.AdmissionRequest{
+		UID:       "1234",
+		Kind:      metav1.GroupVersionKind{Group: rbacv1.GroupName, Version: "v1", Kind: kind},
+		Operation: op,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+	if meta, ok := object.(metav1.Object); ok {
+		req.Namespace, req.Name = meta.GetNamespace(), meta.GetName()
+	}
+	return req
+}
+
+func TestReview(t *testing.T) {
+	generated := role("team-a", "", wildAll)
+	generated.GenerateName = "generated-"
+	// The namespace of a namespaced object may only be in the request
+	unnamespaced := role("", "reader", readSecrets)
+	unnamespacedReq := request(admissionv1.Create, "Role", &unnamespaced)
+	unnamespacedReq.Namespace = "team-a"
+
+	tests := []struct {
+		name    string
+		options Options
+		req     *admissionv1.AdmissionRequest
+		// wantDenied lists substrings of the denial message, nil if the request is allowed
+		wantDenied []string
+		// wantWarnings lists a substring of each warning
+		wantWarnings []string
+	}{
+		{
+			name:       "risky ClusterRole is denied",
+			options:    Options{Deny: audit.RiskHigh, Warn: audit.RiskMedium},
+			req:        request(admissionv1.Create, "ClusterRole", &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "wild"}, Rules: []rbacv1.PolicyRule{wildAll}}),
+			wantDenied: []string{"wildcard-permissions on ClusterRole/wild"},
+		},
+		{
+			name:    "benign Role is allowed",
+			options: Options{Deny: audit.RiskHigh, Warn: audit.RiskMedium},
+			req:     request(admissionv1.Create, "Role", ptr(role("team-a", "events", readEvents))),
+		},
+		{
+			name:         "medium finding is warned about",
+			options:      Options{Deny: audit.RiskHigh, Warn: audit.RiskMedium},
+			req:          unnamespacedReq,
+			wantWarnings: []string{"secrets-read on Role/team-a/reader"},
+		},
+		{
+			name:         "below the deny threshold only warns",
+			options:      Options{Deny: audit.RiskCritical, Warn: audit.RiskMedium},
+			req:          request(admissionv1.Create, "ClusterRole", &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "wild"}, Rules: []rbacv1.PolicyRule{wildAll}}),
+			wantWarnings: []string{"wildcard-permissions on ClusterRole/wild"},
+		},
+		{
+			name:    "empty thresholds never deny or warn",
+			options: Options{},
+			req:     request(admissionv1.Create, "ClusterRole", &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "wild"}, Rules: []rbacv1.PolicyRule{wildAll}}),
+		},
+		{
+			name:    "risky RoleBinding is denied",
+			options: Options{Deny: audit.RiskHigh},
+			req: request(admissionv1.Create, "RoleBinding", &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "anonymous", Namespace: "team-a"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secrets-admin"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:unauthenticated"}},
+			}),
+			wantDenied: []string{"unauthenticated-binding on RoleBinding/team-a/anonymous"},
+		},
+		{
+			name:    "benign RoleBinding is allowed",
+			options: Options{Deny: audit.RiskHigh, Warn: audit.RiskMedium},
+			req: request(admissionv1.Create, "RoleBinding", &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: "team-a"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "events"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "ci", Namespace: "team-a"}},
+			}),
+		},
+		{
+			name:       "generateName objects are evaluated under their prefix",
+			options:    Options{Deny: audit.RiskHigh},
+			req:        request(admissionv1.Create, "Role", &generated),
+			wantDenied: []string{"wildcard-permissions on Role/team-a/generated-"},
+		},
+		{
+			// The wildcard finding already exists, so only the narrower one is new
+			name:         "update narrowing an existing finding",
+			options:      Options{Deny: audit.RiskHigh, Warn: audit.RiskMedium},
+			req:          request(admissionv1.Update, "Role", ptr(role("team-a", "secrets-admin", readSecrets))),
+			wantWarnings: []string{"secrets-read on Role/team-a/secrets-admin"},
+		},
+		{
+			name:    "update keeping an existing finding",
+			options: Options{Deny: audit.RiskHigh, Warn: audit.RiskMedium},
+			req:     request(admissionv1.Update, "Role", ptr(role("team-a", "secrets-admin", wildSecrets, readEvents))),
+		},
+		{
+			name:    "deletions are allowed",
+			options: Options{Deny: audit.RiskInfo},
+			req:     &admissionv1.AdmissionRequest{Operation: admissionv1.Delete, Kind: metav1.GroupVersionKind{Kind: "Role"}},
+		},
+		{
+			name:         "undecodable objects are allowed with a warning",
+			options:      Options{Deny: audit.RiskInfo},
+			req:          &admissionv1.AdmissionRequest{Operation: admissionv1.Create, Kind: metav1.GroupVersionKind{Kind: "Role"}, Object: runtime.RawExtension{Raw: []byte("{")}},
+			wantWarnings: []string{"could not evaluate"},
+		},
+		{
+			name:         "unsupported kinds are allowed with a warning",
+			options:      Options{Deny: audit.RiskInfo},
+			req:          request(admissionv1.Create, "ConfigMap", map[string]any{"metadata": map[string]any{"name": "x"}}),
+			wantWarnings: []string{"unsupported kind \"ConfigMap\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(cluster, tt.options)
+			response := v.Review(tt.req)
+			if response.Allowed != (tt.wantDenied == nil) {
+				t.Fatalf("Allowed = %v, want %v: %+v", response.Allowed, tt.wantDenied == nil, response.Result)
+			}
+			if tt.wantDenied != nil {
+				if response.Result == nil || response.Result.Code != http.StatusForbidden {
+					t.Fatalf("Result = %+v, want a 403 status", response.Result)
+				}
+				for _, want := range tt.wantDenied {
+					if !strings.Contains(response.Result.Message, want) {
+						t.Errorf("denial message %q does not mention %q", response.Result.Message, want)
+					}
+				}
+			}
+			if len(response.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("Warnings = %q, want %q", response.Warnings, tt.wantWarnings)
+			}
+			for i, want := range tt.wantWarnings {
+				if !strings.Contains(response.Warnings[i], want) {
+					t.Errorf("warning %q does not mention %q", response.Warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReviewCachedAudit(t *testing.T) {
+	resources := cluster()
+	v := NewValidator(func() types.RBACResources { return resources }, Options{Deny: audit.RiskHigh})
+	wild := request(admissionv1.Create, "ClusterRole", &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "wild"}, Rules: []rbacv1.PolicyRule{wildAll}})
+	if v.Review(wild).Allowed {
+		t.Fatal("creating a wildcard ClusterRole was allowed")
+	}
+
+	// Once the role exists, applying it again introduces nothing, but only after the cached
+	// audit is invalidated
+	resources.ClusterRoles = []rbacv1.ClusterRole{{ObjectMeta: metav1.ObjectMeta{Name: "wild"}, Rules: []rbacv1.PolicyRule{wildAll}}}
+	if v.Review(wild).Allowed {
+		t.Error("the change was allowed before the cached audit was invalidated")
+	}
+	v.Invalidate()
+	if !v.Review(wild).Allowed {
+		t.Error("re-applying an existing ClusterRole was denied")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	v := NewValidator(cluster, Options{Deny: audit.RiskHigh})
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  request(admissionv1.Create, "ClusterRole", &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "wild"}, Rules: []rbacv1.PolicyRule{wildAll}}),
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	v.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+
+	var got admissionv1.AdmissionReview
+	if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+		t.Fatalf("response is not an AdmissionReview: %v", err)
+	}
+	if got.Kind != "AdmissionReview" || got.APIVersion != "admission.k8s.io/v1" || got.Request != nil {
+		t.Errorf("review = %+v, want an admission.k8s.io/v1 AdmissionReview without the request", got)
+	}
+	if got.Response == nil || got.Response.UID != "1234" || got.Response.Allowed {
+		t.Errorf("response = %+v, want a denial echoing UID 1234", got.Response)
+	}
+
+	for _, body := range []string{"not json", `{"kind": "AdmissionReview"}`} {
+		recorder := httptest.NewRecorder()
+		v.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(body)))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("status for %q = %d, want 400", body, recorder.Code)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -1,33 +1,98 @@
 package audit
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
+	"github.com/flushthemoney/RBACLens/internal/rbac"
 	"github.com/flushthemoney/RBACLens/internal/types"
-	v1 "k8s.io/api/rbac/v1"
 )
 
+// RiskLevel is the canonical, machine-friendly severity of a finding.
+// Display concerns such as emoji and colour belong to the renderers.
 type RiskLevel string
 
 const (
-	RiskLow    RiskLevel = "🔵 Low"
-	RiskMedium RiskLevel = "🟡 Medium"
-	RiskHigh   RiskLevel = "🔴 High"
+	RiskCritical RiskLevel = "critical"
+	RiskHigh     RiskLevel = "high"
+	RiskMedium   RiskLevel = "medium"
+	RiskLow      RiskLevel = "low"
+	RiskInfo     RiskLevel = "info"
 )
 
+// RiskLevels lists all risk levels from most to least severe
+var RiskLevels = []RiskLevel{RiskCritical, RiskHigh, RiskMedium, RiskLow, RiskInfo}
+
+// Score returns the numeric score of the risk level on a 0-10 scale
+func (r RiskLevel) Score() int {
+	switch r {
+	case RiskCritical:
+		return 10
+	case RiskHigh:
+		return 8
+	case RiskMedium:
+		return 5
+	case RiskLow:
+		return 3
+	case RiskInfo:
+		return 1
+	}
+	return 0
+}
+
+// ParseRiskLevel parses a risk level case-insensitively. It also accepts the emoji-prefixed
+// values ("🔴 High", "🟡 Medium", "🔵 Low") written by earlier RBACLens versions.
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty risk level")
+	}
+	level := RiskLevel(strings.ToLower(fields[len(fields)-1]))
+	for _, known := range RiskLevels {
+		if level == known {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown risk level %q", s)
+}
+
+// UnmarshalJSON accepts both canonical and legacy risk levels
+func (r *RiskLevel) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	level, err := ParseRiskLevel(s)
+	if err != nil {
+		return err
+	}
+	*r = level
+	return nil
+}
+
 type AuditResult struct {
+	RuleID       string    `json:"ruleID"`
 	ResourceKind string    `json:"resourceKind"`
 	ResourceName string    `json:"resourceName"`
 	Namespace    string    `json:"namespace,omitempty"`
 	Risk         RiskLevel `json:"risk"`
+	Score        int       `json:"score"`
 	Reason       string    `json:"reason"`
+	Exposure     string    `json:"exposure,omitempty"`
+	Remediation  string    `json:"remediation,omitempty"`
 }
 
 type AuditReport struct {
 	Metadata types.Metadata `json:"metadata"`
 	Findings []AuditResult  `json:"findings"`
 	Summary  AuditSummary   `json:"summary"`
+	// SubjectScores ranks subjects by the risk of their effective permissions, riskiest first
+	SubjectScores []SubjectScore `json:"subjectScores,omitempty"`
+	// NamespaceScores ranks namespaces by the risk of the permissions that reach into them
+	NamespaceScores []NamespaceScore `json:"namespaceScores,omitempty"`
 }
 
 type AuditSummary struct {
@@ -36,14 +101,51 @@ type AuditSummary struct {
 	TotalClusterRoleBindings int `json:"totalClusterRoleBindings"`
 	TotalRoleBindings        int `json:"totalRoleBindings"`
 	TotalFindings            int `json:"totalFindings"`
+	CriticalRiskFindings     int `json:"criticalRiskFindings"`
 	HighRiskFindings         int `json:"highRiskFindings"`
 	MediumRiskFindings       int `json:"mediumRiskFindings"`
 	LowRiskFindings          int `json:"lowRiskFindings"`
+	InfoRiskFindings         int `json:"infoRiskFindings"`
 	SystemResourcesSkipped   int `json:"systemResourcesSkipped"`
+	SuppressedFindings       int `json:"suppressedFindings,omitempty"`
 }
 
 type AuditOptions struct {
 	IncludeSystemComponents bool
+	// SeverityOverrides pins the severity of findings by check ID. Overridden findings
+	// are not adjusted for binding exposure.
+	SeverityOverrides map[string]RiskLevel
+	// Checks are additional checks, such as custom rules, run alongside the built-in checks
+	Checks []Check
+	// DisabledChecks are the IDs of checks that are not run
+	DisabledChecks []string
+	// Suppressions silence accepted findings. They are counted in the summary but not reported.
+	Suppressions []Suppression
+	// AllMatches reports every built-in check that matches a policy rule, not only the most
+	// severe one. Compliance assessments use it, so a rule caught by a broader check still
+	// fails the controls of the narrower checks it also matches.
+	AllMatches bool
+}
+
+// CheckEnabled reports whether the check with the given ID is run
+func (o AuditOptions) CheckEnabled(id string) bool {
+	return !slices.Contains(o.DisabledChecks, id)
+}
+
+// enabledChecks returns the checks that are not disabled by options
+func enabledChecks(checks []Check, options AuditOptions) []Check {
+	return slices.DeleteFunc(slices.Clone(checks), func(check Check) bool {
+		return !options.CheckEnabled(check.ID)
+	})
+}
+
+// engine evaluates checks against a single snapshot
+type engine struct {
+	options AuditOptions
+	checks  []Check
+	custom  []Check
+	index   *rbac.Index
+	mounted map[string]bool
 }
 
 // AuditRBACResources audits the RBAC resources for risky configurations
@@ -53,6 +155,33 @@ func AuditRBACResources(resources types.RBACResources) AuditReport {
 
 // AuditRBACResourcesWithOptions audits the RBAC resources with custom options
 func AuditRBACResourcesWithOptions(resources types.RBACResources, options AuditOptions) AuditReport {
+	e := newEngine(resources, options)
+	report, _ := e.audit(resources, nil)
+	report.SubjectScores, report.NamespaceScores = e.scoreSubjects(e.index.Grants())
+	return report
+}
+
+func newEngine(resources types.RBACResources, options AuditOptions) *engine {
+	return &engine{
+		options: options,
+		checks:  enabledChecks(BuiltinChecks(), options),
+		custom:  enabledChecks(options.Checks, options),
+		index:   rbac.NewIndex(resources),
+		mounted: mountedServiceAccounts(resources.Workloads),
+	}
+}
+
+// objectFindings are the findings of a single object, after suppressions
+type objectFindings struct {
+	findings   []AuditResult
+	suppressed int
+}
+
+// audit evaluates the checks against every object of resources that is not skipped, and
+// returns the report without scores along with the findings of each object. When reuse
+// returns true for an object, its findings are taken from reuse instead of evaluated.
+func (e *engine) audit(resources types.RBACResources, reuse func(ObjectRef) (objectFindings, bool)) (AuditReport, map[ObjectRef]objectFindings) {
+	objects := map[ObjectRef]objectFindings{}
 	findings := []AuditResult{}
 	summary := AuditSummary{
 		TotalClusterRoles:        len(resources.ClusterRoles),
@@ -60,337 +189,252 @@ func AuditRBACResourcesWithOptions(resources types.RBACResources, options AuditO
 		TotalClusterRoleBindings: len(resources.ClusterRoleBindings),
 		TotalRoleBindings:        len(resources.RoleBindings),
 	}
+	add := func(ref ObjectRef, evaluate func() []AuditResult) {
+		of, ok := objectFindings{}, false
+		if reuse != nil {
+			of, ok = reuse(ref)
+		}
+		if !ok {
+			of.findings, of.suppressed = suppress(evaluate(), e.options.Suppressions)
+		}
+		objects[ref] = of
+		findings = append(findings, of.findings...)
+		summary.SuppressedFindings += of.suppressed
+	}
 
 	// Check ClusterRoles for risky rules
 	for _, cr := range resources.ClusterRoles {
-		if !options.IncludeSystemComponents && isSystemResource(cr.Name) {
+		if !e.options.IncludeSystemComponents && isSystemResource(cr.Name) {
 			summary.SystemResourcesSkipped++
 			continue
 		}
 
-		for _, rule := range cr.Rules {
-			if isRuleRisky(rule) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "ClusterRole",
-					ResourceName: cr.Name,
-					Risk:         RiskHigh,
-					Reason:       "ClusterRole grants '*' verbs or resources, which is highly privileged.",
-				})
-				continue
-			}
-			if isRuleMediumRisk(rule) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "ClusterRole",
-					ResourceName: cr.Name,
-					Risk:         RiskMedium,
-					Reason:       mediumRiskReason(rule),
-				})
-				continue
+		add(ObjectRef{Kind: "ClusterRole", Name: cr.Name}, func() []AuditResult {
+			var findings []AuditResult
+			bindings := e.index.BindingsFor("ClusterRole", "", cr.Name)
+			for _, rule := range cr.Rules {
+				findings = append(findings, e.evaluateRule(RuleContext{
+					Kind:     "ClusterRole",
+					Name:     cr.Name,
+					Rule:     rule,
+					Bindings: bindings,
+				})...)
 			}
-			if isRuleLowRisk(rule) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "ClusterRole",
-					ResourceName: cr.Name,
-					Risk:         RiskLow,
-					Reason:       lowRiskReason(rule),
-				})
-			}
-		}
+			return findings
+		})
 	}
 
 	// Check Roles for risky rules
 	for _, r := range resources.Roles {
-		if !options.IncludeSystemComponents && (isSystemResource(r.Name) || isSystemNamespace(r.Namespace)) {
+		if !e.options.IncludeSystemComponents && (isSystemResource(r.Name) || isSystemNamespace(r.Namespace)) {
 			summary.SystemResourcesSkipped++
 			continue
 		}
 
-		for _, rule := range r.Rules {
-			if isRuleRisky(rule) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "Role",
-					ResourceName: r.Name,
-					Namespace:    r.Namespace,
-					Risk:         RiskHigh,
-					Reason:       "Role grants '*' verbs or resources, which is highly privileged.",
-				})
-				continue
-			}
-			if isRuleMediumRisk(rule) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "Role",
-					ResourceName: r.Name,
-					Namespace:    r.Namespace,
-					Risk:         RiskMedium,
-					Reason:       mediumRiskReason(rule),
-				})
-				continue
-			}
-			if isRuleLowRisk(rule) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "Role",
-					ResourceName: r.Name,
-					Namespace:    r.Namespace,
-					Risk:         RiskLow,
-					Reason:       lowRiskReason(rule),
-				})
+		add(ObjectRef{Kind: "Role", Namespace: r.Namespace, Name: r.Name}, func() []AuditResult {
+			var findings []AuditResult
+			bindings := e.index.BindingsFor("Role", r.Namespace, r.Name)
+			for _, rule := range r.Rules {
+				findings = append(findings, e.evaluateRule(RuleContext{
+					Kind:      "Role",
+					Name:      r.Name,
+					Namespace: r.Namespace,
+					Rule:      rule,
+					Bindings:  bindings,
+				})...)
 			}
-		}
+			return findings
+		})
 	}
 
-	// Check ClusterRoleBindings for dangerous bindings
-	for _, crb := range resources.ClusterRoleBindings {
-		if !options.IncludeSystemComponents && (isSystemResource(crb.Name) || isLegitimateSystemBinding(crb.Name)) {
+	// Check ClusterRoleBindings and RoleBindings for dangerous subjects
+	for _, b := range e.index.Bindings() {
+		if !e.options.IncludeSystemComponents && isSystemBinding(b) {
 			summary.SystemResourcesSkipped++
 			continue
 		}
 
-		for _, s := range crb.Subjects {
-			if s.Kind == "Group" && (s.Name == "system:unauthenticated") {
-				findings = append(findings, AuditResult{
-					ResourceKind: "ClusterRoleBinding",
-					ResourceName: crb.Name,
-					Risk:         RiskHigh,
-					Reason:       "ClusterRoleBinding grants cluster-wide access to unauthenticated users.",
-				})
-			}
-			// Only flag service account bindings if they're not legitimate system ones
-			if s.Kind == "Group" && s.Name == "system:serviceaccounts" && !isLegitimateServiceAccountBinding(crb.Name) {
-				findings = append(findings, AuditResult{
-					ResourceKind: "ClusterRoleBinding",
-					ResourceName: crb.Name,
-					Risk:         RiskHigh,
-					Reason:       "ClusterRoleBinding grants cluster-wide access to all service accounts.",
-				})
+		add(ObjectRef{Kind: b.Kind, Namespace: b.Namespace, Name: b.Name}, func() []AuditResult {
+			var findings []AuditResult
+			rules, _ := e.index.Rules(b)
+			for _, s := range b.Subjects {
+				findings = append(findings, e.evaluateSubject(SubjectContext{
+					Binding: b,
+					Subject: s,
+					Rules:   rules,
+				})...)
 			}
-		}
-	}
-
-	// Check RoleBindings for dangerous bindings
-	for _, rb := range resources.RoleBindings {
-		if !options.IncludeSystemComponents && (isSystemResource(rb.Name) || isSystemNamespace(rb.Namespace)) {
-			summary.SystemResourcesSkipped++
-			continue
-		}
-
-		for _, s := range rb.Subjects {
-			if s.Kind == "Group" && s.Name == "system:unauthenticated" {
-				findings = append(findings, AuditResult{
-					ResourceKind: "RoleBinding",
-					ResourceName: rb.Name,
-					Namespace:    rb.Namespace,
-					Risk:         RiskHigh,
-					Reason:       "RoleBinding grants access to unauthenticated users.",
-				})
-			}
-		}
+			return findings
+		})
 	}
 
 	// Calculate summary statistics
-	summary.TotalFindings = len(findings)
-	for _, finding := range findings {
-		switch finding.Risk {
-		case RiskHigh:
-			summary.HighRiskFindings++
-		case RiskMedium:
-			summary.MediumRiskFindings++
-		case RiskLow:
-			summary.LowRiskFindings++
-		}
-	}
+	summary.countFindings(findings)
 
-	// Sort findings by risk: High > Medium > Low
+	// Sort findings by risk, most severe first
 	sortFindingsByRisk(findings)
+
 	return AuditReport{
 		Metadata: resources.Metadata,
 		Findings: findings,
 		Summary:  summary,
-	}
+	}, objects
 }
 
-// sortFindingsByRisk sorts findings in-place by risk: High > Medium > Low
-func sortFindingsByRisk(findings []AuditResult) {
-	riskOrder := map[RiskLevel]int{
-		RiskHigh:   0,
-		RiskMedium: 1,
-		RiskLow:    2,
+// countFindings sets the finding totals of the summary
+func (s *AuditSummary) countFindings(findings []AuditResult) {
+	s.TotalFindings = len(findings)
+	s.CriticalRiskFindings, s.HighRiskFindings, s.MediumRiskFindings, s.LowRiskFindings, s.InfoRiskFindings = 0, 0, 0, 0, 0
+	for _, finding := range findings {
+		switch finding.Risk {
+		case RiskCritical:
+			s.CriticalRiskFindings++
+		case RiskHigh:
+			s.HighRiskFindings++
+		case RiskMedium:
+			s.MediumRiskFindings++
+		case RiskLow:
+			s.LowRiskFindings++
+		case RiskInfo:
+			s.InfoRiskFindings++
+		}
 	}
-	sort.SliceStable(findings, func(i, j int) bool {
-		return riskOrder[findings[i].Risk] < riskOrder[findings[j].Risk]
-	})
 }
 
-// isRuleRisky checks if a policy rule is risky (e.g., grants '*')
-func isRuleRisky(rule v1.PolicyRule) bool {
-	for _, v := range rule.Verbs {
-		if v == "*" {
-			return true
+// Merge adds findings produced outside the audit engine, such as policy violations, to the
+// report. Severity overrides, disabled checks, suppressions and the skipping of system
+// components from options apply to them as to any other finding.
+func (r *AuditReport) Merge(findings []AuditResult, options AuditOptions) {
+	for _, finding := range findings {
+		if !options.CheckEnabled(finding.RuleID) {
+			continue
 		}
-	}
-	for _, r := range rule.Resources {
-		if r == "*" {
-			return true
+		if !options.IncludeSystemComponents && isSystemFinding(finding) {
+			continue
 		}
+		if override, ok := options.SeverityOverrides[finding.RuleID]; ok {
+			finding.Risk = override
+			finding.Score = override.Score()
+		}
+		if slices.ContainsFunc(options.Suppressions, func(s Suppression) bool { return s.Matches(finding) }) {
+			r.Summary.SuppressedFindings++
+			continue
+		}
+		r.Findings = append(r.Findings, finding)
 	}
-	return false
+	sortFindingsByRisk(r.Findings)
+	r.Summary.countFindings(r.Findings)
 }
 
-// isRuleMediumRisk checks for common medium risk RBAC configurations
-func isRuleMediumRisk(rule v1.PolicyRule) bool {
-	// Granting get/list/watch on secrets
-	for _, res := range rule.Resources {
-		if res == "secrets" {
-			for _, v := range rule.Verbs {
-				if v == "get" || v == "list" || v == "watch" {
-					return true
-				}
+// evaluateRule runs the rule checks against a single policy rule. Only the first (most severe)
+// built-in check that matches is reported, so each rule yields at most one built-in finding,
+// unless AllMatches is set. Additional checks are all evaluated.
+func (e *engine) evaluateRule(ctx RuleContext) []AuditResult {
+	var findings []AuditResult
+	for _, checks := range [][]Check{e.checks, e.custom} {
+		for _, check := range checks {
+			if check.MatchRule == nil {
+				continue
 			}
-		}
-	}
-	// Granting create on workloads (pods, deployments, etc.)
-	for _, res := range rule.Resources {
-		if res == "pods" || res == "deployments" || res == "statefulsets" || res == "daemonsets" || res == "jobs" || res == "cronjobs" {
-			for _, v := range rule.Verbs {
-				if v == "create" {
-					return true
+			if reason, ok := check.MatchRule(ctx); ok {
+				finding := AuditResult{
+					RuleID:       check.ID,
+					ResourceKind: ctx.Kind,
+					ResourceName: ctx.Name,
+					Namespace:    ctx.Namespace,
+					Reason:       reason,
+					Remediation:  check.Remediation,
 				}
-			}
-		}
-	}
-	// Granting create on persistentvolumes
-	for _, res := range rule.Resources {
-		if res == "persistentvolumes" {
-			for _, v := range rule.Verbs {
-				if v == "create" {
-					return true
+				e.score(&finding, check, roleExposure(ctx.Bindings, e.mounted))
+				findings = append(findings, finding)
+				if check.Source == SourceBuiltin && !e.options.AllMatches {
+					break
 				}
 			}
 		}
 	}
-	// Granting impersonate, escalate, or bind verbs
-	for _, v := range rule.Verbs {
-		if v == "impersonate" || v == "escalate" || v == "bind" {
-			return true
-		}
-	}
-	// Granting access to proxy subresource of nodes
-	for _, res := range rule.Resources {
-		if res == "nodes/proxy" {
-			return true
-		}
-	}
-	return false
+	return findings
 }
 
-// isRuleLowRisk checks for common low risk RBAC configurations
-func isRuleLowRisk(rule v1.PolicyRule) bool {
-	// Granting list/watch on non-sensitive resources cluster-wide
-	for _, v := range rule.Verbs {
-		if v == "list" || v == "watch" {
-			for _, res := range rule.Resources {
-				if res == "pods" || res == "services" || res == "configmaps" || res == "endpoints" {
-					return true
-				}
-			}
-		}
-	}
-	// Granting get on configmaps
-	for _, res := range rule.Resources {
-		if res == "configmaps" {
-			for _, v := range rule.Verbs {
-				if v == "get" {
-					return true
-				}
-			}
+// evaluateSubject runs the subject checks against a single binding subject
+func (e *engine) evaluateSubject(ctx SubjectContext) []AuditResult {
+	var findings []AuditResult
+	for _, check := range slices.Concat(e.checks, e.custom) {
+		if check.MatchSubject == nil {
+			continue
 		}
-	}
-	// Granting patch on namespaces
-	for _, res := range rule.Resources {
-		if res == "namespaces" {
-			for _, v := range rule.Verbs {
-				if v == "patch" {
-					return true
-				}
+		if reason, ok := check.MatchSubject(ctx); ok {
+			finding := AuditResult{
+				RuleID:       check.ID,
+				ResourceKind: ctx.Binding.Kind,
+				ResourceName: ctx.Binding.Name,
+				Namespace:    ctx.Binding.Namespace,
+				Reason:       reason,
+				Remediation:  check.Remediation,
 			}
+			e.score(&finding, check, e.privilegeExposure(ctx))
+			findings = append(findings, finding)
 		}
 	}
-	return false
+	return findings
 }
 
-// mediumRiskReason returns a string describing the medium risk found
-func mediumRiskReason(rule v1.PolicyRule) string {
-	for _, res := range rule.Resources {
-		if res == "secrets" {
-			for _, v := range rule.Verbs {
-				if v == "get" || v == "list" || v == "watch" {
-					return "Rule grants get/list/watch on secrets, which can leak sensitive data."
-				}
-			}
-		}
-		if res == "pods" || res == "deployments" || res == "statefulsets" || res == "daemonsets" || res == "jobs" || res == "cronjobs" {
-			for _, v := range rule.Verbs {
-				if v == "create" {
-					return "Rule grants create on workloads (pods, deployments, etc.), which can lead to privilege escalation."
-				}
+// privilegeExposure raises binding findings by how privileged the referenced role is
+func (e *engine) privilegeExposure(ctx SubjectContext) exposure {
+	worst := RiskLevel("")
+	for _, rule := range ctx.Rules {
+		for _, check := range e.checks {
+			if check.MatchRule == nil {
+				continue
 			}
-		}
-		if res == "persistentvolumes" {
-			for _, v := range rule.Verbs {
-				if v == "create" {
-					return "Rule grants create on persistentvolumes, which can allow hostPath abuse."
+			if _, ok := check.MatchRule(RuleContext{Rule: rule}); ok {
+				if check.Severity.Score() > worst.Score() {
+					worst = check.Severity
 				}
+				break
 			}
 		}
-		if res == "nodes/proxy" {
-			return "Rule grants access to proxy subresource of nodes, which can allow bypassing audit and admission controls."
-		}
 	}
-	for _, v := range rule.Verbs {
-		if v == "impersonate" {
-			return "Rule grants impersonate verb, which can allow privilege escalation."
-		}
-		if v == "escalate" {
-			return "Rule grants escalate verb, which can allow privilege escalation."
-		}
-		if v == "bind" {
-			return "Rule grants bind verb, which can allow privilege escalation."
-		}
+
+	role := fmt.Sprintf("%s/%s", ctx.Binding.RoleRef.Kind, ctx.Binding.RoleRef.Name)
+	switch {
+	case worst.Score() >= RiskHigh.Score():
+		return exposure{2, fmt.Sprintf("grants %s, which has %s-risk permissions", role, worst)}
+	case worst == RiskMedium:
+		return exposure{1, fmt.Sprintf("grants %s, which has %s-risk permissions", role, worst)}
 	}
-	return "Rule is considered medium risk."
+	return exposure{}
 }
 
-// lowRiskReason returns a string describing the low risk found
-func lowRiskReason(rule v1.PolicyRule) string {
-	for _, v := range rule.Verbs {
-		if v == "list" || v == "watch" {
-			for _, res := range rule.Resources {
-				if res == "pods" || res == "services" || res == "configmaps" || res == "endpoints" {
-					return "Rule grants list/watch on non-sensitive resources cluster-wide."
-				}
-			}
-		}
-	}
-	for _, res := range rule.Resources {
-		if res == "configmaps" {
-			for _, v := range rule.Verbs {
-				if v == "get" {
-					return "Rule grants get on configmaps."
-				}
-			}
-		}
-		if res == "namespaces" {
-			for _, v := range rule.Verbs {
-				if v == "patch" {
-					return "Rule grants patch on namespaces, which can affect pod security or network policies."
-				}
-			}
-		}
+// score sets the risk and score of a finding from the check severity, any configured
+// override and the exposure of the underlying permissions
+func (e *engine) score(finding *AuditResult, check Check, exp exposure) {
+	if override, ok := e.options.SeverityOverrides[check.ID]; ok {
+		finding.Risk = override
+		finding.Score = override.Score()
+		return
 	}
-	return "Rule is considered low risk."
+
+	finding.Score = min(check.Severity.Score()+exp.bonus, RiskCritical.Score())
+	finding.Risk = riskForScore(finding.Score)
+	finding.Exposure = exp.reason
+}
+
+// sortFindingsByRisk sorts findings in-place by score, most severe first
+func sortFindingsByRisk(findings []AuditResult) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Score > findings[j].Score
+	})
 }
 
 // isSystemResource checks if a resource name indicates it's a system component
 func isSystemResource(name string) bool {
+	return SystemResourcePrefix(name) != ""
+}
+
+// SystemResourcePrefix returns the prefix that marks a role or binding name as a system
+// component, or "" if the name has none
+func SystemResourcePrefix(name string) string {
 	systemPrefixes := []string{
 		"system:",
 		"cluster-admin",
@@ -402,12 +446,73 @@ func isSystemResource(name string) bool {
 
 	for _, prefix := range systemPrefixes {
 		if strings.HasPrefix(name, prefix) {
-			return true
+			return prefix
 		}
 	}
+	return ""
+}
+
+// isSystemFinding checks if a finding is about a system component, by the rules the audit
+// uses to skip system roles, bindings and subjects
+func isSystemFinding(f AuditResult) bool {
+	switch f.ResourceKind {
+	case "ClusterRole":
+		return isSystemResource(f.ResourceName)
+	case "Role":
+		return isSystemResource(f.ResourceName) || isSystemNamespace(f.Namespace)
+	case "ClusterRoleBinding", "RoleBinding":
+		return isSystemBinding(rbac.Binding{Kind: f.ResourceKind, Name: f.ResourceName, Namespace: f.Namespace})
+	case "User", "Group", "ServiceAccount":
+		return isSystemSubject(rbac.Subject{Kind: f.ResourceKind, Name: f.ResourceName, Namespace: f.Namespace})
+	}
 	return false
 }
 
+// IsSystemBinding reports whether a binding belongs to a system component, which is skipped
+// unless system components are included
+func IsSystemBinding(b rbac.Binding) bool {
+	return isSystemBinding(b)
+}
+
+// isSystemBinding checks if a binding belongs to a system component
+func isSystemBinding(b rbac.Binding) bool {
+	if isSystemResource(b.Name) {
+		return true
+	}
+	if b.Kind == "ClusterRoleBinding" {
+		return isLegitimateSystemBinding(b.Name)
+	}
+	return isSystemNamespace(b.Namespace)
+}
+
+// IsSystemSubject reports whether a subject is a system component, which is skipped unless
+// system components are included
+func IsSystemSubject(s rbac.Subject) bool {
+	return isSystemSubject(s)
+}
+
+// isSystemSubject checks if a subject is a system component, such as a control plane user
+// or a ServiceAccount in a system namespace
+func isSystemSubject(s rbac.Subject) bool {
+	if s.Kind == "ServiceAccount" {
+		return isSystemNamespace(s.Namespace)
+	}
+	return strings.HasPrefix(s.Name, "system:") && !isBroadSystemGroup(s.Name)
+}
+
+// isBroadSystemGroup checks if a group is one of the built-in groups that span many users,
+// which are never filtered as they are exactly what an audit should surface
+func isBroadSystemGroup(name string) bool {
+	return name == "system:unauthenticated" || name == "system:authenticated" ||
+		name == "system:anonymous" || strings.HasPrefix(name, "system:serviceaccounts")
+}
+
+// IsSystemNamespace reports whether a namespace is a system namespace, whose contents are
+// skipped unless system components are included
+func IsSystemNamespace(namespace string) bool {
+	return isSystemNamespace(namespace)
+}
+
 // isSystemNamespace checks if a namespace is a system namespace
 func isSystemNamespace(namespace string) bool {
 	systemNamespaces := []string{
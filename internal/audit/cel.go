@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	v1 "k8s.io/api/rbac/v1"
+)
+
+// celObject is the Role, ClusterRole or binding under evaluation, as seen by CEL expressions
+type celObject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// celEnv declares the variables available to CEL expressions. Variables that do not apply
+// to the object under evaluation hold empty values:
+//
+//	object   the Role, ClusterRole, RoleBinding or ClusterRoleBinding (kind, name, namespace)
+//	rule     the policy rule under evaluation (Roles and ClusterRoles)
+//	bindings the bindings that grant the role (Roles and ClusterRoles)
+//	binding  the binding under evaluation (RoleBindings and ClusterRoleBindings)
+//	subject  the binding subject, with ServiceAccount namespaces resolved
+//	rules    the rules of the role the binding refers to
+func celEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		ext.NativeTypes(
+			reflect.TypeOf(celObject{}),
+			reflect.TypeOf(v1.PolicyRule{}),
+			reflect.TypeOf(rbac.Binding{}),
+			reflect.TypeOf(rbac.Subject{}),
+			ext.ParseStructTag("json"),
+		),
+		ext.Strings(),
+		ext.Lists(),
+		cel.Variable("object", cel.ObjectType("audit.celObject")),
+		cel.Variable("rule", cel.ObjectType("v1.PolicyRule")),
+		cel.Variable("bindings", cel.ListType(cel.ObjectType("rbac.Binding"))),
+		cel.Variable("binding", cel.ObjectType("rbac.Binding")),
+		cel.Variable("subject", cel.ObjectType("rbac.Subject")),
+		cel.Variable("rules", cel.ListType(cel.ObjectType("v1.PolicyRule"))),
+	)
+}
+
+// celProgram is a compiled CEL expression of a custom rule
+type celProgram struct {
+	program cel.Program
+}
+
+// compileCEL compiles the expression of a custom rule. Errors name the rule and the
+// line and column of each problem in the expression.
+func compileCEL(id string, expression string) (*celProgram, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: failed to create CEL environment: %w", id, err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		var msgs []string
+		for _, e := range issues.Errors() {
+			msgs = append(msgs, fmt.Sprintf("line %d, column %d: %s", e.Location.Line(), e.Location.Column()+1, e.Message))
+		}
+		return nil, fmt.Errorf("rule %q: invalid expression: %s", id, strings.Join(msgs, "; "))
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("rule %q: expression must evaluate to bool, not %s", id, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", id, err)
+	}
+	return &celProgram{program: program}, nil
+}
+
+// evalRule evaluates the expression against a policy rule of a Role or ClusterRole
+func (p *celProgram) evalRule(ctx RuleContext) (bool, error) {
+	bindings := ctx.Bindings
+	if bindings == nil {
+		bindings = []rbac.Binding{}
+	}
+	return p.eval(map[string]any{
+		"object":   celObject{Kind: ctx.Kind, Name: ctx.Name, Namespace: ctx.Namespace},
+		"rule":     ctx.Rule,
+		"bindings": bindings,
+		"binding":  rbac.Binding{},
+		"subject":  rbac.Subject{},
+		"rules":    []v1.PolicyRule{},
+	})
+}
+
+// evalSubject evaluates the expression against a subject of a RoleBinding or ClusterRoleBinding
+func (p *celProgram) evalSubject(ctx SubjectContext) (bool, error) {
+	rules := ctx.Rules
+	if rules == nil {
+		rules = []v1.PolicyRule{}
+	}
+	b := ctx.Binding
+	return p.eval(map[string]any{
+		"object":   celObject{Kind: b.Kind, Name: b.Name, Namespace: b.Namespace},
+		"rule":     v1.PolicyRule{},
+		"bindings": []rbac.Binding{},
+		"binding":  b,
+		"subject":  rbac.NewSubject(ctx.Subject, b.Namespace),
+		"rules":    rules,
+	})
+}
+
+func (p *celProgram) eval(vars map[string]any) (bool, error) {
+	out, _, err := p.program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v, not a bool", out.Value())
+	}
+	return result, nil
+}
@@ -0,0 +1,256 @@
+package audit
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	v1 "k8s.io/api/rbac/v1"
+)
+
+// Check is a single audit check. A check inspects either the policy rules of Roles and
+// ClusterRoles (MatchRule) or the subjects of RoleBindings and ClusterRoleBindings (MatchSubject).
+type Check struct {
+	ID       string
+	Title    string
+	Severity RiskLevel
+	// Source tells where the check is defined, see SourceBuiltin and SourceCustom
+	Source      string
+	Remediation string
+
+	// Rationale, Example and References explain the check in `rbaclens rules explain`
+	Rationale  string
+	Example    string
+	References []string
+
+	// MatchRule reports whether a rule triggers the check, and why
+	MatchRule func(ctx RuleContext) (string, bool)
+	// MatchSubject reports whether a binding subject triggers the check, and why
+	MatchSubject func(ctx SubjectContext) (string, bool)
+}
+
+// Check sources
+const (
+	SourceBuiltin = "builtin"
+	SourceCustom  = "custom"
+)
+
+// RuleContext is a policy rule of a Role or ClusterRole under evaluation
+type RuleContext struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Rule      v1.PolicyRule
+	// Bindings are the bindings that grant the role to subjects
+	Bindings []rbac.Binding
+}
+
+// SubjectContext is a subject of a RoleBinding or ClusterRoleBinding under evaluation
+type SubjectContext struct {
+	Binding rbac.Binding
+	Subject v1.Subject
+	// Rules are the rules of the referenced role, empty if the role does not exist
+	Rules []v1.PolicyRule
+}
+
+// BuiltinChecks returns the built-in checks, most severe first
+func BuiltinChecks() []Check {
+	checks := builtinChecks()
+	for i := range checks {
+		docs := builtinDocs[checks[i].ID]
+		checks[i].Source = SourceBuiltin
+		checks[i].Rationale = docs.rationale
+		checks[i].Example = docs.example
+		checks[i].Remediation = docs.remediation
+		checks[i].References = docs.references
+	}
+	return checks
+}
+
+func builtinChecks() []Check {
+	return []Check{
+		{
+			ID:       "wildcard-permissions",
+			Title:    "Wildcard verbs or resources",
+			Severity: RiskHigh,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasVerb(ctx.Rule, "*") || hasResource(ctx.Rule, "*") {
+					return fmt.Sprintf("%s grants '*' verbs or resources, which is highly privileged.", ctx.Kind), true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "unauthenticated-binding",
+			Title:    "Binding to unauthenticated users",
+			Severity: RiskHigh,
+			MatchSubject: func(ctx SubjectContext) (string, bool) {
+				if ctx.Subject.Kind != "Group" || ctx.Subject.Name != "system:unauthenticated" {
+					return "", false
+				}
+				if ctx.Binding.Kind == "ClusterRoleBinding" {
+					return "ClusterRoleBinding grants cluster-wide access to unauthenticated users.", true
+				}
+				return "RoleBinding grants access to unauthenticated users.", true
+			},
+		},
+		{
+			ID:       "all-serviceaccounts-binding",
+			Title:    "Cluster-wide binding to all service accounts",
+			Severity: RiskHigh,
+			MatchSubject: func(ctx SubjectContext) (string, bool) {
+				// Only flag service account bindings if they're not legitimate system ones
+				if ctx.Binding.Kind == "ClusterRoleBinding" && ctx.Subject.Kind == "Group" &&
+					ctx.Subject.Name == "system:serviceaccounts" && !isLegitimateServiceAccountBinding(ctx.Binding.Name) {
+					return "ClusterRoleBinding grants cluster-wide access to all service accounts.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "cluster-admin-binding",
+			Title:    "Binding to cluster-admin",
+			Severity: RiskHigh,
+			MatchSubject: func(ctx SubjectContext) (string, bool) {
+				if ctx.Binding.RoleRef.Kind != "ClusterRole" || ctx.Binding.RoleRef.Name != "cluster-admin" {
+					return "", false
+				}
+				if ctx.Binding.Kind == "ClusterRoleBinding" {
+					return fmt.Sprintf("ClusterRoleBinding grants cluster-admin to %s %s across the cluster.", ctx.Subject.Kind, ctx.Subject.Name), true
+				}
+				return fmt.Sprintf("RoleBinding grants cluster-admin to %s %s within the namespace.", ctx.Subject.Kind, ctx.Subject.Name), true
+			},
+		},
+		{
+			ID:       "secrets-read",
+			Title:    "Read access to secrets",
+			Severity: RiskMedium,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasResource(ctx.Rule, "secrets", "*") && hasVerb(ctx.Rule, "get", "list", "watch", "*") {
+					return "Rule grants get/list/watch on secrets, which can leak sensitive data.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "workload-create",
+			Title:    "Create workloads",
+			Severity: RiskMedium,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasResource(ctx.Rule, "pods", "deployments", "statefulsets", "daemonsets", "jobs", "cronjobs", "*") && hasVerb(ctx.Rule, "create", "*") {
+					return "Rule grants create on workloads (pods, deployments, etc.), which can lead to privilege escalation.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "persistentvolume-create",
+			Title:    "Create persistent volumes",
+			Severity: RiskMedium,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasResource(ctx.Rule, "persistentvolumes", "*") && hasVerb(ctx.Rule, "create", "*") {
+					return "Rule grants create on persistentvolumes, which can allow hostPath abuse.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "escalation-verbs",
+			Title:    "Impersonate, escalate or bind verbs",
+			Severity: RiskMedium,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				for _, v := range ctx.Rule.Verbs {
+					if v == "impersonate" || v == "escalate" || v == "bind" {
+						return fmt.Sprintf("Rule grants %s verb, which can allow privilege escalation.", v), true
+					}
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "nodes-proxy",
+			Title:    "Access to the nodes/proxy subresource",
+			Severity: RiskMedium,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasResource(ctx.Rule, "nodes/proxy") {
+					return "Rule grants access to proxy subresource of nodes, which can allow bypassing audit and admission controls.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "default-serviceaccount-binding",
+			Title:    "Permissions granted to a default service account",
+			Severity: RiskLow,
+			MatchSubject: func(ctx SubjectContext) (string, bool) {
+				if ctx.Subject.Kind == "ServiceAccount" && ctx.Subject.Name == "default" {
+					return "Binding grants permissions to a default service account, which every pod in its namespace uses unless told otherwise.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "broad-list-watch",
+			Title:    "List or watch on common resources",
+			Severity: RiskLow,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasVerb(ctx.Rule, "list", "watch") && hasResource(ctx.Rule, "pods", "services", "configmaps", "endpoints") {
+					return "Rule grants list/watch on non-sensitive resources cluster-wide.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "configmap-read",
+			Title:    "Read access to configmaps",
+			Severity: RiskLow,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasResource(ctx.Rule, "configmaps") && hasVerb(ctx.Rule, "get") {
+					return "Rule grants get on configmaps.", true
+				}
+				return "", false
+			},
+		},
+		{
+			ID:       "namespace-patch",
+			Title:    "Patch namespaces",
+			Severity: RiskLow,
+			MatchRule: func(ctx RuleContext) (string, bool) {
+				if hasResource(ctx.Rule, "namespaces") && hasVerb(ctx.Rule, "patch") {
+					return "Rule grants patch on namespaces, which can affect pod security or network policies.", true
+				}
+				return "", false
+			},
+		},
+	}
+}
+
+// LookupCheck returns the built-in check with the given ID
+func LookupCheck(id string) (Check, bool) {
+	for _, check := range BuiltinChecks() {
+		if check.ID == id {
+			return check, true
+		}
+	}
+	return Check{}, false
+}
+
+// hasVerb reports whether the rule lists any of the given verbs
+func hasVerb(rule v1.PolicyRule, verbs ...string) bool {
+	for _, v := range rule.Verbs {
+		if slices.Contains(verbs, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasResource reports whether the rule lists any of the given resources
+func hasResource(rule v1.PolicyRule, resources ...string) bool {
+	for _, r := range rule.Resources {
+		if slices.Contains(resources, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	v1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// RuleFile is a file of custom rule definitions
+type RuleFile struct {
+	Rules []CustomRule `json:"rules"`
+}
+
+// CustomRule is a declarative, user-defined check
+type CustomRule struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title,omitempty"`
+	Severity    RiskLevel `json:"severity"`
+	Message     string    `json:"message"`
+	Remediation string    `json:"remediation,omitempty"`
+	Match       RuleMatch `json:"match"`
+	// Expression is an optional CEL expression that must also evaluate to true
+	Expression string `json:"expression,omitempty"`
+}
+
+// RuleMatch selects the objects a custom rule applies to. Empty selectors match everything.
+//
+// For Roles and ClusterRoles each policy rule is matched on its own, and Subjects matches
+// the subjects of the bindings that grant the role. For RoleBindings and ClusterRoleBindings
+// each subject is matched on its own, and APIGroups, Resources and Verbs match any rule
+// of the referenced role.
+type RuleMatch struct {
+	Kinds      Selector `json:"kinds,omitempty"`
+	Namespaces Selector `json:"namespaces,omitempty"`
+	Names      Selector `json:"names,omitempty"`
+	APIGroups  Selector `json:"apiGroups,omitempty"`
+	Resources  Selector `json:"resources,omitempty"`
+	Verbs      Selector `json:"verbs,omitempty"`
+	// Subjects match the subject as Kind:name, or ServiceAccount:namespace/name
+	Subjects Selector `json:"subjects,omitempty"`
+}
+
+// Selector includes and excludes values by glob, where * matches any sequence of characters
+// and ? matches a single character. It can be written as a plain list of include patterns.
+type Selector struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// UnmarshalJSON accepts either a list of include patterns or an include/exclude object
+func (s *Selector) UnmarshalJSON(data []byte) error {
+	var include []string
+	if err := json.Unmarshal(data, &include); err == nil {
+		s.Include = include
+		return nil
+	}
+	type plain Selector
+	var p plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return fmt.Errorf("selector must be a list of patterns or an object with include/exclude: %w", err)
+	}
+	s.Include, s.Exclude = p.Include, p.Exclude
+	return nil
+}
+
+// compile prepares the glob patterns for matching
+func (s *Selector) compile() error {
+	var err error
+	if s.include, err = compileGlobs(s.Include); err != nil {
+		return err
+	}
+	s.exclude, err = compileGlobs(s.Exclude)
+	return err
+}
+
+// empty reports whether the selector places no restriction
+func (s *Selector) empty() bool {
+	return len(s.Include) == 0 && len(s.Exclude) == 0
+}
+
+// matches reports whether a single value is selected
+func (s *Selector) matches(value string) bool {
+	if len(s.include) > 0 && !anyMatch(s.include, value) {
+		return false
+	}
+	return !anyMatch(s.exclude, value)
+}
+
+// matchesAny reports whether any of the values of a policy rule field is selected.
+// A '*' in the rule grants every value, so it satisfies any include pattern.
+func (s *Selector) matchesAny(values []string) bool {
+	if s.empty() {
+		return true
+	}
+	for _, v := range values {
+		if v == "*" {
+			if !anyMatch(s.exclude, v) {
+				return true
+			}
+			continue
+		}
+		if s.matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRuleFiles loads custom rules from YAML files. Directories are searched for *.yaml and
+// *.yml files.
+func LoadRuleFiles(paths ...string) ([]Check, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			files = append(files, matches...)
+		}
+	}
+
+	var checks []Check
+	ids := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules: %w", err)
+		}
+		var rf RuleFile
+		if err := yaml.UnmarshalStrict(data, &rf); err != nil {
+			return nil, fmt.Errorf("failed to parse rules file %s: %w", file, err)
+		}
+		for _, rule := range rf.Rules {
+			if previous, ok := ids[rule.ID]; ok {
+				return nil, fmt.Errorf("%s: rule %q is already defined in %s", file, rule.ID, previous)
+			}
+			ids[rule.ID] = file
+			check, err := rule.Check()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// Check compiles the custom rule into a check run by the audit engine
+func (r CustomRule) Check() (Check, error) {
+	if r.ID == "" {
+		return Check{}, fmt.Errorf("rule without id")
+	}
+	if _, ok := LookupCheck(r.ID); ok {
+		return Check{}, fmt.Errorf("rule %q: id clashes with a built-in check", r.ID)
+	}
+	if r.Severity == "" {
+		return Check{}, fmt.Errorf("rule %q: severity is required", r.ID)
+	}
+	if r.Message == "" {
+		return Check{}, fmt.Errorf("rule %q: message is required", r.ID)
+	}
+	message, err := template.New(r.ID).Option("missingkey=error").Parse(r.Message)
+	if err == nil {
+		err = message.Execute(io.Discard, messageData("", "", "", ""))
+	}
+	if err != nil {
+		return Check{}, fmt.Errorf("rule %q: invalid message template: %w", r.ID, err)
+	}
+
+	m := r.Match
+	for _, s := range []*Selector{&m.Kinds, &m.Namespaces, &m.Names, &m.APIGroups, &m.Resources, &m.Verbs, &m.Subjects} {
+		if err := s.compile(); err != nil {
+			return Check{}, fmt.Errorf("rule %q: %w", r.ID, err)
+		}
+	}
+
+	var program *celProgram
+	if r.Expression != "" {
+		if program, err = compileCEL(r.ID, r.Expression); err != nil {
+			return Check{}, err
+		}
+	}
+
+	title := r.Title
+	if title == "" {
+		title = r.ID
+	}
+	check := Check{
+		ID:          r.ID,
+		Title:       title,
+		Severity:    r.Severity,
+		Remediation: r.Remediation,
+		Source:      SourceCustom,
+	}
+
+	check.MatchRule = func(ctx RuleContext) (string, bool) {
+		if !m.Kinds.matches(ctx.Kind) || !m.Namespaces.matches(ctx.Namespace) || !m.Names.matches(ctx.Name) {
+			return "", false
+		}
+		if !m.matchesRule(ctx.Rule) {
+			return "", false
+		}
+		subject := ""
+		if !m.Subjects.empty() {
+			found := false
+			for _, b := range ctx.Bindings {
+				for _, s := range b.Subjects {
+					if name := rbac.NewSubject(s, b.Namespace).String(); m.Subjects.matches(name) {
+						subject, found = name, true
+						break
+					}
+				}
+			}
+			if !found {
+				return "", false
+			}
+		}
+		if program != nil {
+			ok, err := program.evalRule(ctx)
+			if err != nil {
+				return fmt.Sprintf("Custom rule %s failed to evaluate: %v", r.ID, err), true
+			}
+			if !ok {
+				return "", false
+			}
+		}
+		return renderMessage(message, messageData(ctx.Kind, ctx.Name, ctx.Namespace, subject)), true
+	}
+
+	check.MatchSubject = func(ctx SubjectContext) (string, bool) {
+		b := ctx.Binding
+		if !m.Kinds.matches(b.Kind) || !m.Namespaces.matches(b.Namespace) || !m.Names.matches(b.Name) {
+			return "", false
+		}
+		subject := rbac.NewSubject(ctx.Subject, b.Namespace).String()
+		if !m.Subjects.matches(subject) {
+			return "", false
+		}
+		if !m.APIGroups.empty() || !m.Resources.empty() || !m.Verbs.empty() {
+			if !slices.ContainsFunc(ctx.Rules, m.matchesRule) {
+				return "", false
+			}
+		}
+		if program != nil {
+			ok, err := program.evalSubject(ctx)
+			if err != nil {
+				return fmt.Sprintf("Custom rule %s failed to evaluate: %v", r.ID, err), true
+			}
+			if !ok {
+				return "", false
+			}
+		}
+		return renderMessage(message, messageData(b.Kind, b.Name, b.Namespace, subject)), true
+	}
+
+	return check, nil
+}
+
+// matchesRule reports whether a policy rule is selected by the apiGroups, resources and verbs selectors
+func (m *RuleMatch) matchesRule(rule v1.PolicyRule) bool {
+	return m.APIGroups.matchesAny(rule.APIGroups) && m.Resources.matchesAny(rule.Resources) && m.Verbs.matchesAny(rule.Verbs)
+}
+
+// messageData returns the fields available to message templates
+func messageData(kind, name, namespace, subject string) map[string]string {
+	return map[string]string{
+		"Kind":      kind,
+		"Name":      name,
+		"Namespace": namespace,
+		"Subject":   subject,
+	}
+}
+
+// renderMessage fills in the message template of a custom rule. Templates are validated
+// when the rule is loaded, so execution does not fail in practice.
+func renderMessage(message *template.Template, data map[string]string) string {
+	var buf bytes.Buffer
+	if err := message.Execute(&buf, data); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// compileGlobs turns glob patterns into anchored regular expressions
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		expr := regexp.QuoteMeta(p)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// anyMatch reports whether any of the patterns matches value
+func anyMatch(patterns []*regexp.Regexp, value string) bool {
+	for _, re := range patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package audit
+
+// checkDocs explains a built-in check for `rbaclens rules explain`
+type checkDocs struct {
+	rationale   string
+	example     string
+	remediation string
+	references  []string
+}
+
+const (
+	refGoodPractices = "https://kubernetes.io/docs/concepts/security/rbac-good-practices/"
+	refRBAC          = "https://kubernetes.io/docs/reference/access-authn-authz/rbac/"
+)
+
+// builtinDocs holds the documentation of the built-in checks by ID
+var builtinDocs = map[string]checkDocs{
+	"wildcard-permissions": {
+		rationale: `A '*' in verbs or resources grants every current and future verb or resource, including
+ones added later by CRDs. A role with wildcards is effectively cluster-admin within its scope and
+silently grows as the cluster grows.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: do-everything
+rules:
+- apiGroups: ["*"]
+  resources: ["*"]
+  verbs: ["*"]`,
+		remediation: "List the exact apiGroups, resources and verbs the workload needs instead of '*'.",
+		references:  []string{refGoodPractices + "#least-privilege"},
+	},
+	"unauthenticated-binding": {
+		rationale: `The system:unauthenticated group contains every request that carries no credentials.
+Binding it to a role hands those permissions to anyone who can reach the API server.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: anonymous-read
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+subjects:
+- apiGroup: rbac.authorization.k8s.io
+  kind: Group
+  name: system:unauthenticated`,
+		remediation: "Remove the system:unauthenticated subject from the binding and bind specific users, groups or ServiceAccounts instead.",
+		references:  []string{refRBAC + "#default-roles-and-role-bindings", refGoodPractices},
+	},
+	"all-serviceaccounts-binding": {
+		rationale: `The system:serviceaccounts group contains every ServiceAccount in every namespace. A
+ClusterRoleBinding to it gives the permissions to any pod in the cluster, so compromising a single
+workload is enough to use them.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: all-sa-secrets
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: secret-reader
+subjects:
+- apiGroup: rbac.authorization.k8s.io
+  kind: Group
+  name: system:serviceaccounts`,
+		remediation: "Bind the role to the specific ServiceAccounts that need it, in the namespaces where they run.",
+		references:  []string{refRBAC + "#service-account-permissions"},
+	},
+	"cluster-admin-binding": {
+		rationale: `cluster-admin can perform any action on any resource. Every subject bound to it, and every
+workload running as such a ServiceAccount, holds full control of the cluster, so a single
+compromised credential compromises everything.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: ops-admin
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cluster-admin
+subjects:
+- apiGroup: rbac.authorization.k8s.io
+  kind: Group
+  name: ops`,
+		remediation: "Bind a role with only the permissions the subject needs, and keep cluster-admin for break-glass access.",
+		references:  []string{refRBAC + "#user-facing-roles", refGoodPractices + "#least-privilege"},
+	},
+	"secrets-read": {
+		rationale: `Secrets hold credentials, including ServiceAccount tokens for other identities. list and
+watch return the contents of every secret in scope, not just their names, so read access often
+means access to everything those credentials unlock.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: secret-reader
+  namespace: team-a
+rules:
+- apiGroups: [""]
+  resources: ["secrets"]
+  verbs: ["get", "list", "watch"]`,
+		remediation: "Restrict get to the secrets the workload needs with resourceNames, and avoid granting list and watch on secrets.",
+		references:  []string{refGoodPractices + "#listing-secrets"},
+	},
+	"workload-create": {
+		rationale: `Anyone who can create pods, directly or through a controller such as a Deployment or Job,
+can run a pod as any ServiceAccount in the namespace and mount any secret, configmap or volume
+there. Creating workloads is therefore equivalent to holding all of those permissions.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: deployer
+  namespace: team-a
+rules:
+- apiGroups: ["apps"]
+  resources: ["deployments"]
+  verbs: ["create"]`,
+		remediation: "Limit workload creation to CI/CD identities and namespaces that need it, and enforce Pod Security admission in those namespaces.",
+		references:  []string{refGoodPractices + "#workload-creation"},
+	},
+	"persistentvolume-create": {
+		rationale: `PersistentVolumes are cluster-scoped and can point at hostPath volumes. Creating one allows
+a pod that claims it to read and write arbitrary files on the node.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: pv-maker
+rules:
+- apiGroups: [""]
+  resources: ["persistentvolumes"]
+  verbs: ["create"]`,
+		remediation: "Leave PersistentVolume creation to storage provisioners and administrators; let users request storage with PersistentVolumeClaims.",
+		references:  []string{refGoodPractices + "#persistent-volume-creation"},
+	},
+	"escalation-verbs": {
+		rationale: `impersonate lets a user act as another user, group or ServiceAccount. escalate lets a user
+create or edit roles with permissions they do not hold, and bind lets them bind roles they do not
+hold. Each bypasses RBAC's built-in protection against privilege escalation.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: role-manager
+rules:
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["clusterroles"]
+  verbs: ["bind", "escalate"]`,
+		remediation: "Grant these verbs only to cluster administrators, and restrict them with resourceNames where possible.",
+		references:  []string{refGoodPractices + "#escalate-verb", refGoodPractices + "#bind-verb", refGoodPractices + "#impersonate-verb"},
+	},
+	"nodes-proxy": {
+		rationale: `The nodes/proxy subresource gives direct access to the kubelet API, which can run commands
+in any pod on the node. Requests go straight to the kubelet, bypassing audit logging and admission
+control.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: node-proxy
+rules:
+- apiGroups: [""]
+  resources: ["nodes/proxy"]
+  verbs: ["get"]`,
+		remediation: "Remove nodes/proxy access; monitoring agents can usually use the nodes/metrics or nodes/stats subresources instead.",
+		references:  []string{refGoodPractices + "#access-to-proxy-subresource-of-nodes"},
+	},
+	"default-serviceaccount-binding": {
+		rationale: `Pods that do not name a ServiceAccount run as the default one of their namespace. Any
+permissions granted to it are therefore shared with every such pod, including ones that have no
+need for API access.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: default-reader
+  namespace: team-a
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: pod-reader
+subjects:
+- kind: ServiceAccount
+  name: default
+  namespace: team-a`,
+		remediation: "Create a dedicated ServiceAccount for the workload that needs the permissions, and set automountServiceAccountToken: false on the default ServiceAccount.",
+		references:  []string{"https://kubernetes.io/docs/concepts/security/service-accounts/#default-service-accounts"},
+	},
+	"broad-list-watch": {
+		rationale: `list and watch on common resources reveal the layout of the cluster: workloads, services
+and their configuration. The information is rarely sensitive on its own, but is valuable for
+reconnaissance.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: pod-lister
+rules:
+- apiGroups: [""]
+  resources: ["pods", "services"]
+  verbs: ["list", "watch"]`,
+		remediation: "Scope list and watch to the namespaces the identity works in by using a RoleBinding instead of a ClusterRoleBinding.",
+		references:  []string{refGoodPractices + "#least-privilege"},
+	},
+	"configmap-read": {
+		rationale: `ConfigMaps are not meant for secrets, but often end up containing connection strings,
+internal endpoints or credentials anyway.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: config-reader
+  namespace: team-a
+rules:
+- apiGroups: [""]
+  resources: ["configmaps"]
+  verbs: ["get"]`,
+		remediation: "Restrict access with resourceNames to the configmaps the workload reads.",
+		references:  []string{refGoodPractices + "#least-privilege"},
+	},
+	"namespace-patch": {
+		rationale: `Namespace labels drive Pod Security admission and are commonly used by NetworkPolicies and
+policy engines. Patching a namespace can switch off those protections for everything inside it.`,
+		example: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: namespace-labeler
+rules:
+- apiGroups: [""]
+  resources: ["namespaces"]
+  verbs: ["patch"]`,
+		remediation: "Reserve namespace updates for administrators, or guard security-relevant labels with an admission policy.",
+		references:  []string{"https://kubernetes.io/docs/concepts/security/pod-security-admission/"},
+	},
+}
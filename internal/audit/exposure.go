@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	v1 "k8s.io/api/rbac/v1"
+)
+
+// Score bonuses added to a finding's base score depending on who can use the permissions.
+// A role that nobody is bound to keeps its base score.
+const (
+	bonusAnonymous          = 5
+	bonusAllAuthenticated   = 4
+	bonusAllServiceAccounts = 3
+	bonusNamespaceSAs       = 2
+	bonusMountedSA          = 1
+)
+
+// exposure describes how widely a finding's permissions are reachable
+type exposure struct {
+	bonus  int
+	reason string
+}
+
+// mountedServiceAccounts returns the ServiceAccounts whose token is mounted into a running workload,
+// keyed by namespace/name
+func mountedServiceAccounts(workloads []types.Workload) map[string]bool {
+	mounted := map[string]bool{}
+	for _, w := range workloads {
+		if w.AutomountToken {
+			mounted[w.Namespace+"/"+w.ServiceAccount] = true
+		}
+	}
+	return mounted
+}
+
+// subjectExposure scores a single binding subject
+func subjectExposure(b rbac.Binding, s v1.Subject, mounted map[string]bool) exposure {
+	via := fmt.Sprintf(" via %s/%s", b.Kind, b.Name)
+	switch {
+	case s.Kind == "Group" && s.Name == "system:unauthenticated",
+		s.Kind == "User" && s.Name == "system:anonymous":
+		return exposure{bonusAnonymous, "bound to anonymous users" + via}
+	case s.Kind == "Group" && s.Name == "system:authenticated":
+		return exposure{bonusAllAuthenticated, "bound to all authenticated users" + via}
+	case s.Kind == "Group" && s.Name == "system:serviceaccounts":
+		return exposure{bonusAllServiceAccounts, "bound to all service accounts" + via}
+	case s.Kind == "Group" && strings.HasPrefix(s.Name, "system:serviceaccounts:"):
+		return exposure{bonusNamespaceSAs, fmt.Sprintf("bound to all service accounts in namespace %s%s", strings.TrimPrefix(s.Name, "system:serviceaccounts:"), via)}
+	case s.Kind == "ServiceAccount":
+		namespace := s.Namespace
+		if namespace == "" {
+			namespace = b.Namespace
+		}
+		if mounted[namespace+"/"+s.Name] {
+			return exposure{bonusMountedSA, fmt.Sprintf("bound to ServiceAccount %s/%s, which is mounted by running workloads%s", namespace, s.Name, via)}
+		}
+	}
+	return exposure{}
+}
+
+// roleExposure returns the widest exposure over all subjects the role is bound to
+func roleExposure(bindings []rbac.Binding, mounted map[string]bool) exposure {
+	widest := exposure{}
+	for _, b := range bindings {
+		for _, s := range b.Subjects {
+			if e := subjectExposure(b, s, mounted); e.bonus > widest.bonus {
+				widest = e
+			}
+		}
+	}
+	return widest
+}
+
+// riskForScore maps a numeric score back onto a risk level
+func riskForScore(score int) RiskLevel {
+	switch {
+	case score >= RiskCritical.Score():
+		return RiskCritical
+	case score >= RiskHigh.Score():
+		return RiskHigh
+	case score >= RiskMedium.Score():
+		return RiskMedium
+	case score >= RiskLow.Score():
+		return RiskLow
+	default:
+		return RiskInfo
+	}
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"github.com/flushthemoney/RBACLens/internal/types"
+	v1 "k8s.io/api/rbac/v1"
+)
+
+// ObjectRef identifies a Role, ClusterRole, RoleBinding or ClusterRoleBinding. Namespace is
+// empty for cluster-scoped objects.
+type ObjectRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ObjectRefs returns the references an Auditor needs for a changed object: the object itself
+// and, for bindings, the role it refers to. It returns nil for other types, including nil.
+func ObjectRefs(object any) []ObjectRef {
+	switch o := object.(type) {
+	case *v1.Role:
+		return []ObjectRef{{Kind: "Role", Namespace: o.Namespace, Name: o.Name}}
+	case *v1.ClusterRole:
+		return []ObjectRef{{Kind: "ClusterRole", Name: o.Name}}
+	case *v1.RoleBinding:
+		role := ObjectRef{Kind: o.RoleRef.Kind, Name: o.RoleRef.Name}
+		if role.Kind == "Role" {
+			role.Namespace = o.Namespace
+		}
+		return []ObjectRef{{Kind: "RoleBinding", Namespace: o.Namespace, Name: o.Name}, role}
+	case *v1.ClusterRoleBinding:
+		return []ObjectRef{{Kind: "ClusterRoleBinding", Name: o.Name}, {Kind: o.RoleRef.Kind, Name: o.RoleRef.Name}}
+	}
+	return nil
+}
+
+// Auditor audits successive versions of the same RBAC objects, such as those of a watch. Each
+// audit only evaluates the objects a change can affect, and reuses the findings of the others.
+type Auditor struct {
+	options AuditOptions
+	// objects holds the findings of each object in the last audit, nil before the first
+	objects map[ObjectRef]objectFindings
+}
+
+// NewAuditor returns an auditor running the checks selected by options
+func NewAuditor(options AuditOptions) *Auditor {
+	return &Auditor{options: options}
+}
+
+// Audit audits resources after the objects in changed were added, updated or deleted. The
+// report is the one AuditRBACResourcesWithOptions returns, without subject and namespace
+// scores.
+//
+// The findings of a role depend on the bindings that refer to it, and those of a binding on
+// its role, so the bindings of changed roles are evaluated again too. Callers changing a
+// binding must list the roles it referred to before and after the change, as ObjectRefs of
+// the old and new versions does. The first audit, and audits with a nil changed, evaluate
+// every object; use them when something other than the RBAC objects, such as the
+// workloads, changed.
+func (a *Auditor) Audit(resources types.RBACResources, changed []ObjectRef) AuditReport {
+	e := newEngine(resources, a.options)
+
+	var reuse func(ObjectRef) (objectFindings, bool)
+	if a.objects != nil && changed != nil {
+		dirty := map[ObjectRef]bool{}
+		for _, ref := range changed {
+			dirty[ref] = true
+			if ref.Kind == "Role" || ref.Kind == "ClusterRole" {
+				for _, b := range e.index.BindingsFor(ref.Kind, ref.Namespace, ref.Name) {
+					dirty[ObjectRef{Kind: b.Kind, Namespace: b.Namespace, Name: b.Name}] = true
+				}
+			}
+		}
+		previous := a.objects
+		reuse = func(ref ObjectRef) (objectFindings, bool) {
+			if dirty[ref] {
+				return objectFindings{}, false
+			}
+			of, ok := previous[ref]
+			return of, ok
+		}
+	}
+
+	report, objects := e.audit(resources, reuse)
+	a.objects = objects
+	return report
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testResources(readerResource string) types.RBACResources {
+	return types.RBACResources{
+		ClusterRoles: []rbacv1.ClusterRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "wild"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "reader"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{readerResource}, Verbs: []string{"get", "list"}}},
+			},
+		},
+		ClusterRoleBindings: []rbacv1.ClusterRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "wild"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "wild"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "reader"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "reader"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:authenticated"}},
+			},
+		},
+	}
+}
+
+func TestAuditorMatchesFullAudit(t *testing.T) {
+	a := NewAuditor(AuditOptions{})
+	before := testResources("configmaps")
+	report := a.Audit(before, nil)
+	full := AuditRBACResourcesWithOptions(before, AuditOptions{})
+	if !reflect.DeepEqual(report.Findings, full.Findings) || report.Summary != full.Summary {
+		t.Fatalf("first audit = %+v, want %+v", report, full)
+	}
+
+	// Changing the role also re-evaluates the binding to it
+	after := testResources("secrets")
+	report = a.Audit(after, []ObjectRef{{Kind: "ClusterRole", Name: "reader"}})
+	full = AuditRBACResourcesWithOptions(after, AuditOptions{})
+	if !reflect.DeepEqual(report.Findings, full.Findings) || report.Summary != full.Summary {
+		t.Errorf("audit after a change = %+v, want %+v", report.Findings, full.Findings)
+	}
+	if reflect.DeepEqual(report.Findings, AuditRBACResourcesWithOptions(before, AuditOptions{}).Findings) {
+		t.Fatalf("the change made no difference to the findings, the test is not meaningful")
+	}
+}
+
+func TestAuditorReusesUnchangedObjects(t *testing.T) {
+	a := NewAuditor(AuditOptions{})
+	before := a.Audit(testResources("configmaps"), nil)
+
+	// The change is not reported, so the previous findings are kept
+	report := a.Audit(testResources("secrets"), []ObjectRef{})
+	if !reflect.DeepEqual(report.Findings, before.Findings) {
+		t.Errorf("findings of unchanged objects = %+v, want %+v", report.Findings, before.Findings)
+	}
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	v1 "k8s.io/api/rbac/v1"
+)
+
+// SubjectScore is the aggregated risk of everything a subject is allowed to do
+type SubjectScore struct {
+	rbac.Subject
+	Score int `json:"score"`
+	// Rules is the number of distinct rules the subject holds
+	Rules int `json:"rules"`
+	// Capabilities summarises what made the score high, e.g. "wildcard" or "secrets"
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// NamespaceScore is the aggregated risk of the permissions that reach into a namespace
+type NamespaceScore struct {
+	Namespace string `json:"namespace"`
+	Score     int    `json:"score"`
+	// Subjects is the number of subjects contributing to the score
+	Subjects int `json:"subjects"`
+}
+
+// Verbs and resources that weigh more when scoring effective permissions
+var (
+	writeVerbs         = []string{"create", "update", "patch", "delete", "deletecollection"}
+	escalationVerbs    = []string{"bind", "escalate", "impersonate"}
+	sensitiveResources = []string{
+		"secrets", "pods/exec", "pods/attach", "nodes", "nodes/proxy", "serviceaccounts/token",
+		"persistentvolumes", "certificatesigningrequests/approval",
+		"roles", "clusterroles", "rolebindings", "clusterrolebindings",
+		"mutatingwebhookconfigurations", "validatingwebhookconfigurations",
+	}
+)
+
+// clusterWideMultiplier is applied to rules granted through ClusterRoleBindings
+const clusterWideMultiplier = 2
+
+// ruleWeight scores a single rule: the heaviest verb times the heaviest resource.
+// Reads weigh 1, writes 3, escalation verbs and wildcards 5; ordinary resources weigh 1,
+// sensitive resources 3 and wildcards 5.
+func ruleWeight(rule v1.PolicyRule) (int, []string) {
+	var capabilities []string
+
+	verbWeight := 1
+	switch {
+	case hasVerb(rule, "*"):
+		verbWeight = 5
+		capabilities = append(capabilities, "wildcard")
+	case hasVerb(rule, escalationVerbs...):
+		verbWeight = 5
+		capabilities = append(capabilities, "escalation")
+	case hasVerb(rule, writeVerbs...):
+		verbWeight = 3
+		capabilities = append(capabilities, "write")
+	}
+
+	resourceWeight := 1
+	switch {
+	case hasResource(rule, "*"):
+		resourceWeight = 5
+		if !slices.Contains(capabilities, "wildcard") {
+			capabilities = append(capabilities, "wildcard")
+		}
+	case hasResource(rule, sensitiveResources...):
+		resourceWeight = 3
+		if hasResource(rule, "secrets") {
+			capabilities = append(capabilities, "secrets")
+		} else {
+			capabilities = append(capabilities, "sensitive")
+		}
+	}
+
+	// Non-resource URLs such as /metrics carry little risk on their own
+	if len(rule.Resources) == 0 {
+		return 1, nil
+	}
+	return verbWeight * resourceWeight, capabilities
+}
+
+// scoreSubjects computes per-subject and per-namespace risk scores from effective permissions.
+// A namespace collects the rules granted within it as well as the cluster-wide rules held by
+// its ServiceAccounts, since compromising a workload there yields those permissions.
+func (e *engine) scoreSubjects(grants []rbac.Grant) ([]SubjectScore, []NamespaceScore) {
+	type ruleKey struct {
+		subject   rbac.Subject
+		namespace string
+		rule      string
+	}
+
+	subjects := map[rbac.Subject]*SubjectScore{}
+	namespaces := map[string]*NamespaceScore{}
+	namespaceSubjects := map[string]map[rbac.Subject]bool{}
+	seen := map[ruleKey]bool{}
+
+	addToNamespace := func(namespace string, subject rbac.Subject, weight int) {
+		if namespaces[namespace] == nil {
+			namespaces[namespace] = &NamespaceScore{Namespace: namespace}
+			namespaceSubjects[namespace] = map[rbac.Subject]bool{}
+		}
+		namespaces[namespace].Score += weight
+		namespaceSubjects[namespace][subject] = true
+	}
+
+	for _, g := range grants {
+		if !e.options.IncludeSystemComponents && (isSystemBinding(g.Binding) || isSystemSubject(g.Subject)) {
+			continue
+		}
+
+		// The same rule granted twice (e.g. via two bindings to one role) only counts once
+		key := ruleKey{g.Subject, g.Namespace, g.Rule.String()}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		weight, capabilities := ruleWeight(g.Rule)
+		if g.Namespace == "" {
+			weight *= clusterWideMultiplier
+		}
+
+		score := subjects[g.Subject]
+		if score == nil {
+			score = &SubjectScore{Subject: g.Subject}
+			subjects[g.Subject] = score
+		}
+		score.Score += weight
+		score.Rules++
+		for _, c := range capabilities {
+			if !slices.Contains(score.Capabilities, c) {
+				score.Capabilities = append(score.Capabilities, c)
+			}
+		}
+
+		switch {
+		case g.Namespace != "":
+			addToNamespace(g.Namespace, g.Subject, weight)
+		case g.Subject.Kind == "ServiceAccount":
+			addToNamespace(g.Subject.Namespace, g.Subject, weight)
+		}
+	}
+
+	subjectScores := make([]SubjectScore, 0, len(subjects))
+	for _, score := range subjects {
+		sort.Strings(score.Capabilities)
+		subjectScores = append(subjectScores, *score)
+	}
+	sort.Slice(subjectScores, func(i, j int) bool {
+		if subjectScores[i].Score != subjectScores[j].Score {
+			return subjectScores[i].Score > subjectScores[j].Score
+		}
+		return subjectScores[i].String() < subjectScores[j].String()
+	})
+
+	namespaceScores := make([]NamespaceScore, 0, len(namespaces))
+	for namespace, score := range namespaces {
+		score.Subjects = len(namespaceSubjects[namespace])
+		namespaceScores = append(namespaceScores, *score)
+	}
+	sort.Slice(namespaceScores, func(i, j int) bool {
+		if namespaceScores[i].Score != namespaceScores[j].Score {
+			return namespaceScores[i].Score > namespaceScores[j].Score
+		}
+		return namespaceScores[i].Namespace < namespaceScores[j].Namespace
+	})
+
+	return subjectScores, namespaceScores
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// Suppression silences findings that were reviewed and accepted. Empty fields match any
+// value, and Namespace and Name may use shell patterns such as team-*.
+type Suppression struct {
+	Rule      string `json:"rule,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Reason records why the findings are accepted
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate reports whether the suppression is well-formed. A suppression must set at least
+// one field to match on, so it cannot silence every finding by accident.
+func (s Suppression) Validate() error {
+	if s.Rule == "" && s.Kind == "" && s.Namespace == "" && s.Name == "" {
+		return errors.New("suppression must set at least one of rule, kind, namespace and name")
+	}
+	for _, pattern := range []string{s.Namespace, s.Name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid suppression pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the suppression applies to a finding
+func (s Suppression) Matches(f AuditResult) bool {
+	return (s.Rule == "" || s.Rule == f.RuleID) &&
+		(s.Kind == "" || s.Kind == f.ResourceKind) &&
+		matchPattern(s.Namespace, f.Namespace) &&
+		matchPattern(s.Name, f.ResourceName)
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// suppress removes the findings matched by a suppression and returns the rest, with the
+// number removed
+func suppress(findings []AuditResult, suppressions []Suppression) ([]AuditResult, int) {
+	if len(suppressions) == 0 {
+		return findings, 0
+	}
+	kept := findings[:0]
+	for _, f := range findings {
+		suppressed := false
+		for _, s := range suppressions {
+			if s.Matches(f) {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			kept = append(kept, f)
+		}
+	}
+	return kept, len(findings) - len(kept)
+}
@@ -0,0 +1,200 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+)
+
+// maxLineSize bounds a single audit event; request and response bodies can make events large
+const maxLineSize = 16 * 1024 * 1024
+
+// decisionAnnotation is set by the API server to the authorization decision of a request
+const decisionAnnotation = "authorization.k8s.io/decision"
+
+// event is the subset of an audit.k8s.io/v1 Event that RBACLens needs
+type event struct {
+	Kind                     string            `json:"kind"`
+	Stage                    string            `json:"stage"`
+	Verb                     string            `json:"verb"`
+	User                     userInfo          `json:"user"`
+	ImpersonatedUser         *userInfo         `json:"impersonatedUser,omitempty"`
+	ObjectRef                *objectReference  `json:"objectRef,omitempty"`
+	ResponseStatus           *responseStatus   `json:"responseStatus,omitempty"`
+	Annotations              map[string]string `json:"annotations,omitempty"`
+	RequestReceivedTimestamp time.Time         `json:"requestReceivedTimestamp"`
+	// Items holds the events of an EventList, as sent by the webhook backend
+	Items []event `json:"items,omitempty"`
+}
+
+type userInfo struct {
+	Username string   `json:"username"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+type objectReference struct {
+	Resource    string `json:"resource"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	APIGroup    string `json:"apiGroup"`
+	Subresource string `json:"subresource"`
+}
+
+type responseStatus struct {
+	Code int `json:"code"`
+}
+
+// Request is an authorized resource request made by a subject
+type Request struct {
+	Subject rbac.Subject `json:"subject"`
+	// Groups are the groups the subject belonged to when making the request
+	Groups []string    `json:"groups,omitempty"`
+	Access rbac.Access `json:"access"`
+	Time   time.Time   `json:"time"`
+}
+
+// Window limits the requests read to a time range. Zero bounds are open.
+type Window struct {
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+}
+
+// Contains reports whether t falls within the window
+func (w Window) Contains(t time.Time) bool {
+	return (w.Since.IsZero() || !t.Before(w.Since)) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+// ReadFiles reads the audit log files at paths, "-" meaning stdin
+func ReadFiles(window Window, paths ...string) ([]Request, error) {
+	var requests []Request
+	for _, path := range paths {
+		read, err := readFile(path, window)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+		}
+		requests = append(requests, read...)
+	}
+	return requests, nil
+}
+
+func readFile(path string, window Window) ([]Request, error) {
+	if path == "-" {
+		return Read(os.Stdin, window)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, window)
+}
+
+// Read parses an audit log in JSON lines format, as written by the API server log backend.
+// Only completed, authorized resource requests within the window are returned; requests to
+// non-resource URLs such as /healthz are skipped.
+func Read(r io.Reader, window Window) ([]Request, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var requests []Request
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var e event
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events := []event{e}
+		if e.Kind == "EventList" {
+			events = e.Items
+		}
+		for _, e := range events {
+			if request, ok := e.request(); ok && window.Contains(request.Time) {
+				requests = append(requests, request)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// request converts an event to a request. The second return value is false for events that
+// do not record an authorized, completed resource request.
+func (e event) request() (Request, bool) {
+	if e.Stage != "ResponseComplete" || e.ObjectRef == nil || e.ObjectRef.Resource == "" {
+		return Request{}, false
+	}
+	if e.Annotations[decisionAnnotation] == "forbid" || (e.ResponseStatus != nil && e.ResponseStatus.Code == 403) {
+		return Request{}, false
+	}
+
+	// Impersonated requests are authorized as the impersonated user
+	user := e.User
+	if e.ImpersonatedUser != nil {
+		user = *e.ImpersonatedUser
+	}
+	if user.Username == "" {
+		return Request{}, false
+	}
+
+	resource := e.ObjectRef.Resource
+	if e.ObjectRef.Subresource != "" {
+		resource += "/" + e.ObjectRef.Subresource
+	}
+	return Request{
+		Subject: SubjectFor(user.Username),
+		Groups:  user.Groups,
+		Access: rbac.Access{
+			Verb:      e.Verb,
+			APIGroup:  e.ObjectRef.APIGroup,
+			Resource:  resource,
+			Namespace: e.ObjectRef.Namespace,
+			Name:      e.ObjectRef.Name,
+		},
+		Time: e.RequestReceivedTimestamp,
+	}, true
+}
+
+// SubjectFor returns the RBAC subject of an authenticated username. ServiceAccount tokens
+// authenticate as system:serviceaccount:<namespace>:<name>.
+func SubjectFor(username string) rbac.Subject {
+	if rest, ok := strings.CutPrefix(username, "system:serviceaccount:"); ok {
+		if namespace, name, ok := strings.Cut(rest, ":"); ok {
+			return rbac.Subject{Kind: "ServiceAccount", Name: name, Namespace: namespace}
+		}
+	}
+	return rbac.Subject{Kind: "User", Name: username}
+}
+
+// ParseSubject parses a subject given by a user, such as a --subject flag, in the Kind:name
+// or ServiceAccount:namespace/name form of rbac.ParseSubject, or as a ServiceAccount
+// username, system:serviceaccount:<namespace>:<name>. ServiceAccount usernames, also as
+// User:system:serviceaccount:..., are returned as ServiceAccount subjects, as requests from
+// them are in the audit log.
+func ParseSubject(s string) (rbac.Subject, error) {
+	if strings.HasPrefix(s, "system:serviceaccount:") {
+		subject := SubjectFor(s)
+		if subject.Kind != "ServiceAccount" || subject.Namespace == "" || subject.Name == "" {
+			return rbac.Subject{}, fmt.Errorf("invalid subject %q, expected system:serviceaccount:namespace:name", s)
+		}
+		return subject, nil
+	}
+	subject, err := rbac.ParseSubject(s)
+	if err != nil {
+		return rbac.Subject{}, err
+	}
+	if subject.Kind == "User" && strings.HasPrefix(subject.Name, "system:serviceaccount:") {
+		return ParseSubject(subject.Name)
+	}
+	return subject, nil
+}
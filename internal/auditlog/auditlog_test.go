@@ -0,0 +1,38 @@
+package auditlog
+
+import (
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+)
+
+func TestParseSubject(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    rbac.Subject
+		wantErr bool
+	}{
+		{in: "User:alice", want: rbac.Subject{Kind: "User", Name: "alice"}},
+		{in: "Group:devs", want: rbac.Subject{Kind: "Group", Name: "devs"}},
+		{in: "ServiceAccount:team-a/deployer", want: rbac.Subject{Kind: "ServiceAccount", Namespace: "team-a", Name: "deployer"}},
+		{in: "system:serviceaccount:team-a:deployer", want: rbac.Subject{Kind: "ServiceAccount", Namespace: "team-a", Name: "deployer"}},
+		{in: "User:system:serviceaccount:team-a:deployer", want: rbac.Subject{Kind: "ServiceAccount", Namespace: "team-a", Name: "deployer"}},
+		{in: "User:system:kube-scheduler", want: rbac.Subject{Kind: "User", Name: "system:kube-scheduler"}},
+		{in: "alice", wantErr: true},
+		{in: "Usr:alice", wantErr: true},
+		{in: "ServiceAccount:deployer", wantErr: true},
+		{in: "system:serviceaccount:team-a", wantErr: true},
+		{in: "system:serviceaccount::deployer", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSubject(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSubject(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSubject(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package compliance
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+)
+
+// Control statuses
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	// StatusManual marks controls that cannot be assessed from an RBAC snapshot
+	StatusManual = "manual"
+	// StatusSkipped marks controls whose checks are all disabled, or whose data is missing from the snapshot
+	StatusSkipped = "skipped"
+)
+
+// Framework is a set of controls from a security benchmark or hardening guide
+type Framework struct {
+	ID       string
+	Name     string
+	Controls []Control
+}
+
+// Control is a single benchmark control. A control fails when any of its mapped checks
+// reports a finding, or when its snapshot evaluation finds a violation.
+type Control struct {
+	ID    string
+	Title string
+	// Checks are the IDs of the audit checks that cover the control
+	Checks []string
+	// Evaluate assesses parts of the control that are not covered by audit checks
+	Evaluate func(resources types.RBACResources, options audit.AuditOptions) ([]audit.AuditResult, bool)
+	// Manual explains how to assess a control that cannot be checked automatically
+	Manual string
+}
+
+// Report is the result of assessing a snapshot against a framework
+type Report struct {
+	Framework string          `json:"framework"`
+	Name      string          `json:"name"`
+	Metadata  types.Metadata  `json:"metadata"`
+	Controls  []ControlResult `json:"controls"`
+	Summary   Summary         `json:"summary"`
+}
+
+// ControlResult is the outcome of a single control
+type ControlResult struct {
+	ID       string              `json:"id"`
+	Title    string              `json:"title"`
+	Status   string              `json:"status"`
+	Checks   []string            `json:"checks,omitempty"`
+	Note     string              `json:"note,omitempty"`
+	Findings []audit.AuditResult `json:"findings,omitempty"`
+}
+
+// Summary counts the controls by status
+type Summary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Manual  int `json:"manual"`
+	Skipped int `json:"skipped"`
+}
+
+// Frameworks returns the supported frameworks
+func Frameworks() []Framework {
+	return []Framework{cisFramework(), nsaFramework()}
+}
+
+// Lookup returns the framework with the given ID
+func Lookup(id string) (Framework, error) {
+	var ids []string
+	for _, framework := range Frameworks() {
+		if framework.ID == id {
+			return framework, nil
+		}
+		ids = append(ids, framework.ID)
+	}
+	return Framework{}, fmt.Errorf("unknown framework %q, supported frameworks: %v", id, ids)
+}
+
+// ControlsFor returns the controls, as "<framework> <control>", that a check is mapped to
+func ControlsFor(checkID string) []string {
+	var controls []string
+	for _, framework := range Frameworks() {
+		for _, control := range framework.Controls {
+			if slices.Contains(control.Checks, checkID) {
+				controls = append(controls, framework.ID+" "+control.ID)
+			}
+		}
+	}
+	return controls
+}
+
+// Assess audits a snapshot and evaluates the framework controls against it. Every check is
+// evaluated against every policy rule, so a rule that a more severe check already reports on
+// still fails the controls of the other checks it matches.
+func Assess(framework Framework, resources types.RBACResources, options audit.AuditOptions) Report {
+	options.AllMatches = true
+	report := audit.AuditRBACResourcesWithOptions(resources, options)
+	result := Report{
+		Framework: framework.ID,
+		Name:      framework.Name,
+		Metadata:  report.Metadata,
+	}
+
+	for _, control := range framework.Controls {
+		cr := ControlResult{
+			ID:     control.ID,
+			Title:  control.Title,
+			Checks: control.Checks,
+			Status: StatusPass,
+		}
+
+		enabled := false
+		for _, id := range control.Checks {
+			enabled = enabled || options.CheckEnabled(id)
+		}
+		for _, finding := range report.Findings {
+			if slices.Contains(control.Checks, finding.RuleID) {
+				cr.Findings = append(cr.Findings, finding)
+			}
+		}
+
+		evaluated := false
+		if control.Evaluate != nil {
+			var findings []audit.AuditResult
+			findings, evaluated = control.Evaluate(resources, options)
+			cr.Findings = append(cr.Findings, findings...)
+		}
+
+		switch {
+		case control.Manual != "":
+			cr.Status = StatusManual
+			cr.Note = control.Manual
+		case len(cr.Findings) > 0:
+			cr.Status = StatusFail
+		case !enabled && !evaluated:
+			cr.Status = StatusSkipped
+			cr.Note = "All checks covering this control are disabled."
+			if control.Evaluate != nil {
+				cr.Note = "The snapshot has no service account or workload data to assess this control."
+			}
+		}
+		sort.SliceStable(cr.Findings, func(i, j int) bool {
+			return cr.Findings[i].Score > cr.Findings[j].Score
+		})
+
+		switch cr.Status {
+		case StatusPass:
+			result.Summary.Passed++
+		case StatusFail:
+			result.Summary.Failed++
+		case StatusManual:
+			result.Summary.Manual++
+		case StatusSkipped:
+			result.Summary.Skipped++
+		}
+		result.Controls = append(result.Controls, cr)
+	}
+	return result
+}
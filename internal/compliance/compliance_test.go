@@ -0,0 +1,128 @@
+package compliance
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// roleWith returns a snapshot holding a single Role with the given rule
+func roleWith(rule rbacv1.PolicyRule) types.RBACResources {
+	return types.RBACResources{Roles: []rbacv1.Role{{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+		Rules:      []rbacv1.PolicyRule{rule},
+	}}}
+}
+
+// statuses returns the status of each control of the report by control ID
+func statuses(report Report) map[string]string {
+	result := map[string]string{}
+	for _, c := range report.Controls {
+		result[c.ID] = c.Status
+	}
+	return result
+}
+
+func TestAssessEveryMatchingCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		rule rbacv1.PolicyRule
+		// cis and nsa are the controls expected to fail, in sorted order
+		cis []string
+		nsa []string
+	}{
+		{
+			name: "secrets read and pod create in one rule",
+			rule: rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets", "pods"}, Verbs: []string{"get", "create"}},
+			cis:  []string{"5.1.2", "5.1.4"},
+			nsa:  []string{"secrets", "workload-creation"},
+		},
+		{
+			name: "wildcard verbs on secrets",
+			rule: rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}},
+			cis:  []string{"5.1.2", "5.1.3"},
+			nsa:  []string{"least-privilege", "secrets"},
+		},
+		{
+			name: "wildcard verbs on pods",
+			rule: rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"*"}},
+			cis:  []string{"5.1.3", "5.1.4"},
+			nsa:  []string{"least-privilege", "workload-creation"},
+		},
+		{
+			name: "wildcard resources",
+			rule: rbacv1.PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get", "create"}},
+			cis:  []string{"5.1.2", "5.1.3", "5.1.4"},
+			nsa:  []string{"least-privilege", "secrets", "workload-creation"},
+		},
+		{
+			name: "harmless rule",
+			rule: rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"get"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, fw := range []struct {
+				id   string
+				fail []string
+			}{{"cis-1.9", tt.cis}, {"nsa-cisa", tt.nsa}} {
+				framework, err := Lookup(fw.id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				report := Assess(framework, roleWith(tt.rule), audit.AuditOptions{})
+				var failed []string
+				for id, status := range statuses(report) {
+					if status == StatusFail {
+						failed = append(failed, id)
+					}
+				}
+				sort.Strings(failed)
+				if !reflect.DeepEqual(failed, fw.fail) {
+					t.Errorf("%s failed controls = %q, want %q", fw.id, failed, fw.fail)
+				}
+			}
+		})
+	}
+}
+
+func TestAssessStatuses(t *testing.T) {
+	framework, err := Lookup("cis-1.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := roleWith(rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}})
+
+	report := Assess(framework, resources, audit.AuditOptions{DisabledChecks: []string{"secrets-read"}})
+	want := map[string]string{
+		"5.1.1": StatusPass,
+		"5.1.2": StatusSkipped,
+		"5.1.3": StatusPass,
+		"5.1.4": StatusPass,
+		// No ServiceAccounts or workloads in the snapshot
+		"5.1.5": StatusPass,
+		"5.1.6": StatusSkipped,
+		"5.1.7": StatusManual,
+		"5.1.8": StatusPass,
+	}
+	if got := statuses(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if report.Summary != (Summary{Passed: 5, Manual: 1, Skipped: 2}) {
+		t.Errorf("Summary = %+v, want 5 passed, 1 manual and 2 skipped", report.Summary)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := Lookup("pci"); err == nil {
+		t.Error("Lookup(pci) succeeded, want an error")
+	}
+	if got, want := ControlsFor("secrets-read"), []string{"cis-1.9 5.1.2", "nsa-cisa secrets"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ControlsFor(secrets-read) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+package compliance
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+)
+
+// cisFramework maps the RBAC controls of the CIS Kubernetes Benchmark v1.9, section 5.1
+func cisFramework() Framework {
+	return Framework{
+		ID:   "cis-1.9",
+		Name: "CIS Kubernetes Benchmark v1.9 - 5.1 RBAC and Service Accounts",
+		Controls: []Control{
+			{
+				ID:     "5.1.1",
+				Title:  "Ensure that the cluster-admin role is only used where required",
+				Checks: []string{"cluster-admin-binding"},
+			},
+			{
+				ID:     "5.1.2",
+				Title:  "Minimize access to secrets",
+				Checks: []string{"secrets-read"},
+			},
+			{
+				ID:     "5.1.3",
+				Title:  "Minimize wildcard use in Roles and ClusterRoles",
+				Checks: []string{"wildcard-permissions"},
+			},
+			{
+				ID:     "5.1.4",
+				Title:  "Minimize access to create pods",
+				Checks: []string{"workload-create"},
+			},
+			{
+				ID:       "5.1.5",
+				Title:    "Ensure that default service accounts are not actively used",
+				Checks:   []string{"default-serviceaccount-binding"},
+				Evaluate: defaultServiceAccountAutomount,
+			},
+			{
+				ID:       "5.1.6",
+				Title:    "Ensure that Service Account Tokens are only mounted where necessary",
+				Evaluate: unnecessaryTokenMounts,
+			},
+			{
+				ID:    "5.1.7",
+				Title: "Avoid use of system:masters group",
+				Manual: "Membership of system:masters comes from client certificates and bypasses RBAC, so it cannot be " +
+					"assessed from RBAC objects. Review which client certificates are issued with the system:masters organisation.",
+			},
+			{
+				ID:     "5.1.8",
+				Title:  "Limit use of the Bind, Impersonate and Escalate permissions in the Kubernetes cluster",
+				Checks: []string{"escalation-verbs"},
+			},
+		},
+	}
+}
+
+// nsaFramework maps the RBAC guidance of the NSA/CISA Kubernetes Hardening Guide v1.2
+func nsaFramework() Framework {
+	return Framework{
+		ID:   "nsa-cisa",
+		Name: "NSA/CISA Kubernetes Hardening Guide v1.2 - Authentication and Authorization",
+		Controls: []Control{
+			{
+				ID:     "anonymous-access",
+				Title:  "Disable anonymous access to the API server",
+				Checks: []string{"unauthenticated-binding"},
+			},
+			{
+				ID:     "least-privilege",
+				Title:  "Grant only the permissions needed, without wildcards or cluster-admin",
+				Checks: []string{"wildcard-permissions", "cluster-admin-binding"},
+			},
+			{
+				ID:     "privilege-escalation",
+				Title:  "Restrict permissions that allow privilege escalation",
+				Checks: []string{"escalation-verbs", "nodes-proxy"},
+			},
+			{
+				ID:     "secrets",
+				Title:  "Restrict access to secrets",
+				Checks: []string{"secrets-read"},
+			},
+			{
+				ID:     "workload-creation",
+				Title:  "Restrict who can create workloads and volumes",
+				Checks: []string{"workload-create", "persistentvolume-create"},
+			},
+			{
+				ID:       "service-accounts",
+				Title:    "Use dedicated service accounts and only mount their tokens where needed",
+				Checks:   []string{"all-serviceaccounts-binding", "default-serviceaccount-binding"},
+				Evaluate: unnecessaryTokenMounts,
+			},
+		},
+	}
+}
+
+// defaultServiceAccountAutomount reports default ServiceAccounts that do not disable token
+// automounting. It evaluates nothing if the snapshot has no ServiceAccounts.
+func defaultServiceAccountAutomount(resources types.RBACResources, options audit.AuditOptions) ([]audit.AuditResult, bool) {
+	if len(resources.ServiceAccounts) == 0 {
+		return nil, false
+	}
+	var findings []audit.AuditResult
+	for _, sa := range resources.ServiceAccounts {
+		if sa.Name != "default" || (!options.IncludeSystemComponents && audit.IsSystemNamespace(sa.Namespace)) {
+			continue
+		}
+		if sa.AutomountToken == nil || *sa.AutomountToken {
+			findings = append(findings, audit.AuditResult{
+				RuleID:       "default-serviceaccount-automount",
+				ResourceKind: "ServiceAccount",
+				ResourceName: sa.Name,
+				Namespace:    sa.Namespace,
+				Risk:         audit.RiskLow,
+				Score:        audit.RiskLow.Score(),
+				Reason:       "Default service account does not set automountServiceAccountToken: false.",
+				Remediation:  "Set automountServiceAccountToken: false on the default ServiceAccount.",
+			})
+		}
+	}
+	return findings, true
+}
+
+// unnecessaryTokenMounts reports workloads that mount the token of a ServiceAccount without
+// any RBAC permissions, held directly or through the groups every ServiceAccount belongs to.
+// Grants of system bindings, such as the API discovery roles every authenticated user holds,
+// do not count. It evaluates nothing if the snapshot has no workloads.
+func unnecessaryTokenMounts(resources types.RBACResources, options audit.AuditOptions) ([]audit.AuditResult, bool) {
+	if len(resources.Workloads) == 0 {
+		return nil, false
+	}
+	grants := slices.DeleteFunc(rbac.NewIndex(resources).Grants(), func(g rbac.Grant) bool {
+		return audit.IsSystemBinding(g.Binding)
+	})
+	var findings []audit.AuditResult
+	for _, w := range resources.Workloads {
+		if !w.AutomountToken || (!options.IncludeSystemComponents && audit.IsSystemNamespace(w.Namespace)) {
+			continue
+		}
+		subject := rbac.Subject{Kind: "ServiceAccount", Name: w.ServiceAccount, Namespace: w.Namespace}
+		if len(rbac.SubjectGrants(grants, subject, rbac.ServiceAccountGroups(w.Namespace))) > 0 {
+			continue
+		}
+		findings = append(findings, audit.AuditResult{
+			RuleID:       "serviceaccount-token-mount",
+			ResourceKind: w.Kind,
+			ResourceName: w.Name,
+			Namespace:    w.Namespace,
+			Risk:         audit.RiskLow,
+			Score:        audit.RiskLow.Score(),
+			Reason:       fmt.Sprintf("%s mounts the token of service account %s, which has no RBAC permissions.", w.Kind, w.ServiceAccount),
+			Remediation:  "Set automountServiceAccountToken: false on the pod template or its ServiceAccount.",
+		})
+	}
+	return findings, true
+}
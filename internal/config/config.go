@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultFile is the config file name looked up in the user's home directory
+const DefaultFile = ".rbaclens.yaml"
+
+// Config holds user settings for RBACLens
+type Config struct {
+	// SeverityOverrides maps check IDs to the severity their findings should be reported with
+	SeverityOverrides map[string]audit.RiskLevel `json:"severityOverrides,omitempty"`
+	// DisabledChecks lists check IDs that are not run unless enabled with --enable
+	DisabledChecks []string `json:"disabledChecks,omitempty"`
+	// Suppressions silence findings that were reviewed and accepted
+	Suppressions []audit.Suppression `json:"suppressions,omitempty"`
+}
+
+// Load reads the config file at path. If path is empty, $HOME/.rbaclens.yaml is used
+// when it exists; otherwise an empty config is returned.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &Config{}, nil
+		}
+		path = filepath.Join(home, DefaultFile)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	for _, suppression := range cfg.Suppressions {
+		if err := suppression.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+	return &cfg, nil
+}
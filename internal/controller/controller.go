@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/policyreport"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Options configure a controller
+type Options struct {
+	// Namespace limits the Roles and RoleBindings watched to one namespace
+	Namespace string
+	Audit     audit.AuditOptions
+	// Metadata is recorded in every audit, with the time of the audit
+	Metadata types.Metadata
+	// Debounce is how long to wait after a change for further changes before re-auditing
+	Debounce time.Duration
+	// Resync is how often to refresh ServiceAccounts and workloads, which are not watched,
+	// and re-audit. 0 disables resyncing.
+	Resync time.Duration
+}
+
+// Controller keeps PolicyReports up to date with the RBAC objects of a cluster. It watches
+// the RBAC objects through informers and, after each burst of changes, re-audits the objects
+// that changed and those bound to them; only reports whose results changed are written.
+type Controller struct {
+	client    *k8s.Client
+	cache     *k8s.RBACCache
+	auditor   *audit.Auditor
+	publisher *policyreport.Publisher
+	options   Options
+
+	// changed collects the objects changed since the last audit
+	mu      sync.Mutex
+	changed []audit.ObjectRef
+
+	serviceAccounts []types.ServiceAccount
+	workloads       []types.Workload
+}
+
+// New returns a controller publishing reports through the client
+func New(client *k8s.Client, options Options) (*Controller, error) {
+	dynamicClient, err := client.Dynamic()
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{
+		client:    client,
+		cache:     client.NewRBACCache(options.Namespace),
+		auditor:   audit.NewAuditor(options.Audit),
+		publisher: policyreport.NewPublisher(dynamicClient, options.Namespace),
+		options:   options,
+	}, nil
+}
+
+// Run watches and audits until ctx is done. It returns an error only if the watch cannot start.
+func (c *Controller) Run(ctx context.Context) error {
+	changes := make(chan struct{}, 1)
+	notify := func(objects ...any) {
+		c.mu.Lock()
+		for _, obj := range objects {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.changed = append(c.changed, audit.ObjectRefs(obj)...)
+		}
+		c.mu.Unlock()
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	err := c.cache.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { notify(obj) },
+		UpdateFunc: func(old, new any) { notify(old, new) },
+		DeleteFunc: func(obj any) { notify(obj) },
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.cache.Start(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	// The initial adds are covered by the first audit
+	select {
+	case <-changes:
+	default:
+	}
+	c.takeChanged()
+	c.refreshWorkloads(ctx)
+	c.reconcile(ctx, nil)
+
+	var resync <-chan time.Time
+	if c.options.Resync > 0 {
+		ticker := time.NewTicker(c.options.Resync)
+		defer ticker.Stop()
+		resync = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changes:
+			if !c.debounce(ctx, changes) {
+				return nil
+			}
+			c.reconcile(ctx, c.takeChanged())
+		case <-resync:
+			// Workload changes can affect any finding, so everything is audited again
+			c.takeChanged()
+			c.refreshWorkloads(ctx)
+			c.reconcile(ctx, nil)
+		}
+	}
+}
+
+// debounce waits until no change has arrived for the debounce period. It returns false if
+// ctx is done first.
+func (c *Controller) debounce(ctx context.Context, changes <-chan struct{}) bool {
+	timer := time.NewTimer(c.options.Debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changes:
+			timer.Reset(c.options.Debounce)
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+func (c *Controller) refreshWorkloads(ctx context.Context) {
+	serviceAccounts, workloads, err := c.client.GetWorkloads(ctx, c.options.Namespace)
+	if err != nil {
+		log.Printf("Refreshing workloads failed: %v", err)
+		return
+	}
+	c.serviceAccounts, c.workloads = serviceAccounts, workloads
+}
+
+// takeChanged returns the objects changed since it was last called
+func (c *Controller) takeChanged() []audit.ObjectRef {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed := c.changed
+	c.changed = nil
+	if changed == nil {
+		// A nil list would audit every object
+		changed = []audit.ObjectRef{}
+	}
+	return changed
+}
+
+// reconcile audits the cached RBAC objects and publishes the results. Only the changed
+// objects and those bound to them are evaluated; a nil changed evaluates every object.
+func (c *Controller) reconcile(ctx context.Context, changed []audit.ObjectRef) {
+	resources := c.cache.Resources()
+	resources.ServiceAccounts = c.serviceAccounts
+	resources.Workloads = c.workloads
+	resources.Metadata = c.options.Metadata
+	resources.Metadata.Timestamp = time.Now()
+
+	report := c.auditor.Audit(resources, changed)
+	written, err := c.publisher.Publish(ctx, policyreport.Build(report, resources.Metadata.Timestamp))
+	if err != nil {
+		log.Printf("Publishing reports failed: %v", err)
+	}
+	if written > 0 {
+		log.Printf("Audited %d findings, %d reports written", len(report.Findings), written)
+	}
+}
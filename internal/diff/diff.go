@@ -0,0 +1,252 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Object change types
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// Report is the difference between two snapshots and their audit reports
+type Report struct {
+	From types.Metadata `json:"from"`
+	To   types.Metadata `json:"to"`
+	// Objects are the Roles, ClusterRoles and bindings added, removed or modified
+	Objects []ObjectChange `json:"objects"`
+	// NewFindings are in the later report only, ResolvedFindings in the earlier one only
+	NewFindings      []audit.AuditResult `json:"newFindings"`
+	ResolvedFindings []audit.AuditResult `json:"resolvedFindings"`
+	// Subjects are the subjects whose effective permissions changed
+	Subjects []SubjectChange `json:"subjects"`
+	Summary  Summary         `json:"summary"`
+}
+
+// ObjectChange is an RBAC object that differs between two snapshots
+type ObjectChange struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Change    string `json:"change"`
+}
+
+// SubjectChange lists the rules a subject gained and lost
+type SubjectChange struct {
+	Subject rbac.Subject `json:"subject"`
+	Added   []rbac.Grant `json:"added,omitempty"`
+	Removed []rbac.Grant `json:"removed,omitempty"`
+}
+
+// Widened reports whether the subject gained any rule
+func (c SubjectChange) Widened() bool {
+	return len(c.Added) > 0
+}
+
+// Summary counts the changes of a report
+type Summary struct {
+	ObjectsAdded     int `json:"objectsAdded"`
+	ObjectsRemoved   int `json:"objectsRemoved"`
+	ObjectsModified  int `json:"objectsModified"`
+	NewFindings      int `json:"newFindings"`
+	ResolvedFindings int `json:"resolvedFindings"`
+	SubjectsWidened  int `json:"subjectsWidened"`
+	SubjectsNarrowed int `json:"subjectsNarrowed"`
+}
+
+// Compare reports the changes from one snapshot and its audit report to another
+func Compare(from types.RBACResources, fromReport audit.AuditReport, to types.RBACResources, toReport audit.AuditReport) Report {
+	report := Report{
+		From:             from.Metadata,
+		To:               to.Metadata,
+		Objects:          CompareObjects(from, to),
+		NewFindings:      []audit.AuditResult{},
+		ResolvedFindings: []audit.AuditResult{},
+		Subjects:         CompareGrants(rbac.NewIndex(from), rbac.NewIndex(to)),
+	}
+	if report.Objects == nil {
+		report.Objects = []ObjectChange{}
+	}
+	if report.Subjects == nil {
+		report.Subjects = []SubjectChange{}
+	}
+
+	before := findingSet(fromReport.Findings)
+	after := findingSet(toReport.Findings)
+	for _, f := range toReport.Findings {
+		if !before[FindingKey(f)] {
+			report.NewFindings = append(report.NewFindings, f)
+		}
+	}
+	for _, f := range fromReport.Findings {
+		if !after[FindingKey(f)] {
+			report.ResolvedFindings = append(report.ResolvedFindings, f)
+		}
+	}
+
+	for _, c := range report.Objects {
+		switch c.Change {
+		case Added:
+			report.Summary.ObjectsAdded++
+		case Removed:
+			report.Summary.ObjectsRemoved++
+		case Modified:
+			report.Summary.ObjectsModified++
+		}
+	}
+	for _, c := range report.Subjects {
+		if c.Widened() {
+			report.Summary.SubjectsWidened++
+		}
+		if len(c.Removed) > 0 {
+			report.Summary.SubjectsNarrowed++
+		}
+	}
+	report.Summary.NewFindings = len(report.NewFindings)
+	report.Summary.ResolvedFindings = len(report.ResolvedFindings)
+	return report
+}
+
+// FindingKey identifies a finding across audits of different snapshots
+func FindingKey(f audit.AuditResult) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", f.RuleID, f.ResourceKind, f.Namespace, f.ResourceName, f.Reason)
+}
+
+// Introduced returns the findings of after that are not in before, or are more severe than
+// in before
+func Introduced(before, after audit.AuditReport) []audit.AuditResult {
+	existing := map[string]audit.RiskLevel{}
+	for _, f := range before.Findings {
+		existing[FindingKey(f)] = f.Risk
+	}
+	var findings []audit.AuditResult
+	for _, f := range after.Findings {
+		risk, ok := existing[FindingKey(f)]
+		if !ok || f.Risk.Score() > risk.Score() {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func findingSet(findings []audit.AuditResult) map[string]bool {
+	set := map[string]bool{}
+	for _, f := range findings {
+		set[FindingKey(f)] = true
+	}
+	return set
+}
+
+type objectKey struct{ kind, namespace, name string }
+
+// CompareObjects lists the Roles, ClusterRoles, RoleBindings and ClusterRoleBindings added,
+// removed or modified between two snapshots. Only rules, aggregation rules, role references
+// and subjects are compared; metadata changes are ignored.
+func CompareObjects(from, to types.RBACResources) []ObjectChange {
+	before, after := objectSpecs(from), objectSpecs(to)
+	var changes []ObjectChange
+	for key, spec := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			changes = append(changes, ObjectChange{Kind: key.kind, Namespace: key.namespace, Name: key.name, Change: Added})
+		case !equality.Semantic.DeepEqual(old, spec):
+			changes = append(changes, ObjectChange{Kind: key.kind, Namespace: key.namespace, Name: key.name, Change: Modified})
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, ObjectChange{Kind: key.kind, Namespace: key.namespace, Name: key.name, Change: Removed})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return changes
+}
+
+// objectSpecs returns the compared parts of each RBAC object in a snapshot
+func objectSpecs(resources types.RBACResources) map[objectKey]any {
+	specs := map[objectKey]any{}
+	for _, cr := range resources.ClusterRoles {
+		specs[objectKey{"ClusterRole", "", cr.Name}] = []any{cr.Rules, cr.AggregationRule}
+	}
+	for _, r := range resources.Roles {
+		specs[objectKey{"Role", r.Namespace, r.Name}] = r.Rules
+	}
+	for _, crb := range resources.ClusterRoleBindings {
+		specs[objectKey{"ClusterRoleBinding", "", crb.Name}] = []any{crb.RoleRef, crb.Subjects}
+	}
+	for _, rb := range resources.RoleBindings {
+		specs[objectKey{"RoleBinding", rb.Namespace, rb.Name}] = []any{rb.RoleRef, rb.Subjects}
+	}
+	return specs
+}
+
+// CompareGrants lists the subjects that gained or lost rules between two indexes. A rule
+// moved to another binding counts as both gained and lost.
+func CompareGrants(from, to *rbac.Index) []SubjectChange {
+	before, after := grantsBySubject(from), grantsBySubject(to)
+	changes := map[rbac.Subject]*SubjectChange{}
+	change := func(s rbac.Subject) *SubjectChange {
+		if changes[s] == nil {
+			changes[s] = &SubjectChange{Subject: s}
+		}
+		return changes[s]
+	}
+	for s, grants := range after {
+		for _, g := range grants {
+			if !containsGrant(before[s], g) {
+				change(s).Added = append(change(s).Added, g)
+			}
+		}
+	}
+	for s, grants := range before {
+		for _, g := range grants {
+			if !containsGrant(after[s], g) {
+				change(s).Removed = append(change(s).Removed, g)
+			}
+		}
+	}
+
+	var list []SubjectChange
+	for _, c := range changes {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Subject.String() < list[j].Subject.String() })
+	return list
+}
+
+func grantsBySubject(index *rbac.Index) map[rbac.Subject][]rbac.Grant {
+	grants := map[rbac.Subject][]rbac.Grant{}
+	for _, g := range index.Grants() {
+		grants[g.Subject] = append(grants[g.Subject], g)
+	}
+	return grants
+}
+
+// containsGrant reports whether grants hold the same rule through the same binding
+func containsGrant(grants []rbac.Grant, g rbac.Grant) bool {
+	for _, other := range grants {
+		if other.Binding.Kind == g.Binding.Kind && other.Binding.Namespace == g.Binding.Namespace &&
+			other.Binding.Name == g.Binding.Name && equality.Semantic.DeepEqual(other.Rule, g.Rule) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package diff
+
+import "testing"
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"both empty", "", "", ""},
+		{"added", "", "a\nb\n", "+ a\n+ b\n"},
+		{"removed", "a\nb\n", "", "- a\n- b\n"},
+		{"unchanged", "a\nb", "a\nb\n", "  a\n  b\n"},
+		{"line inserted", "a\nc\n", "a\nb\nc\n", "  a\n+ b\n  c\n"},
+		{"line replaced", "a\nb\nc\n", "a\nx\nc\n", "  a\n- b\n+ x\n  c\n"},
+		{"lines reordered", "a\nb\n", "b\na\n", "- a\n  b\n+ a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); got != tt.want {
+				t.Errorf("Lines(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package explore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// subjectDetails describes a subject: its bindings, the roles they refer to, its effective
+// permissions and the findings on them. ServiceAccounts also list the workloads running as them.
+func (m *model) subjectDetails(s rbac.Subject) ([]section, []link) {
+	overview := section{title: s.String()}
+	for _, score := range m.report.SubjectScores {
+		if score.Subject == s {
+			overview.lines = append(overview.lines, fmt.Sprintf("Risk score %d from %d rules", score.Score, score.Rules))
+			if len(score.Capabilities) > 0 {
+				overview.lines = append(overview.lines, "Capabilities: "+strings.Join(score.Capabilities, ", "))
+			}
+		}
+	}
+	if s.Kind == "ServiceAccount" {
+		for _, sa := range m.resources.ServiceAccounts {
+			if sa.Namespace == s.Namespace && sa.Name == s.Name && sa.AutomountToken != nil {
+				overview.lines = append(overview.lines, fmt.Sprintf("Automounts token: %t", *sa.AutomountToken))
+			}
+		}
+	}
+	sections := []section{overview}
+	var links []link
+
+	bindings := m.bindingsOf(s)
+	var bindingLines, roleLines []string
+	var findings []int
+	seenRoles := map[string]bool{}
+	for _, b := range bindings {
+		bindingLines = append(bindingLines, fmt.Sprintf("%s -> %s/%s", b, b.RoleRef.Kind, b.RoleRef.Name))
+		links = append(links, link{label: "Binding " + b.String(), target: bindingID(b)})
+		findings = append(findings, m.findings[bindingID(b)]...)
+
+		id := roleRefID(b)
+		if seenRoles[id] {
+			continue
+		}
+		seenRoles[id] = true
+		findings = append(findings, m.findings[id]...)
+		if _, ok := m.index.Rules(b); !ok {
+			roleLines = append(roleLines, fmt.Sprintf("%s/%s (missing)", b.RoleRef.Kind, b.RoleRef.Name))
+			continue
+		}
+		roleLines = append(roleLines, roleName(b))
+		links = append(links, link{label: "Role " + roleName(b), target: id})
+	}
+	sections = append(sections,
+		section{title: fmt.Sprintf("Bindings (%d)", len(bindings)), lines: bindingLines},
+		section{title: fmt.Sprintf("Roles (%d)", len(roleLines)), lines: roleLines},
+	)
+
+	var permissions []string
+	for _, g := range m.index.GrantsFor(s) {
+		scope := "cluster-wide"
+		if g.Namespace != "" {
+			scope = "in " + g.Namespace
+		}
+		permissions = append(permissions, fmt.Sprintf("%s (%s)", rbac.DescribeRule(g.Rule), scope))
+	}
+	sections = append(sections, section{title: fmt.Sprintf("Effective Permissions (%d)", len(permissions)), lines: permissions})
+
+	if s.Kind == "ServiceAccount" {
+		var workloads []string
+		for _, w := range m.resources.Workloads {
+			if w.Namespace == s.Namespace && w.ServiceAccount == s.Name {
+				workloads = append(workloads, fmt.Sprintf("%s/%s/%s (token mounted: %t)", w.Kind, w.Namespace, w.Name, w.AutomountToken))
+			}
+		}
+		if len(workloads) > 0 {
+			sections = append(sections, section{title: fmt.Sprintf("Workloads (%d)", len(workloads)), lines: workloads})
+		}
+	}
+
+	sections = append(sections, m.findingsSection(findings))
+	return sections, append(links, m.findingLinks(findings)...)
+}
+
+// roleDetails describes a role: its rules, the bindings that refer to it and its findings
+func (m *model) roleDetails(kind, namespace, name string, rules []rbacv1.PolicyRule, bindings []rbac.Binding) ([]section, []link) {
+	title := kind + "/" + name
+	if namespace != "" {
+		title = fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+	}
+	var ruleLines, bindingLines []string
+	for _, rule := range rules {
+		ruleLines = append(ruleLines, rbac.DescribeRule(rule))
+	}
+	var links []link
+	for _, b := range bindings {
+		bindingLines = append(bindingLines, b.String())
+		if _, ok := m.byID[bindingID(b)]; ok {
+			links = append(links, link{label: "Binding " + b.String(), target: bindingID(b)})
+		}
+	}
+
+	findings := m.findings[roleID(kind, namespace, name)]
+	sections := []section{
+		{title: title},
+		{title: fmt.Sprintf("Rules (%d)", len(rules)), lines: ruleLines},
+		{title: fmt.Sprintf("Bound By (%d)", len(bindings)), lines: bindingLines},
+		m.findingsSection(findings),
+	}
+	return sections, append(links, m.findingLinks(findings)...)
+}
+
+// bindingDetails describes a binding: its subjects, its role and their findings
+func (m *model) bindingDetails(b rbac.Binding) ([]section, []link) {
+	var links []link
+	var subjectLines []string
+	for _, bs := range b.Subjects {
+		s := rbac.NewSubject(bs, b.Namespace)
+		subjectLines = append(subjectLines, s.String())
+		if _, ok := m.byID[subjectID(s)]; ok {
+			links = append(links, link{label: "Subject " + s.String(), target: subjectID(s)})
+		}
+	}
+
+	roleLines := []string{roleName(b)}
+	if rules, ok := m.index.Rules(b); ok {
+		links = append(links, link{label: "Role " + roleName(b), target: roleRefID(b)})
+		for _, rule := range rules {
+			roleLines = append(roleLines, "  "+rbac.DescribeRule(rule))
+		}
+	} else {
+		roleLines[0] += " (missing)"
+	}
+
+	findings := append(append([]int{}, m.findings[bindingID(b)]...), m.findings[roleRefID(b)]...)
+	sections := []section{
+		{title: b.String()},
+		{title: fmt.Sprintf("Subjects (%d)", len(b.Subjects)), lines: subjectLines},
+		{title: "Role", lines: roleLines},
+		m.findingsSection(findings),
+	}
+	return sections, append(links, m.findingLinks(findings)...)
+}
+
+// findingDetails describes a finding and links to the object it is about
+func (m *model) findingDetails(f audit.AuditResult) ([]section, []link) {
+	lines := []string{
+		fmt.Sprintf("Severity: %s (score %d)", f.Risk, f.Score),
+		"Check: " + f.RuleID,
+		"Object: " + objectName(f),
+		"Reason: " + f.Reason,
+	}
+	if f.Exposure != "" {
+		lines = append(lines, "Exposure: "+f.Exposure)
+	}
+	sections := []section{{title: "Finding", lines: lines}}
+	if f.Remediation != "" {
+		sections = append(sections, section{title: "Remediation", lines: strings.Split(f.Remediation, "\n")})
+	}
+
+	var links []link
+	if id := objectID(f); id != "" {
+		if _, ok := m.byID[id]; ok {
+			links = append(links, link{label: objectName(f), target: id})
+		}
+	}
+	return sections, links
+}
+
+// findingsSection lists findings by their index in the report
+func (m *model) findingsSection(findings []int) section {
+	s := section{title: fmt.Sprintf("Findings (%d)", len(findings))}
+	for _, i := range findings {
+		f := m.report.Findings[i]
+		s.lines = append(s.lines, fmt.Sprintf("[%s] %s: %s", f.Risk, f.RuleID, f.Reason))
+	}
+	return s
+}
+
+func (m *model) findingLinks(findings []int) []link {
+	var links []link
+	for _, i := range findings {
+		f := m.report.Findings[i]
+		links = append(links, link{label: fmt.Sprintf("Finding %s on %s", f.RuleID, objectName(f)), target: findingID(i)})
+	}
+	return links
+}
+
+// roleName returns the role a binding refers to, with its namespace for Roles
+func roleName(b rbac.Binding) string {
+	if b.RoleRef.Kind == "Role" {
+		return fmt.Sprintf("Role/%s/%s", b.Namespace, b.RoleRef.Name)
+	}
+	return b.RoleRef.Kind + "/" + b.RoleRef.Name
+}
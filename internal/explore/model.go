@@ -0,0 +1,265 @@
+package explore
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Views of the explorer
+const (
+	viewSubjects = iota
+	viewRoles
+	viewBindings
+	viewFindings
+)
+
+var viewNames = []string{"Subjects", "Roles", "Bindings", "Findings"}
+
+var viewColumns = [][]string{
+	{"KIND", "SUBJECT", "SCORE", "BINDINGS", "FINDINGS"},
+	{"KIND", "NAMESPACE", "NAME", "RULES", "BINDINGS", "FINDINGS"},
+	{"KIND", "NAMESPACE", "NAME", "ROLE", "SUBJECTS", "FINDINGS"},
+	{"SEVERITY", "SCORE", "CHECK", "OBJECT", "REASON"},
+}
+
+// entry is a single row of a view
+type entry struct {
+	id    string
+	view  int
+	cells []string
+	// namespaces are the namespaces the entry relates to, for filtering
+	namespaces []string
+	// risk is the most severe finding of the entry, empty if it has none
+	risk audit.RiskLevel
+	// details describes the entry and the entries it relates to
+	details func() ([]section, []link)
+}
+
+// link is a related entry shown in the details of another
+type link struct {
+	label  string
+	target string
+}
+
+// section is a titled block of the details of an entry
+type section struct {
+	title string
+	lines []string
+}
+
+// model holds the entries of a snapshot and its audit report
+type model struct {
+	resources  types.RBACResources
+	index      *rbac.Index
+	report     audit.AuditReport
+	views      [4][]*entry
+	byID       map[string]*entry
+	namespaces []string
+	// findings of each object, by object ID
+	findings map[string][]int
+}
+
+func subjectID(s rbac.Subject) string { return "subject:" + s.String() }
+func roleID(kind, namespace, name string) string {
+	if kind == "ClusterRole" {
+		namespace = ""
+	}
+	return fmt.Sprintf("role:%s/%s/%s", kind, namespace, name)
+}
+func bindingID(b rbac.Binding) string { return "binding:" + b.String() }
+func findingID(i int) string          { return "finding:" + strconv.Itoa(i) }
+
+// objectID returns the ID of the role or binding a finding is about
+func objectID(f audit.AuditResult) string {
+	switch f.ResourceKind {
+	case "Role", "ClusterRole":
+		return roleID(f.ResourceKind, f.Namespace, f.ResourceName)
+	case "RoleBinding", "ClusterRoleBinding":
+		return bindingID(rbac.Binding{Kind: f.ResourceKind, Namespace: f.Namespace, Name: f.ResourceName})
+	}
+	return ""
+}
+
+// newModel builds the entries of every view. System subjects and bindings are left out
+// unless the audit included system components.
+func newModel(resources types.RBACResources, report audit.AuditReport, options audit.AuditOptions) *model {
+	m := &model{
+		resources: resources,
+		index:     rbac.NewIndex(resources),
+		report:    report,
+		byID:      map[string]*entry{},
+		findings:  map[string][]int{},
+	}
+	namespaces := map[string]bool{}
+	addNamespace := func(ns string) {
+		if ns != "" {
+			namespaces[ns] = true
+		}
+	}
+
+	for i, f := range report.Findings {
+		id := objectID(f)
+		m.findings[id] = append(m.findings[id], i)
+		addNamespace(f.Namespace)
+		m.add(&entry{
+			id:         findingID(i),
+			view:       viewFindings,
+			cells:      []string{string(f.Risk), strconv.Itoa(f.Score), f.RuleID, objectName(f), f.Reason},
+			namespaces: []string{f.Namespace},
+			risk:       f.Risk,
+			details:    func() ([]section, []link) { return m.findingDetails(f) },
+		})
+	}
+
+	scores := map[rbac.Subject]int{}
+	for _, s := range report.SubjectScores {
+		scores[s.Subject] = s.Score
+	}
+	for _, s := range m.index.Subjects() {
+		if !options.IncludeSystemComponents && audit.IsSystemSubject(s) {
+			continue
+		}
+		bindings := m.bindingsOf(s)
+		e := &entry{id: subjectID(s), view: viewSubjects, details: func() ([]section, []link) { return m.subjectDetails(s) }}
+		if s.Namespace != "" {
+			e.namespaces = append(e.namespaces, s.Namespace)
+		}
+		findings := 0
+		for _, b := range bindings {
+			e.namespaces = append(e.namespaces, b.Namespace)
+			for _, id := range []string{bindingID(b), roleRefID(b)} {
+				findings += len(m.findings[id])
+				e.risk = m.maxRisk(e.risk, id)
+			}
+		}
+		name := s.Name
+		if s.Namespace != "" {
+			name = s.Namespace + "/" + s.Name
+		}
+		e.cells = []string{s.Kind, name, strconv.Itoa(scores[s]), strconv.Itoa(len(bindings)), strconv.Itoa(findings)}
+		m.add(e)
+	}
+
+	for _, cr := range resources.ClusterRoles {
+		id := roleID("ClusterRole", "", cr.Name)
+		bindings := m.index.BindingsFor("ClusterRole", "", cr.Name)
+		e := &entry{id: id, view: viewRoles, risk: m.maxRisk("", id), details: func() ([]section, []link) {
+			return m.roleDetails("ClusterRole", "", cr.Name, cr.Rules, bindings)
+		}}
+		for _, b := range bindings {
+			e.namespaces = append(e.namespaces, b.Namespace)
+		}
+		e.cells = []string{"ClusterRole", "", cr.Name, strconv.Itoa(len(cr.Rules)), strconv.Itoa(len(bindings)), strconv.Itoa(len(m.findings[id]))}
+		m.add(e)
+	}
+	for _, r := range resources.Roles {
+		id := roleID("Role", r.Namespace, r.Name)
+		addNamespace(r.Namespace)
+		bindings := m.index.BindingsFor("Role", r.Namespace, r.Name)
+		m.add(&entry{
+			id:         id,
+			view:       viewRoles,
+			cells:      []string{"Role", r.Namespace, r.Name, strconv.Itoa(len(r.Rules)), strconv.Itoa(len(bindings)), strconv.Itoa(len(m.findings[id]))},
+			namespaces: []string{r.Namespace},
+			risk:       m.maxRisk("", id),
+			details: func() ([]section, []link) {
+				return m.roleDetails("Role", r.Namespace, r.Name, r.Rules, bindings)
+			},
+		})
+	}
+
+	for _, b := range m.index.Bindings() {
+		if !options.IncludeSystemComponents && audit.IsSystemBinding(b) {
+			continue
+		}
+		id := bindingID(b)
+		addNamespace(b.Namespace)
+		var subjects []string
+		for _, s := range b.Subjects {
+			subjects = append(subjects, rbac.NewSubject(s, b.Namespace).String())
+		}
+		m.add(&entry{
+			id:         id,
+			view:       viewBindings,
+			cells:      []string{b.Kind, b.Namespace, b.Name, b.RoleRef.Kind + "/" + b.RoleRef.Name, strings.Join(subjects, ", "), strconv.Itoa(len(m.findings[id]))},
+			namespaces: []string{b.Namespace},
+			risk:       m.maxRisk(m.maxRisk("", id), roleRefID(b)),
+			details:    func() ([]section, []link) { return m.bindingDetails(b) },
+		})
+	}
+
+	for ns := range namespaces {
+		m.namespaces = append(m.namespaces, ns)
+	}
+	sort.Strings(m.namespaces)
+	return m
+}
+
+func (m *model) add(e *entry) {
+	m.views[e.view] = append(m.views[e.view], e)
+	m.byID[e.id] = e
+}
+
+// maxRisk returns the more severe of risk and the findings of the object
+func (m *model) maxRisk(risk audit.RiskLevel, id string) audit.RiskLevel {
+	for _, i := range m.findings[id] {
+		if f := m.report.Findings[i]; risk == "" || f.Risk.Score() > risk.Score() {
+			risk = f.Risk
+		}
+	}
+	return risk
+}
+
+// roleRefID returns the ID of the role a binding refers to
+func roleRefID(b rbac.Binding) string {
+	return roleID(b.RoleRef.Kind, b.Namespace, b.RoleRef.Name)
+}
+
+// bindingsOf returns the bindings that name a subject
+func (m *model) bindingsOf(s rbac.Subject) []rbac.Binding {
+	var bindings []rbac.Binding
+	for _, b := range m.index.Bindings() {
+		if slices.ContainsFunc(b.Subjects, func(bs rbacv1.Subject) bool { return rbac.NewSubject(bs, b.Namespace) == s }) {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+func objectName(f audit.AuditResult) string {
+	if f.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", f.ResourceKind, f.Namespace, f.ResourceName)
+	}
+	return f.ResourceKind + "/" + f.ResourceName
+}
+
+// filter holds the criteria entries are shown by
+type filter struct {
+	search    string
+	namespace string
+	// severity is the least severe risk shown; empty shows all entries
+	severity audit.RiskLevel
+}
+
+// matches reports whether an entry satisfies the filter
+func (f filter) matches(e *entry) bool {
+	if f.namespace != "" && !slices.Contains(e.namespaces, f.namespace) {
+		return false
+	}
+	if f.severity != "" && (e.risk == "" || e.risk.Score() < f.severity.Score()) {
+		return false
+	}
+	if f.search != "" {
+		text := strings.ToLower(strings.Join(e.cells, " "))
+		return strings.Contains(text, strings.ToLower(f.search))
+	}
+	return true
+}
@@ -0,0 +1,304 @@
+package explore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const helpText = "[yellow]1-4[-] views  [yellow]/[-] search  [yellow]Tab[-] next pane  [yellow]Enter[-] open  [yellow]Esc[-] back  [yellow]q[-] quit"
+
+var riskColors = map[audit.RiskLevel]tcell.Color{
+	audit.RiskCritical: tcell.ColorRed,
+	audit.RiskHigh:     tcell.ColorOrangeRed,
+	audit.RiskMedium:   tcell.ColorYellow,
+	audit.RiskLow:      tcell.ColorDodgerBlue,
+	audit.RiskInfo:     tcell.ColorGray,
+}
+
+// ui is the state of the explorer
+type ui struct {
+	model  *model
+	app    *tview.Application
+	view   int
+	filter filter
+	// rows are the entries shown in the table, in order
+	rows []*entry
+	// history holds the IDs of the entries navigated away from, most recent last
+	history []string
+
+	tabs      *tview.TextView
+	search    *tview.InputField
+	namespace *tview.DropDown
+	severity  *tview.DropDown
+	table     *tview.Table
+	details   *tview.TextView
+	links     *tview.List
+	focus     []tview.Primitive
+}
+
+// Run starts the interactive explorer on a snapshot and its audit report and blocks until
+// the user quits
+func Run(resources types.RBACResources, report audit.AuditReport, options audit.AuditOptions) error {
+	return newUI(newModel(resources, report, options)).app.Run()
+}
+
+func newUI(m *model) *ui {
+	u := &ui{model: m, app: tview.NewApplication()}
+
+	u.tabs = tview.NewTextView().SetDynamicColors(true)
+	u.search = tview.NewInputField().SetLabel("Search: ").SetFieldWidth(30)
+	u.search.SetChangedFunc(func(text string) {
+		u.filter.search = text
+		u.refresh("")
+	})
+	u.search.SetDoneFunc(func(tcell.Key) { u.app.SetFocus(u.table) })
+
+	u.namespace = tview.NewDropDown().SetLabel("Namespace: ")
+	u.namespace.SetOptions(append([]string{"all"}, m.namespaces...), nil)
+	u.namespace.SetCurrentOption(0)
+	u.namespace.SetSelectedFunc(func(text string, index int) {
+		u.filter.namespace = ""
+		if index > 0 {
+			u.filter.namespace = text
+		}
+		u.refresh("")
+	})
+
+	severities := []string{"all"}
+	for _, level := range audit.RiskLevels {
+		severities = append(severities, string(level))
+	}
+	u.severity = tview.NewDropDown().SetLabel("Min severity: ")
+	u.severity.SetOptions(severities, nil)
+	u.severity.SetCurrentOption(0)
+	u.severity.SetSelectedFunc(func(text string, index int) {
+		u.filter.severity = ""
+		if index > 0 {
+			u.filter.severity = audit.RiskLevel(text)
+		}
+		u.refresh("")
+	})
+
+	u.table = tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	u.table.SetBorder(true)
+	u.table.SetSelectionChangedFunc(func(row, column int) { u.showDetails() })
+	u.table.SetSelectedFunc(func(row, column int) {
+		if u.links.GetItemCount() > 0 {
+			u.app.SetFocus(u.links)
+		}
+	})
+
+	u.details = tview.NewTextView().SetDynamicColors(true).SetWrap(true).SetWordWrap(true)
+	u.details.SetBorder(true).SetTitle(" Details ")
+	u.links = tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	u.links.SetBorder(true).SetTitle(" Related (Enter to open) ")
+
+	filters := tview.NewFlex().
+		AddItem(u.search, 0, 2, false).
+		AddItem(u.namespace, 0, 1, false).
+		AddItem(u.severity, 0, 1, false)
+	side := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.details, 0, 3, false).
+		AddItem(u.links, 0, 1, false)
+	body := tview.NewFlex().
+		AddItem(u.table, 0, 3, true).
+		AddItem(side, 0, 2, false)
+	help := tview.NewTextView().SetDynamicColors(true).SetText(helpText)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.tabs, 1, 0, false).
+		AddItem(filters, 1, 0, false).
+		AddItem(body, 0, 1, true).
+		AddItem(help, 1, 0, false)
+
+	u.focus = []tview.Primitive{u.table, u.links, u.search, u.namespace, u.severity}
+	u.app.SetRoot(root, true).SetFocus(u.table).SetInputCapture(u.handleKey)
+	u.switchView(viewSubjects, "")
+	return u
+}
+
+// handleKey handles the keys that work across panes. Typing in the search field is left alone.
+func (u *ui) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyTab, tcell.KeyBacktab:
+		u.cycleFocus(event.Key() == tcell.KeyTab)
+		return nil
+	case tcell.KeyEscape:
+		if u.app.GetFocus() != u.table && u.app.GetFocus() != u.links {
+			return event
+		}
+		u.back()
+		return nil
+	case tcell.KeyCtrlC:
+		u.app.Stop()
+		return nil
+	}
+	if u.app.GetFocus() == u.search || event.Key() != tcell.KeyRune {
+		return event
+	}
+	switch r := event.Rune(); {
+	case r >= '1' && r <= '4':
+		u.switchView(int(r-'1'), "")
+	case r == '/':
+		u.app.SetFocus(u.search)
+	case r == 'q':
+		u.app.Stop()
+	default:
+		return event
+	}
+	return nil
+}
+
+func (u *ui) cycleFocus(forward bool) {
+	current := 0
+	for i, p := range u.focus {
+		if p.HasFocus() {
+			current = i
+		}
+	}
+	step := 1
+	if !forward {
+		step = len(u.focus) - 1
+	}
+	u.app.SetFocus(u.focus[(current+step)%len(u.focus)])
+}
+
+// switchView shows a view, selecting the entry with the given ID if it is shown
+func (u *ui) switchView(view int, id string) {
+	u.view = view
+	var tabs []string
+	for i, name := range viewNames {
+		label := fmt.Sprintf("%d %s (%d)", i+1, name, len(u.model.views[i]))
+		if i == view {
+			label = "[black:yellow] " + label + " [-:-]"
+		} else {
+			label = " " + label + " "
+		}
+		tabs = append(tabs, label)
+	}
+	u.tabs.SetText(strings.Join(tabs, " "))
+	u.table.SetTitle(" " + viewNames[view] + " ")
+	u.refresh(id)
+}
+
+// refresh fills the table with the entries of the current view that match the filter. The
+// entry with the given ID is selected, or the previously selected one if id is empty.
+func (u *ui) refresh(id string) {
+	if id == "" {
+		if e := u.selected(); e != nil && e.view == u.view {
+			id = e.id
+		}
+	}
+
+	u.table.Clear()
+	for col, name := range viewColumns[u.view] {
+		u.table.SetCell(0, col, tview.NewTableCell(name).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	u.rows = u.rows[:0]
+	selected := 1
+	for _, e := range u.model.views[u.view] {
+		if !u.filter.matches(e) {
+			continue
+		}
+		u.rows = append(u.rows, e)
+		row := len(u.rows)
+		if e.id == id {
+			selected = row
+		}
+		for col, text := range e.cells {
+			cell := tview.NewTableCell(tview.Escape(text)).SetMaxWidth(60)
+			if col == 0 && e.risk != "" {
+				cell.SetTextColor(riskColors[e.risk])
+			}
+			u.table.SetCell(row, col, cell)
+		}
+	}
+	u.table.ScrollToBeginning()
+	u.table.Select(selected, 0)
+	u.showDetails()
+}
+
+func (u *ui) selected() *entry {
+	row, _ := u.table.GetSelection()
+	if row < 1 || row > len(u.rows) {
+		return nil
+	}
+	return u.rows[row-1]
+}
+
+// showDetails describes the selected entry in the side panes
+func (u *ui) showDetails() {
+	u.details.Clear()
+	u.links.Clear()
+	e := u.selected()
+	if e == nil {
+		u.details.SetText("No matching entries")
+		return
+	}
+
+	sections, links := e.details()
+	var b strings.Builder
+	for i, s := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[yellow::b]%s[-::-]\n", tview.Escape(s.title))
+		for _, line := range s.lines {
+			fmt.Fprintf(&b, "  %s\n", colorRisk(tview.Escape(line)))
+		}
+		if len(s.lines) == 0 && i > 0 {
+			b.WriteString("  [gray]none[-]\n")
+		}
+	}
+	u.details.SetText(b.String()).ScrollToBeginning()
+
+	for _, l := range links {
+		target := l.target
+		u.links.AddItem(tview.Escape(l.label), "", 0, func() { u.open(target, true) })
+	}
+}
+
+// colorRisk colours the severity tags of finding lines
+func colorRisk(line string) string {
+	for level, color := range riskColors {
+		tag := "[" + string(level) + "[]"
+		if strings.HasPrefix(line, tag) {
+			return fmt.Sprintf("[%s]%s[-]%s", color.Name(), tag, strings.TrimPrefix(line, tag))
+		}
+	}
+	return line
+}
+
+// open shows the entry with the given ID, clearing the filter if it hides the entry. With
+// remember, the current entry is pushed onto the history so Esc returns to it.
+func (u *ui) open(id string, remember bool) {
+	e, ok := u.model.byID[id]
+	if !ok {
+		return
+	}
+	if current := u.selected(); remember && current != nil {
+		u.history = append(u.history, current.id)
+	}
+	if !u.filter.matches(e) {
+		u.filter = filter{}
+		u.search.SetText("")
+		u.namespace.SetCurrentOption(0)
+		u.severity.SetCurrentOption(0)
+	}
+	u.switchView(e.view, id)
+	u.app.SetFocus(u.table)
+}
+
+// back returns to the entry navigated away from most recently
+func (u *ui) back() {
+	if len(u.history) == 0 {
+		return
+	}
+	id := u.history[len(u.history)-1]
+	u.history = u.history[:len(u.history)-1]
+	u.open(id, false)
+}
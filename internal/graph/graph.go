@@ -0,0 +1,253 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Node kinds
+const (
+	KindSubject  = "Subject"
+	KindBinding  = "Binding"
+	KindRole     = "Role"
+	KindResource = "Resource"
+)
+
+// Graph is the RBAC model as subjects, bindings, roles and the resources they grant access to
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []Edge  `json:"edges"`
+}
+
+// Node is a single subject, binding, role or resource
+type Node struct {
+	// ID is unique within the graph and stable across runs on the same snapshot
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Label     string `json:"label"`
+	Namespace string `json:"namespace,omitempty"`
+	// Type is the Kubernetes kind of the object, e.g. ServiceAccount or ClusterRole
+	Type string `json:"type"`
+	// Risk is the most severe finding on the node. Subjects take the most severe finding
+	// on their bindings and roles.
+	Risk     audit.RiskLevel `json:"risk,omitempty"`
+	Findings []string        `json:"findings,omitempty"`
+	// Missing marks roles referenced by a binding but absent from the snapshot
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Edge connects a subject to a binding, a binding to its role or a role to a resource
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Label holds the verbs of role to resource edges
+	Label string `json:"label,omitempty"`
+}
+
+// Options select the part of the RBAC model to graph
+type Options struct {
+	// Subjects limits the graph to these subjects, in the Kind:name or
+	// ServiceAccount:namespace/name form
+	Subjects []string
+	// Namespaces limits RoleBindings to these namespaces. ClusterRoleBindings are kept.
+	Namespaces []string
+	// OnlyRisky keeps only bindings where the binding or its role has findings
+	OnlyRisky bool
+	// IncludeSystem keeps system subjects and bindings
+	IncludeSystem bool
+}
+
+// Build graphs the bindings of a snapshot selected by the options, colouring nodes with the
+// findings of the audit report
+func Build(resources types.RBACResources, report audit.AuditReport, options Options) *Graph {
+	index := rbac.NewIndex(resources)
+	findings := FindingsByObject(report)
+	b := &builder{graph: &Graph{}, nodes: map[string]*Node{}, edges: map[Edge]bool{}}
+
+	for _, sel := range selectBindings(index, findings, options) {
+		binding := sel.binding
+		var subjectNodes []*Node
+		for _, subject := range sel.subjects {
+			subjectNodes = append(subjectNodes, b.node("subject:"+subject.String(), func() *Node {
+				label := subject.Name
+				if subject.Namespace != "" {
+					label = subject.Namespace + "/" + subject.Name
+				}
+				return &Node{Kind: KindSubject, Type: subject.Kind, Label: label, Namespace: subject.Namespace}
+			}))
+		}
+		bindingNode := b.node("binding:"+binding.String(), func() *Node {
+			return &Node{Kind: KindBinding, Type: binding.Kind, Label: binding.Name, Namespace: binding.Namespace}
+		})
+		addFindings(bindingNode, findings[bindingKey(binding)])
+		roleNode := b.node("role:"+roleKey(binding), func() *Node {
+			return &Node{Kind: KindRole, Type: binding.RoleRef.Kind, Label: binding.RoleRef.Name, Namespace: roleNamespace(binding)}
+		})
+		addFindings(roleNode, findings[roleKey(binding)])
+
+		for _, subjectNode := range subjectNodes {
+			subjectNode.Risk = maxRisk(subjectNode.Risk, bindingNode.Risk, roleNode.Risk)
+			b.edge(subjectNode.ID, bindingNode.ID, "")
+		}
+		b.edge(bindingNode.ID, roleNode.ID, "")
+
+		rules, ok := index.Rules(binding)
+		if !ok {
+			roleNode.Missing = true
+			continue
+		}
+		for _, rule := range rules {
+			verbs := strings.Join(rule.Verbs, ",")
+			for _, resource := range ruleResources(rule) {
+				resourceNode := b.node("resource:"+resource, func() *Node {
+					return &Node{Kind: KindResource, Type: KindResource, Label: resource}
+				})
+				b.edge(roleNode.ID, resourceNode.ID, verbs)
+			}
+		}
+	}
+	return b.graph
+}
+
+// selection is a binding chosen for a graph, with the subjects of it to include
+type selection struct {
+	binding  rbac.Binding
+	subjects []rbac.Subject
+}
+
+// selectBindings returns the bindings of the index selected by the options
+func selectBindings(index *rbac.Index, findings map[string][]audit.AuditResult, options Options) []selection {
+	var selected []selection
+	for _, binding := range index.Bindings() {
+		if !options.IncludeSystem && audit.IsSystemBinding(binding) {
+			continue
+		}
+		if binding.Kind == "RoleBinding" && len(options.Namespaces) > 0 && !slices.Contains(options.Namespaces, binding.Namespace) {
+			continue
+		}
+		if options.OnlyRisky && len(findings[bindingKey(binding)]) == 0 && len(findings[roleKey(binding)]) == 0 {
+			continue
+		}
+
+		var subjects []rbac.Subject
+		for _, s := range binding.Subjects {
+			subject := rbac.NewSubject(s, binding.Namespace)
+			if len(options.Subjects) > 0 && !slices.Contains(options.Subjects, subject.String()) {
+				continue
+			}
+			if len(options.Subjects) == 0 && !options.IncludeSystem && audit.IsSystemSubject(subject) {
+				continue
+			}
+			subjects = append(subjects, subject)
+		}
+		if len(subjects) > 0 {
+			selected = append(selected, selection{binding: binding, subjects: subjects})
+		}
+	}
+	return selected
+}
+
+type builder struct {
+	graph *Graph
+	nodes map[string]*Node
+	edges map[Edge]bool
+}
+
+// node returns the node with the given ID, adding it with create if it is new
+func (b *builder) node(id string, create func() *Node) *Node {
+	if n, ok := b.nodes[id]; ok {
+		return n
+	}
+	n := create()
+	n.ID = id
+	b.nodes[id] = n
+	b.graph.Nodes = append(b.graph.Nodes, n)
+	return n
+}
+
+func (b *builder) edge(from, to, label string) {
+	e := Edge{From: from, To: to, Label: label}
+	if !b.edges[e] {
+		b.edges[e] = true
+		b.graph.Edges = append(b.graph.Edges, e)
+	}
+}
+
+// ObjectKey identifies an RBAC object as Kind/namespace/name, or Kind/name when cluster-scoped
+func ObjectKey(kind, namespace, name string) string {
+	if namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+	}
+	return kind + "/" + name
+}
+
+// FindingsByObject groups the findings of a report by the object they are about
+func FindingsByObject(report audit.AuditReport) map[string][]audit.AuditResult {
+	findings := map[string][]audit.AuditResult{}
+	for _, f := range report.Findings {
+		key := ObjectKey(f.ResourceKind, f.Namespace, f.ResourceName)
+		findings[key] = append(findings[key], f)
+	}
+	return findings
+}
+
+// bindingKey returns the object key of a binding
+func bindingKey(b rbac.Binding) string {
+	return ObjectKey(b.Kind, b.Namespace, b.Name)
+}
+
+// roleKey returns the object key of the role a binding refers to
+func roleKey(b rbac.Binding) string {
+	return ObjectKey(b.RoleRef.Kind, roleNamespace(b), b.RoleRef.Name)
+}
+
+// roleNamespace returns the namespace of the role a binding refers to, empty for ClusterRoles
+func roleNamespace(b rbac.Binding) string {
+	if b.RoleRef.Kind == "ClusterRole" {
+		return ""
+	}
+	return b.Namespace
+}
+
+// ruleResources lists the resources of a rule as group/resource, core resources without a
+// group, and its non-resource URLs as they are
+func ruleResources(rule rbacv1.PolicyRule) []string {
+	var resources []string
+	for _, group := range rule.APIGroups {
+		for _, resource := range rule.Resources {
+			if group != "" {
+				resource = group + "/" + resource
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return append(resources, rule.NonResourceURLs...)
+}
+
+func addFindings(n *Node, findings []audit.AuditResult) {
+	for _, f := range findings {
+		if !slices.Contains(n.Findings, f.RuleID) {
+			n.Findings = append(n.Findings, f.RuleID)
+		}
+		n.Risk = maxRisk(n.Risk, f.Risk)
+	}
+	sort.Strings(n.Findings)
+}
+
+// maxRisk returns the most severe of the given risk levels, empty if all are empty
+func maxRisk(levels ...audit.RiskLevel) audit.RiskLevel {
+	var max audit.RiskLevel
+	for _, level := range levels {
+		if level != "" && (max == "" || level.Score() > max.Score()) {
+			max = level
+		}
+	}
+	return max
+}
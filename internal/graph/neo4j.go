@@ -0,0 +1,386 @@
+package graph
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+)
+
+// Node labels of the property graph
+const (
+	LabelSubject   = "Subject"
+	LabelBinding   = "Binding"
+	LabelRole      = "Role"
+	LabelRule      = "Rule"
+	LabelNamespace = "Namespace"
+	LabelResource  = "Resource"
+)
+
+// Relationship types of the property graph
+const (
+	RelHasBinding  = "HAS_BINDING"
+	RelGrants      = "GRANTS"
+	RelHasRule     = "HAS_RULE"
+	RelAllows      = "ALLOWS"
+	RelInNamespace = "IN_NAMESPACE"
+)
+
+// Property types, named as in neo4j-admin CSV headers
+const (
+	typeString      = "string"
+	typeInt         = "int"
+	typeBoolean     = "boolean"
+	typeStringArray = "string[]"
+)
+
+type column struct {
+	name string
+	typ  string
+}
+
+// findingColumns hold the audit findings of bindings and roles
+var findingColumns = []column{
+	{"risk", typeString},
+	{"riskScore", typeInt},
+	{"findings", typeStringArray},
+	{"findingReasons", typeStringArray},
+}
+
+// nodeColumns are the properties of each node label, besides id
+var nodeColumns = map[string][]column{
+	LabelSubject: {
+		{"kind", typeString}, {"name", typeString}, {"namespace", typeString},
+		{"riskScore", typeInt}, {"capabilities", typeStringArray},
+	},
+	LabelBinding: append([]column{
+		{"kind", typeString}, {"name", typeString}, {"namespace", typeString},
+	}, findingColumns...),
+	LabelRole: append([]column{
+		{"kind", typeString}, {"name", typeString}, {"namespace", typeString}, {"missing", typeBoolean},
+	}, findingColumns...),
+	LabelRule: {
+		{"verbs", typeStringArray}, {"apiGroups", typeStringArray}, {"resources", typeStringArray},
+		{"resourceNames", typeStringArray}, {"nonResourceURLs", typeStringArray},
+	},
+	LabelNamespace: {{"name", typeString}},
+	LabelResource:  {{"apiGroup", typeString}, {"resource", typeString}, {"nonResourceURL", typeBoolean}},
+}
+
+// relationshipColumns are the properties of each relationship type
+var relationshipColumns = map[string][]column{
+	RelAllows: {{"verbs", typeStringArray}},
+}
+
+var nodeLabels = []string{LabelNamespace, LabelSubject, LabelBinding, LabelRole, LabelRule, LabelResource}
+var relationshipTypes = []string{RelHasBinding, RelGrants, RelHasRule, RelAllows, RelInNamespace}
+
+// PropertyGraph is the RBAC model as labelled nodes and typed relationships, for graph
+// databases such as Neo4j
+type PropertyGraph struct {
+	Nodes         []PropertyNode
+	Relationships []Relationship
+}
+
+// PropertyNode is a node of the property graph. Properties are keyed by the names in
+// nodeColumns; absent properties are left unset.
+type PropertyNode struct {
+	ID         string
+	Label      string
+	Properties map[string]any
+}
+
+// Relationship is a directed, typed edge between two nodes
+type Relationship struct {
+	From       string
+	To         string
+	Type       string
+	Properties map[string]any
+}
+
+// BuildPropertyGraph builds the property graph of the bindings selected by the options, and
+// of the subjects, roles, rules, resources and namespaces they reach. Bindings and roles
+// carry their audit findings as properties; subjects carry their risk score.
+func BuildPropertyGraph(resources types.RBACResources, report audit.AuditReport, options Options) *PropertyGraph {
+	index := rbac.NewIndex(resources)
+	findings := FindingsByObject(report)
+	g := &PropertyGraph{}
+	seen := map[string]bool{}
+	addNode := func(id, label string, properties map[string]any) bool {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+		g.Nodes = append(g.Nodes, PropertyNode{ID: id, Label: label, Properties: properties})
+		return true
+	}
+	relate := func(from, to, typ string, properties map[string]any) {
+		g.Relationships = append(g.Relationships, Relationship{From: from, To: to, Type: typ, Properties: properties})
+	}
+	inNamespace := func(id, namespace string) {
+		if namespace == "" {
+			return
+		}
+		nsID := "namespace:" + namespace
+		addNode(nsID, LabelNamespace, map[string]any{"name": namespace})
+		relate(id, nsID, RelInNamespace, nil)
+	}
+
+	scores := map[rbac.Subject]audit.SubjectScore{}
+	for _, s := range report.SubjectScores {
+		scores[s.Subject] = s
+	}
+
+	for _, sel := range selectBindings(index, findings, options) {
+		binding := sel.binding
+		bindingID := "binding:" + binding.String()
+		properties := map[string]any{"kind": binding.Kind, "name": binding.Name, "namespace": binding.Namespace}
+		addFindingProperties(properties, findings[bindingKey(binding)])
+		addNode(bindingID, LabelBinding, properties)
+		inNamespace(bindingID, binding.Namespace)
+
+		for _, subject := range sel.subjects {
+			subjectID := "subject:" + subject.String()
+			properties := map[string]any{"kind": subject.Kind, "name": subject.Name, "namespace": subject.Namespace}
+			if score, ok := scores[subject]; ok {
+				properties["riskScore"] = score.Score
+				properties["capabilities"] = score.Capabilities
+			}
+			if addNode(subjectID, LabelSubject, properties) {
+				inNamespace(subjectID, subject.Namespace)
+			}
+			relate(subjectID, bindingID, RelHasBinding, nil)
+		}
+
+		roleID := "role:" + roleKey(binding)
+		rules, ok := index.Rules(binding)
+		relate(bindingID, roleID, RelGrants, nil)
+		properties = map[string]any{
+			"kind": binding.RoleRef.Kind, "name": binding.RoleRef.Name, "namespace": roleNamespace(binding), "missing": !ok,
+		}
+		addFindingProperties(properties, findings[roleKey(binding)])
+		if !addNode(roleID, LabelRole, properties) {
+			continue
+		}
+		inNamespace(roleID, roleNamespace(binding))
+
+		for i, rule := range rules {
+			ruleID := fmt.Sprintf("%s#%d", roleID, i)
+			addNode(ruleID, LabelRule, map[string]any{
+				"verbs":           rule.Verbs,
+				"apiGroups":       rule.APIGroups,
+				"resources":       rule.Resources,
+				"resourceNames":   rule.ResourceNames,
+				"nonResourceURLs": rule.NonResourceURLs,
+			})
+			relate(roleID, ruleID, RelHasRule, nil)
+
+			for _, group := range rule.APIGroups {
+				for _, resource := range rule.Resources {
+					resourceID := "resource:" + resource
+					if group != "" {
+						resourceID = "resource:" + group + "/" + resource
+					}
+					addNode(resourceID, LabelResource, map[string]any{"apiGroup": group, "resource": resource, "nonResourceURL": false})
+					relate(ruleID, resourceID, RelAllows, map[string]any{"verbs": rule.Verbs})
+				}
+			}
+			for _, url := range rule.NonResourceURLs {
+				resourceID := "url:" + url
+				addNode(resourceID, LabelResource, map[string]any{"resource": url, "nonResourceURL": true})
+				relate(ruleID, resourceID, RelAllows, map[string]any{"verbs": rule.Verbs})
+			}
+		}
+	}
+	return g
+}
+
+// addFindingProperties sets the finding properties of a binding or role node
+func addFindingProperties(properties map[string]any, findings []audit.AuditResult) {
+	if len(findings) == 0 {
+		return
+	}
+	var ids, reasons []string
+	var risk audit.RiskLevel
+	score := 0
+	for _, f := range findings {
+		if !slices.Contains(ids, f.RuleID) {
+			ids = append(ids, f.RuleID)
+		}
+		reasons = append(reasons, f.Reason)
+		risk = maxRisk(risk, f.Risk)
+		score = max(score, f.Score)
+	}
+	sort.Strings(ids)
+	properties["risk"] = string(risk)
+	properties["riskScore"] = score
+	properties["findings"] = ids
+	properties["findingReasons"] = reasons
+}
+
+// WriteCypher writes the property graph as Cypher statements: a uniqueness constraint on the
+// id of each label, a CREATE statement per node and a MATCH ... CREATE statement per
+// relationship. The output can be run with cypher-shell.
+func WriteCypher(w io.Writer, g *PropertyGraph) error {
+	var b strings.Builder
+	for _, label := range nodeLabels {
+		fmt.Fprintf(&b, "CREATE CONSTRAINT rbaclens_%s_id IF NOT EXISTS FOR (n:%s) REQUIRE n.id IS UNIQUE;\n", strings.ToLower(label), label)
+	}
+	b.WriteString("\n")
+
+	labels := map[string]string{}
+	for _, n := range g.Nodes {
+		labels[n.ID] = n.Label
+		fmt.Fprintf(&b, "CREATE (:%s {%s});\n", n.Label, cypherProperties(n.ID, nodeColumns[n.Label], n.Properties))
+	}
+	b.WriteString("\n")
+	for _, r := range g.Relationships {
+		fmt.Fprintf(&b, "MATCH (a:%s {id: %s}), (b:%s {id: %s}) CREATE (a)-[:%s",
+			labels[r.From], cypherString(r.From), labels[r.To], cypherString(r.To), r.Type)
+		if props := cypherProperties("", relationshipColumns[r.Type], r.Properties); props != "" {
+			fmt.Fprintf(&b, " {%s}", props)
+		}
+		b.WriteString("]->(b);\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cypherProperties renders a Cypher property map body, with id first when it is set
+func cypherProperties(id string, columns []column, properties map[string]any) string {
+	var parts []string
+	if id != "" {
+		parts = append(parts, "id: "+cypherString(id))
+	}
+	for _, c := range columns {
+		value, ok := properties[c.name]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				parts = append(parts, c.name+": "+cypherString(v))
+			}
+		case int:
+			parts = append(parts, c.name+": "+strconv.Itoa(v))
+		case bool:
+			parts = append(parts, c.name+": "+strconv.FormatBool(v))
+		case []string:
+			if len(v) > 0 {
+				quoted := make([]string, len(v))
+				for i, s := range v {
+					quoted[i] = cypherString(s)
+				}
+				parts = append(parts, c.name+": ["+strings.Join(quoted, ", ")+"]")
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cypherString quotes a Cypher string literal
+func cypherString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// csvArrayDelimiter separates array values in CSV files; it is the neo4j-admin default
+const csvArrayDelimiter = ";"
+
+// WriteNeo4jCSV writes the property graph as neo4j-admin import files to dir: one node file
+// per label and one relationship file per type. It returns the names of the files written,
+// node files first.
+func WriteNeo4jCSV(dir string, g *PropertyGraph) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var files []string
+	for _, label := range nodeLabels {
+		header := []string{"id:ID"}
+		for _, c := range nodeColumns[label] {
+			header = append(header, csvHeader(c))
+		}
+		header = append(header, ":LABEL")
+		var rows [][]string
+		for _, n := range g.Nodes {
+			if n.Label == label {
+				rows = append(rows, append(append([]string{n.ID}, csvValues(nodeColumns[label], n.Properties)...), n.Label))
+			}
+		}
+		name := "nodes-" + strings.ToLower(label) + ".csv"
+		if err := writeCSV(filepath.Join(dir, name), header, rows); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+
+	for _, typ := range relationshipTypes {
+		header := []string{":START_ID", ":END_ID"}
+		for _, c := range relationshipColumns[typ] {
+			header = append(header, csvHeader(c))
+		}
+		header = append(header, ":TYPE")
+		var rows [][]string
+		for _, r := range g.Relationships {
+			if r.Type == typ {
+				rows = append(rows, append(append([]string{r.From, r.To}, csvValues(relationshipColumns[typ], r.Properties)...), r.Type))
+			}
+		}
+		name := "relationships-" + strings.ToLower(strings.ReplaceAll(typ, "_", "-")) + ".csv"
+		if err := writeCSV(filepath.Join(dir, name), header, rows); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// csvHeader returns the header of a column, e.g. verbs:string[]. Plain strings need no type.
+func csvHeader(c column) string {
+	if c.typ == typeString {
+		return c.name
+	}
+	return c.name + ":" + c.typ
+}
+
+func csvValues(columns []column, properties map[string]any) []string {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		switch v := properties[c.name].(type) {
+		case string:
+			values[i] = v
+		case int:
+			values[i] = strconv.Itoa(v)
+		case bool:
+			values[i] = strconv.FormatBool(v)
+		case []string:
+			values[i] = strings.Join(v, csvArrayDelimiter)
+		}
+	}
+	return values
+}
+
+func writeCSV(path string, header []string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,217 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+)
+
+// Fill colours of nodes by risk level
+var riskColors = map[audit.RiskLevel]string{
+	audit.RiskCritical: "#d32f2f",
+	audit.RiskHigh:     "#f57c00",
+	audit.RiskMedium:   "#fbc02d",
+	audit.RiskLow:      "#64b5f6",
+	audit.RiskInfo:     "#b0bec5",
+}
+
+// noRiskColor fills nodes without findings
+const noRiskColor = "#ffffff"
+
+func fillColor(n *Node) string {
+	if color, ok := riskColors[n.Risk]; ok {
+		return color
+	}
+	return noRiskColor
+}
+
+// displayLabel returns the label of a node with its type, e.g. "ClusterRole\nadmin"
+func displayLabel(n *Node, newline string) string {
+	label := n.Type + newline + n.Label
+	if n.Missing {
+		label += newline + "(missing)"
+	}
+	return label
+}
+
+// WriteDOT renders the graph in the Graphviz DOT language
+func WriteDOT(w io.Writer, g *Graph) error {
+	shapes := map[string]string{
+		KindSubject:  "ellipse",
+		KindBinding:  "box",
+		KindRole:     "hexagon",
+		KindResource: "note",
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph rbac {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled, fontname=\"Helvetica\", fontsize=10];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n")
+	for _, n := range g.Nodes {
+		style := "filled"
+		if n.Missing {
+			style = "filled,dashed"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s, fillcolor=%q, style=%q];\n",
+			strconv.Quote(n.ID), strconv.Quote(displayLabel(n, "\n")), shapes[n.Kind], fillColor(n), style)
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(e.Label))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid renders the graph as a Mermaid flowchart
+func WriteMermaid(w io.Writer, g *Graph) error {
+	// Mermaid IDs must be plain identifiers, so nodes are numbered
+	ids := map[string]string{}
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	shapes := map[string][2]string{
+		KindSubject:  {"([", "])"},
+		KindBinding:  {"[", "]"},
+		KindRole:     {"{{", "}}"},
+		KindResource: {"[/", "/]"},
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		shape := shapes[n.Kind]
+		fmt.Fprintf(&b, "  %s%s\"%s\"%s\n", ids[n.ID], shape[0], mermaidEscape(displayLabel(n, "<br/>")), shape[1])
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", ids[e.From], mermaidEscape(e.Label), ids[e.To])
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+		}
+	}
+	for _, level := range audit.RiskLevels {
+		var members []string
+		for _, n := range g.Nodes {
+			if n.Risk == level {
+				members = append(members, ids[n.ID])
+			}
+		}
+		if len(members) > 0 {
+			fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#333\n", level, riskColors[level])
+			fmt.Fprintf(&b, "  class %s %s\n", strings.Join(members, ","), level)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscape escapes the characters that end a quoted Mermaid label
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "*", "#42;").Replace(s)
+}
+
+// GraphML document structure
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML renders the graph as GraphML, with the node attributes as data keys
+func WriteGraphML(w io.Writer, g *Graph) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "kind", For: "node", Name: "kind", Type: "string"},
+			{ID: "type", For: "node", Name: "type", Type: "string"},
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "namespace", For: "node", Name: "namespace", Type: "string"},
+			{ID: "risk", For: "node", Name: "risk", Type: "string"},
+			{ID: "findings", For: "node", Name: "findings", Type: "string"},
+			{ID: "missing", For: "node", Name: "missing", Type: "boolean"},
+			{ID: "color", For: "node", Name: "color", Type: "string"},
+			{ID: "verbs", For: "edge", Name: "verbs", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "rbac", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		node := graphMLNode{ID: n.ID, Data: []graphMLData{
+			{Key: "kind", Value: n.Kind},
+			{Key: "type", Value: n.Type},
+			{Key: "label", Value: n.Label},
+			{Key: "color", Value: fillColor(n)},
+		}}
+		if n.Namespace != "" {
+			node.Data = append(node.Data, graphMLData{Key: "namespace", Value: n.Namespace})
+		}
+		if n.Risk != "" {
+			node.Data = append(node.Data, graphMLData{Key: "risk", Value: string(n.Risk)})
+		}
+		if len(n.Findings) > 0 {
+			node.Data = append(node.Data, graphMLData{Key: "findings", Value: strings.Join(n.Findings, ",")})
+		}
+		if n.Missing {
+			node.Data = append(node.Data, graphMLData{Key: "missing", Value: "true"})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		edge := graphMLEdge{Source: e.From, Target: e.To}
+		if e.Label != "" {
+			edge.Data = append(edge.Data, graphMLData{Key: "verbs", Value: e.Label})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,143 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/diff"
+)
+
+// Run is a single audit in the history
+type Run struct {
+	ID     string
+	Time   time.Time
+	Report audit.AuditReport
+}
+
+// Report is the finding history across a series of audits
+type Report struct {
+	Runs []RunCounts `json:"runs"`
+	// Findings are all findings seen in any run, open ones first, then by severity
+	Findings []Finding `json:"findings"`
+	// MeanTimeToRemediate is the mean time from first seen to resolved, over resolved findings
+	MeanTimeToRemediate Duration `json:"meanTimeToRemediate"`
+	// MeanTimeToRemediateBySeverity only has entries for severities with resolved findings
+	MeanTimeToRemediateBySeverity map[audit.RiskLevel]Duration `json:"meanTimeToRemediateBySeverity,omitempty"`
+	Open                          int                          `json:"open"`
+	Resolved                      int                          `json:"resolved"`
+}
+
+// RunCounts counts the findings of a run by severity
+type RunCounts struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Total    int       `json:"total"`
+	Critical int       `json:"critical"`
+	High     int       `json:"high"`
+	Medium   int       `json:"medium"`
+	Low      int       `json:"low"`
+	Info     int       `json:"info"`
+}
+
+// Finding is a finding with when it first and last appeared. A finding is resolved when a
+// later run no longer reports it; if it reappears, it is open again and keeps its first seen
+// time.
+type Finding struct {
+	audit.AuditResult
+	FirstSeen time.Time  `json:"firstSeen"`
+	LastSeen  time.Time  `json:"lastSeen"`
+	Resolved  *time.Time `json:"resolved,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "72h0m0s" in JSON and YAML
+type Duration time.Duration
+
+// MarshalText writes the duration as a string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Build computes the history of runs, which must be ordered oldest first
+func Build(runs []Run) Report {
+	report := Report{Runs: []RunCounts{}, Findings: []Finding{}}
+	findings := map[string]*Finding{}
+	var order []string
+	for _, run := range runs {
+		seen := map[string]bool{}
+		for _, f := range run.Report.Findings {
+			key := diff.FindingKey(f)
+			seen[key] = true
+			finding := findings[key]
+			if finding == nil {
+				finding = &Finding{FirstSeen: run.Time}
+				findings[key] = finding
+				order = append(order, key)
+			}
+			finding.AuditResult = f
+			finding.LastSeen = run.Time
+			finding.Resolved = nil
+		}
+		for key, finding := range findings {
+			if !seen[key] && finding.Resolved == nil {
+				resolved := run.Time
+				finding.Resolved = &resolved
+			}
+		}
+		report.Runs = append(report.Runs, countRun(run))
+	}
+
+	var total time.Duration
+	bySeverity := map[audit.RiskLevel][]time.Duration{}
+	for _, key := range order {
+		f := findings[key]
+		report.Findings = append(report.Findings, *f)
+		if f.Resolved == nil {
+			report.Open++
+			continue
+		}
+		report.Resolved++
+		d := f.Resolved.Sub(f.FirstSeen)
+		total += d
+		bySeverity[f.Risk] = append(bySeverity[f.Risk], d)
+	}
+	if report.Resolved > 0 {
+		report.MeanTimeToRemediate = Duration(total / time.Duration(report.Resolved))
+		report.MeanTimeToRemediateBySeverity = map[audit.RiskLevel]Duration{}
+		for level, durations := range bySeverity {
+			var sum time.Duration
+			for _, d := range durations {
+				sum += d
+			}
+			report.MeanTimeToRemediateBySeverity[level] = Duration(sum / time.Duration(len(durations)))
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if (a.Resolved == nil) != (b.Resolved == nil) {
+			return a.Resolved == nil
+		}
+		return a.Risk.Score() > b.Risk.Score()
+	})
+	return report
+}
+
+func countRun(run Run) RunCounts {
+	counts := RunCounts{ID: run.ID, Time: run.Time, Total: len(run.Report.Findings)}
+	for _, f := range run.Report.Findings {
+		switch f.Risk {
+		case audit.RiskCritical:
+			counts.Critical++
+		case audit.RiskHigh:
+			counts.High++
+		case audit.RiskMedium:
+			counts.Medium++
+		case audit.RiskLow:
+			counts.Low++
+		case audit.RiskInfo:
+			counts.Info++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+)
+
+func TestBuild(t *testing.T) {
+	wild := audit.AuditResult{RuleID: "wildcard-permissions", Risk: audit.RiskCritical, ResourceKind: "ClusterRole", ResourceName: "wild"}
+	secrets := audit.AuditResult{RuleID: "secrets-read", Risk: audit.RiskHigh, ResourceKind: "Role", ResourceName: "reader", Namespace: "team-a"}
+	configMaps := audit.AuditResult{RuleID: "configmap-read", Risk: audit.RiskLow, ResourceKind: "Role", ResourceName: "cm", Namespace: "team-a"}
+
+	start := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return start.Add(time.Duration(n) * 24 * time.Hour) }
+	run := func(n int, findings ...audit.AuditResult) Run {
+		return Run{ID: day(n).Format("0102"), Time: day(n), Report: audit.AuditReport{Findings: findings}}
+	}
+
+	report := Build([]Run{
+		run(0, wild, secrets),
+		// secrets is fixed, configMaps appears
+		run(1, wild, configMaps),
+		// wild is fixed, secrets comes back
+		run(3, secrets, configMaps),
+		// configMaps is fixed
+		run(4, secrets),
+	})
+
+	wantRuns := []RunCounts{
+		{ID: "0801", Time: day(0), Total: 2, Critical: 1, High: 1},
+		{ID: "0802", Time: day(1), Total: 2, Critical: 1, Low: 1},
+		{ID: "0804", Time: day(3), Total: 2, High: 1, Low: 1},
+		{ID: "0805", Time: day(4), Total: 1, High: 1},
+	}
+	if !reflect.DeepEqual(report.Runs, wantRuns) {
+		t.Errorf("Runs = %+v, want %+v", report.Runs, wantRuns)
+	}
+
+	resolved := func(n int) *time.Time {
+		t := day(n)
+		return &t
+	}
+	// Open findings come first, then by severity; the reopened finding keeps its first seen time
+	wantFindings := []Finding{
+		{AuditResult: secrets, FirstSeen: day(0), LastSeen: day(4)},
+		{AuditResult: wild, FirstSeen: day(0), LastSeen: day(1), Resolved: resolved(3)},
+		{AuditResult: configMaps, FirstSeen: day(1), LastSeen: day(3), Resolved: resolved(4)},
+	}
+	if !reflect.DeepEqual(report.Findings, wantFindings) {
+		t.Errorf("Findings = %+v, want %+v", report.Findings, wantFindings)
+	}
+	if report.Open != 1 || report.Resolved != 2 {
+		t.Errorf("Open, Resolved = %d, %d, want 1, 2", report.Open, report.Resolved)
+	}
+	if want := Duration(3 * 24 * time.Hour); report.MeanTimeToRemediate != want {
+		t.Errorf("MeanTimeToRemediate = %v, want %v", time.Duration(report.MeanTimeToRemediate), time.Duration(want))
+	}
+	wantBySeverity := map[audit.RiskLevel]Duration{
+		audit.RiskCritical: Duration(3 * 24 * time.Hour),
+		audit.RiskLow:      Duration(3 * 24 * time.Hour),
+	}
+	if !reflect.DeepEqual(report.MeanTimeToRemediateBySeverity, wantBySeverity) {
+		t.Errorf("MeanTimeToRemediateBySeverity = %v, want %v", report.MeanTimeToRemediateBySeverity, wantBySeverity)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	report := Build(nil)
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	want := `{"runs":[],"findings":[],"meanTimeToRemediate":"0s","open":0,"resolved":0}`
+	if string(data) != want {
+		t.Errorf("empty history = %s, want %s", data, want)
+	}
+}
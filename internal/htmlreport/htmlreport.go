@@ -0,0 +1,240 @@
+package htmlreport
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+var tmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"riskClass": func(r audit.RiskLevel) string { return "risk-" + string(r) },
+}).Parse(reportTemplate))
+
+// clusterScope labels cluster-scoped findings in the namespace breakdown
+const clusterScope = "(cluster-wide)"
+
+// page is the data the report template is rendered with
+type page struct {
+	Metadata  types.Metadata
+	Generated time.Time
+	Summary   audit.AuditSummary
+	Cards     []card
+	Findings  []finding
+	// Namespaces holds the finding counts per namespace, cluster-scoped findings first
+	Namespaces []namespaceCounts
+	Subjects   []audit.SubjectScore
+	Objects    []object
+	Levels     []audit.RiskLevel
+}
+
+type card struct {
+	Label string
+	Value int
+	Class string
+}
+
+type finding struct {
+	audit.AuditResult
+	Scope  string
+	Object string
+	// Anchor links to the YAML of the object, empty if the object is not in the snapshot
+	Anchor string
+}
+
+type namespaceCounts struct {
+	Namespace string
+	Counts    []int
+	Total     int
+	Score     int
+}
+
+type object struct {
+	Kind     string
+	Name     string
+	Anchor   string
+	Risk     audit.RiskLevel
+	Findings int
+	YAML     string
+}
+
+// Write renders the report as a single HTML page. Styles and scripts are inlined, so the
+// page works offline. Resources are the snapshot the report was produced from; they supply
+// the YAML of each object with findings.
+func Write(w io.Writer, resources types.RBACResources, report audit.AuditReport) error {
+	manifests, err := manifestsByObject(resources)
+	if err != nil {
+		return err
+	}
+
+	p := page{
+		Metadata:  report.Metadata,
+		Generated: time.Now().UTC(),
+		Summary:   report.Summary,
+		Subjects:  report.SubjectScores,
+		Levels:    audit.RiskLevels,
+		Cards: []card{
+			{"Findings", report.Summary.TotalFindings, "total"},
+			{"Critical", report.Summary.CriticalRiskFindings, "risk-critical"},
+			{"High", report.Summary.HighRiskFindings, "risk-high"},
+			{"Medium", report.Summary.MediumRiskFindings, "risk-medium"},
+			{"Low", report.Summary.LowRiskFindings, "risk-low"},
+			{"Info", report.Summary.InfoRiskFindings, "risk-info"},
+			{"ClusterRoles", report.Summary.TotalClusterRoles, ""},
+			{"Roles", report.Summary.TotalRoles, ""},
+			{"ClusterRoleBindings", report.Summary.TotalClusterRoleBindings, ""},
+			{"RoleBindings", report.Summary.TotalRoleBindings, ""},
+			{"System Skipped", report.Summary.SystemResourcesSkipped, ""},
+		},
+	}
+
+	namespaces := map[string]*namespaceCounts{}
+	objects := map[string]*object{}
+	var order []string
+	for _, f := range report.Findings {
+		scope := f.Namespace
+		if scope == "" {
+			scope = clusterScope
+		}
+		name := objectName(f.ResourceKind, f.Namespace, f.ResourceName)
+		row := finding{AuditResult: f, Scope: scope, Object: name}
+
+		if manifest, ok := manifests[name]; ok {
+			row.Anchor = anchor(name)
+			o, seen := objects[name]
+			if !seen {
+				o = &object{Kind: f.ResourceKind, Name: name, Anchor: row.Anchor, Risk: f.Risk, YAML: manifest}
+				objects[name] = o
+				order = append(order, name)
+			}
+			o.Findings++
+			if f.Risk.Score() > o.Risk.Score() {
+				o.Risk = f.Risk
+			}
+		}
+		p.Findings = append(p.Findings, row)
+
+		counts, ok := namespaces[scope]
+		if !ok {
+			counts = &namespaceCounts{Namespace: scope, Counts: make([]int, len(audit.RiskLevels))}
+			namespaces[scope] = counts
+		}
+		for i, level := range audit.RiskLevels {
+			if f.Risk == level {
+				counts.Counts[i]++
+			}
+		}
+		counts.Total++
+	}
+
+	for _, s := range report.NamespaceScores {
+		if counts, ok := namespaces[s.Namespace]; ok {
+			counts.Score = s.Score
+		}
+	}
+	for _, counts := range namespaces {
+		p.Namespaces = append(p.Namespaces, *counts)
+	}
+	sort.Slice(p.Namespaces, func(i, j int) bool {
+		a, b := p.Namespaces[i], p.Namespaces[j]
+		if (a.Namespace == clusterScope) != (b.Namespace == clusterScope) {
+			return a.Namespace == clusterScope
+		}
+		return a.Namespace < b.Namespace
+	})
+	for _, name := range order {
+		p.Objects = append(p.Objects, *objects[name])
+	}
+
+	return tmpl.Execute(w, p)
+}
+
+// manifestsByObject renders the YAML of each role and binding in the snapshot, keyed by
+// object name
+func manifestsByObject(resources types.RBACResources) (map[string]string, error) {
+	manifests := map[string]string{}
+	add := func(kind string, meta metav1.ObjectMeta, object any) error {
+		data, err := k8s.ManifestYAML(object)
+		if err != nil {
+			return fmt.Errorf("failed to render %s %s: %w", kind, meta.Name, err)
+		}
+		manifests[objectName(kind, meta.Namespace, meta.Name)] = string(data)
+		return nil
+	}
+	typeMeta := func(kind string) metav1.TypeMeta {
+		return metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: kind}
+	}
+
+	for _, cr := range resources.ClusterRoles {
+		cr.TypeMeta, cr.ObjectMeta = typeMeta("ClusterRole"), displayMeta(cr.ObjectMeta)
+		if err := add("ClusterRole", cr.ObjectMeta, cr); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range resources.Roles {
+		r.TypeMeta, r.ObjectMeta = typeMeta("Role"), displayMeta(r.ObjectMeta)
+		if err := add("Role", r.ObjectMeta, r); err != nil {
+			return nil, err
+		}
+	}
+	for _, crb := range resources.ClusterRoleBindings {
+		crb.TypeMeta, crb.ObjectMeta = typeMeta("ClusterRoleBinding"), displayMeta(crb.ObjectMeta)
+		if err := add("ClusterRoleBinding", crb.ObjectMeta, crb); err != nil {
+			return nil, err
+		}
+	}
+	for _, rb := range resources.RoleBindings {
+		rb.TypeMeta, rb.ObjectMeta = typeMeta("RoleBinding"), displayMeta(rb.ObjectMeta)
+		if err := add("RoleBinding", rb.ObjectMeta, rb); err != nil {
+			return nil, err
+		}
+	}
+	return manifests, nil
+}
+
+// displayMeta drops the metadata that only adds noise to a report
+func displayMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        meta.Name,
+		Namespace:   meta.Namespace,
+		Labels:      meta.Labels,
+		Annotations: withoutLastApplied(meta.Annotations),
+	}
+}
+
+func withoutLastApplied(annotations map[string]string) map[string]string {
+	out := map[string]string{}
+	for k, v := range annotations {
+		if k != "kubectl.kubernetes.io/last-applied-configuration" {
+			out[k] = v
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func objectName(kind, namespace, name string) string {
+	if namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+	}
+	return kind + "/" + name
+}
+
+// anchor returns the HTML id of an object's YAML
+func anchor(name string) string {
+	return "obj-" + strings.NewReplacer("/", "-", ":", "-").Replace(strings.ToLower(name))
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// dryRunOption returns the DryRun option of write requests
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// ReplaceRBACObject replaces a Role or ClusterRole with the given YAML or JSON manifest. The
+// manifest must carry the resourceVersion of the object it was generated from, so the update
+// fails with a conflict if the object changed since. With dryRun the request is validated
+// and admitted by the API server but not persisted.
+func (c *Client) ReplaceRBACObject(ctx context.Context, manifest []byte, dryRun bool) error {
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(manifest, &meta); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	opts := metav1.UpdateOptions{DryRun: dryRunOption(dryRun)}
+	rbacClient := c.clientset.RbacV1()
+
+	var err error
+	switch meta.Kind {
+	case "ClusterRole":
+		var role rbacv1.ClusterRole
+		if err := yaml.UnmarshalStrict(manifest, &role); err != nil {
+			return fmt.Errorf("failed to parse ClusterRole: %w", err)
+		}
+		if role.ResourceVersion == "" {
+			return errNoResourceVersion
+		}
+		_, err = rbacClient.ClusterRoles().Update(ctx, &role, opts)
+	case "Role":
+		var role rbacv1.Role
+		if err := yaml.UnmarshalStrict(manifest, &role); err != nil {
+			return fmt.Errorf("failed to parse Role: %w", err)
+		}
+		if role.ResourceVersion == "" {
+			return errNoResourceVersion
+		}
+		_, err = rbacClient.Roles(role.Namespace).Update(ctx, &role, opts)
+	default:
+		return fmt.Errorf("unsupported kind %q, expected Role or ClusterRole", meta.Kind)
+	}
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("the object changed since the snapshot the fix was generated from, regenerate the fix: %w", err)
+	}
+	return err
+}
+
+// errNoResourceVersion is returned for replacement manifests that could silently overwrite
+// changes made since the snapshot
+var errNoResourceVersion = errors.New("manifest has no metadata.resourceVersion, regenerate the fix from a current snapshot")
+
+// PatchRBACObject applies an RFC 6902 JSON patch to a Role, ClusterRole, RoleBinding or
+// ClusterRoleBinding. With dryRun the request is validated and admitted by the API server
+// but not persisted.
+func (c *Client) PatchRBACObject(ctx context.Context, kind, namespace, name string, patch []byte, dryRun bool) error {
+	opts := metav1.PatchOptions{DryRun: dryRunOption(dryRun)}
+	rbacClient := c.clientset.RbacV1()
+
+	var err error
+	switch kind {
+	case "ClusterRole":
+		_, err = rbacClient.ClusterRoles().Patch(ctx, name, k8stypes.JSONPatchType, patch, opts)
+	case "Role":
+		_, err = rbacClient.Roles(namespace).Patch(ctx, name, k8stypes.JSONPatchType, patch, opts)
+	case "ClusterRoleBinding":
+		_, err = rbacClient.ClusterRoleBindings().Patch(ctx, name, k8stypes.JSONPatchType, patch, opts)
+	case "RoleBinding":
+		_, err = rbacClient.RoleBindings(namespace).Patch(ctx, name, k8stypes.JSONPatchType, patch, opts)
+	default:
+		err = fmt.Errorf("unsupported kind %q", kind)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// RBACCache keeps the Roles, ClusterRoles, RoleBindings and ClusterRoleBindings of a cluster
+// up to date through informers, so they can be read without listing them from the API server
+type RBACCache struct {
+	factory   informers.SharedInformerFactory
+	informers []cache.SharedIndexInformer
+
+	roles               rbaclisters.RoleLister
+	clusterRoles        rbaclisters.ClusterRoleLister
+	roleBindings        rbaclisters.RoleBindingLister
+	clusterRoleBindings rbaclisters.ClusterRoleBindingLister
+}
+
+// NewRBACCache returns a cache of the RBAC objects of the cluster. If namespace is not empty,
+// only the Roles and RoleBindings of that namespace are kept.
+func (c *Client) NewRBACCache(namespace string) *RBACCache {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0, informers.WithNamespace(namespace))
+	rbacInformers := factory.Rbac().V1()
+	return &RBACCache{
+		factory: factory,
+		informers: []cache.SharedIndexInformer{
+			rbacInformers.Roles().Informer(),
+			rbacInformers.ClusterRoles().Informer(),
+			rbacInformers.RoleBindings().Informer(),
+			rbacInformers.ClusterRoleBindings().Informer(),
+		},
+		roles:               rbacInformers.Roles().Lister(),
+		clusterRoles:        rbacInformers.ClusterRoles().Lister(),
+		roleBindings:        rbacInformers.RoleBindings().Lister(),
+		clusterRoleBindings: rbacInformers.ClusterRoleBindings().Lister(),
+	}
+}
+
+// AddEventHandler calls handler on every change to an RBAC object. Objects already in the
+// cluster are delivered as adds when the cache starts.
+func (r *RBACCache) AddEventHandler(handler cache.ResourceEventHandler) error {
+	for _, informer := range r.informers {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start starts the informers and waits until the cache holds the current RBAC objects. List
+// failures are retried, so it only fails if ctx is done first. The informers stop when ctx
+// is done.
+func (r *RBACCache) Start(ctx context.Context) error {
+	r.factory.Start(ctx.Done())
+	for _, synced := range r.factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("RBAC cache did not sync: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Resources returns the RBAC objects in the cache, sorted by namespace and name. The objects
+// are shared with the cache and must not be modified.
+func (r *RBACCache) Resources() types.RBACResources {
+	var resources types.RBACResources
+	roles, _ := r.roles.List(labels.Everything())
+	for _, role := range roles {
+		resources.Roles = append(resources.Roles, *role)
+	}
+	clusterRoles, _ := r.clusterRoles.List(labels.Everything())
+	for _, role := range clusterRoles {
+		resources.ClusterRoles = append(resources.ClusterRoles, *role)
+	}
+	roleBindings, _ := r.roleBindings.List(labels.Everything())
+	for _, binding := range roleBindings {
+		resources.RoleBindings = append(resources.RoleBindings, *binding)
+	}
+	clusterRoleBindings, _ := r.clusterRoleBindings.List(labels.Everything())
+	for _, binding := range clusterRoleBindings {
+		resources.ClusterRoleBindings = append(resources.ClusterRoleBindings, *binding)
+	}
+
+	sort.Slice(resources.Roles, func(i, j int) bool { return lessObject(resources.Roles[i].ObjectMeta, resources.Roles[j].ObjectMeta) })
+	sort.Slice(resources.ClusterRoles, func(i, j int) bool { return resources.ClusterRoles[i].Name < resources.ClusterRoles[j].Name })
+	sort.Slice(resources.RoleBindings, func(i, j int) bool {
+		return lessObject(resources.RoleBindings[i].ObjectMeta, resources.RoleBindings[j].ObjectMeta)
+	})
+	sort.Slice(resources.ClusterRoleBindings, func(i, j int) bool {
+		return resources.ClusterRoleBindings[i].Name < resources.ClusterRoleBindings[j].Name
+	})
+	return resources
+}
+
+// lessObject orders objects by namespace, then name
+func lessObject(a, b metav1.ObjectMeta) bool {
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
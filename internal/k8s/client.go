@@ -8,8 +8,11 @@ import (
 	"path/filepath"
 
 	"github.com/flushthemoney/RBACLens/internal/types"
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -19,6 +22,11 @@ import (
 type Client struct {
 	clientset *kubernetes.Clientset
 	config    *rest.Config
+
+	// contextName and clusterName come from the kubeconfig current-context.
+	// Both are empty when running with in-cluster config.
+	contextName string
+	clusterName string
 }
 
 // NewClient creates a new Kubernetes client
@@ -51,15 +59,30 @@ func NewClient(kubeconfig string) (*Client, error) {
 		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	return &Client{
+	client := &Client{
 		clientset: clientset,
 		config:    config,
-	}, nil
+	}
+	if kubeconfig != "" {
+		if raw, err := clientcmd.LoadFromFile(kubeconfig); err == nil {
+			client.contextName = raw.CurrentContext
+			if kubeContext, ok := raw.Contexts[raw.CurrentContext]; ok {
+				client.clusterName = kubeContext.Cluster
+			}
+		}
+	}
+
+	return client, nil
+}
+
+// Dynamic returns a dynamic client for resources without typed clients, such as custom resources
+func (c *Client) Dynamic() (dynamic.Interface, error) {
+	return dynamic.NewForConfig(c.config)
 }
 
 // GetRBACResources fetches RBAC resources. If namespace is empty, fetches Roles and RoleBindings from all namespaces.
 func (c *Client) GetRBACResources(ctx context.Context, namespace string) (*types.RBACResources, error) {
-	resources := &types.RBACResources{}
+	resources := &types.RBACResources{SchemaVersion: types.SchemaVersion}
 
 	// Get Roles
 	roles, err := c.getRoles(ctx, namespace)
@@ -89,9 +112,29 @@ func (c *Client) GetRBACResources(ctx context.Context, namespace string) (*types
 	}
 	resources.ClusterRoleBindings = clusterRoleBindings
 
+	resources.ServiceAccounts, resources.Workloads, err = c.GetWorkloads(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
 	return resources, nil
 }
 
+// GetWorkloads fetches ServiceAccounts and the workloads running as them. They only refine the
+// audit, so a caller that may not list them gets empty lists rather than an error.
+func (c *Client) GetWorkloads(ctx context.Context, namespace string) ([]types.ServiceAccount, []types.Workload, error) {
+	serviceAccounts, err := c.getServiceAccounts(ctx, namespace)
+	if err != nil && !apierrors.IsForbidden(err) {
+		return nil, nil, fmt.Errorf("failed to get service accounts: %w", err)
+	}
+
+	workloads, err := c.getWorkloads(ctx, namespace, serviceAccounts)
+	if err != nil && !apierrors.IsForbidden(err) {
+		return nil, nil, fmt.Errorf("failed to get workloads: %w", err)
+	}
+	return serviceAccounts, workloads, nil
+}
+
 // getRoles retrieves roles from the cluster. If namespace is empty, fetches from all namespaces.
 func (c *Client) getRoles(ctx context.Context, namespace string) ([]rbacv1.Role, error) {
 	if namespace != "" {
@@ -145,3 +188,82 @@ func (c *Client) getClusterRoleBindings(ctx context.Context) ([]rbacv1.ClusterRo
 	}
 	return clusterRoleBindings.Items, nil
 }
+
+// getServiceAccounts retrieves service accounts from the cluster. If namespace is empty, fetches from all namespaces.
+func (c *Client) getServiceAccounts(ctx context.Context, namespace string) ([]types.ServiceAccount, error) {
+	serviceAccountList, err := c.clientset.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccounts := make([]types.ServiceAccount, 0, len(serviceAccountList.Items))
+	for _, sa := range serviceAccountList.Items {
+		serviceAccounts = append(serviceAccounts, types.ServiceAccount{
+			Namespace:      sa.Namespace,
+			Name:           sa.Name,
+			AutomountToken: sa.AutomountServiceAccountToken,
+		})
+	}
+	return serviceAccounts, nil
+}
+
+// podPageSize is the number of pods listed per request, so fetching a large cluster never
+// holds every Pod object in memory at once
+const podPageSize = 500
+
+// getWorkloads retrieves pods from the cluster and groups them by controlling owner and ServiceAccount.
+// If namespace is empty, fetches from all namespaces. Pods are listed a page at a time.
+func (c *Client) getWorkloads(ctx context.Context, namespace string, serviceAccounts []types.ServiceAccount) ([]types.Workload, error) {
+	saAutomount := map[string]*bool{}
+	for _, sa := range serviceAccounts {
+		saAutomount[sa.Namespace+"/"+sa.Name] = sa.AutomountToken
+	}
+
+	seen := map[types.Workload]bool{}
+	workloads := []types.Workload{}
+	options := metav1.ListOptions{Limit: podPageSize}
+	for {
+		pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			workload := podWorkload(&pods.Items[i], saAutomount)
+			if !seen[workload] {
+				seen[workload] = true
+				workloads = append(workloads, workload)
+			}
+		}
+		if pods.Continue == "" {
+			return workloads, nil
+		}
+		options.Continue = pods.Continue
+	}
+}
+
+// podWorkload returns the workload a pod belongs to. saAutomount maps namespace/name of
+// each ServiceAccount to its automountServiceAccountToken setting.
+func podWorkload(pod *corev1.Pod, saAutomount map[string]*bool) types.Workload {
+	workload := types.Workload{
+		Kind:           "Pod",
+		Namespace:      pod.Namespace,
+		Name:           pod.Name,
+		ServiceAccount: pod.Spec.ServiceAccountName,
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		workload.Kind = owner.Kind
+		workload.Name = owner.Name
+	}
+	if workload.ServiceAccount == "" {
+		workload.ServiceAccount = "default"
+	}
+
+	// The pod setting takes precedence over the ServiceAccount setting, and tokens
+	// are mounted when neither says otherwise
+	automount := pod.Spec.AutomountServiceAccountToken
+	if automount == nil {
+		automount = saAutomount[pod.Namespace+"/"+workload.ServiceAccount]
+	}
+	workload.AutomountToken = automount == nil || *automount
+	return workload
+}
@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodWorkload(t *testing.T) {
+	no, yes := false, true
+	saAutomount := map[string]*bool{"team-a/builder": &no}
+	controller := true
+	owned := metav1.ObjectMeta{Name: "api-7d9f-x2k", Namespace: "team-a", OwnerReferences: []metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "api-7d9f", Controller: &controller},
+	}}
+
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want types.Workload
+	}{
+		{
+			name: "bare pod with the default ServiceAccount",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "team-a"}},
+			want: types.Workload{Kind: "Pod", Namespace: "team-a", Name: "debug", ServiceAccount: "default", AutomountToken: true},
+		},
+		{
+			name: "owned pod",
+			pod:  corev1.Pod{ObjectMeta: owned, Spec: corev1.PodSpec{ServiceAccountName: "api"}},
+			want: types.Workload{Kind: "ReplicaSet", Namespace: "team-a", Name: "api-7d9f", ServiceAccount: "api", AutomountToken: true},
+		},
+		{
+			name: "ServiceAccount disables automount",
+			pod:  corev1.Pod{ObjectMeta: owned, Spec: corev1.PodSpec{ServiceAccountName: "builder"}},
+			want: types.Workload{Kind: "ReplicaSet", Namespace: "team-a", Name: "api-7d9f", ServiceAccount: "builder", AutomountToken: false},
+		},
+		{
+			name: "pod setting overrides the ServiceAccount",
+			pod:  corev1.Pod{ObjectMeta: owned, Spec: corev1.PodSpec{ServiceAccountName: "builder", AutomountServiceAccountToken: &yes}},
+			want: types.Workload{Kind: "ReplicaSet", Namespace: "team-a", Name: "api-7d9f", ServiceAccount: "builder", AutomountToken: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podWorkload(&tt.pod, saAutomount); got != tt.want {
+				t.Errorf("podWorkload() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"maps"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Compact trims the metadata of every object in resources to its name, namespace, labels,
+// annotations, owner references, resourceVersion and creation timestamp. The rest, notably
+// managedFields and the kubectl.kubernetes.io/last-applied-configuration annotation, is not
+// used by the audit and makes up most of the size of a full snapshot. Annotations and the
+// resourceVersion are kept so fixes generated from the snapshot preserve them.
+func Compact(resources *types.RBACResources) {
+	for i := range resources.Roles {
+		compactMeta(&resources.Roles[i].ObjectMeta)
+	}
+	for i := range resources.ClusterRoles {
+		compactMeta(&resources.ClusterRoles[i].ObjectMeta)
+	}
+	for i := range resources.RoleBindings {
+		compactMeta(&resources.RoleBindings[i].ObjectMeta)
+	}
+	for i := range resources.ClusterRoleBindings {
+		compactMeta(&resources.ClusterRoleBindings[i].ObjectMeta)
+	}
+}
+
+func compactMeta(meta *metav1.ObjectMeta) {
+	annotations := maps.Clone(meta.Annotations)
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	*meta = metav1.ObjectMeta{
+		Name:              meta.Name,
+		Namespace:         meta.Namespace,
+		Labels:            meta.Labels,
+		Annotations:       annotations,
+		OwnerReferences:   meta.OwnerReferences,
+		ResourceVersion:   meta.ResourceVersion,
+		CreationTimestamp: meta.CreationTimestamp,
+	}
+}
@@ -0,0 +1,47 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompact(t *testing.T) {
+	resources := types.RBACResources{
+		ClusterRoles: []rbacv1.ClusterRole{{ObjectMeta: metav1.ObjectMeta{
+			Name:            "reader",
+			UID:             "1234",
+			ResourceVersion: "42",
+			Labels:          map[string]string{"app": "demo"},
+			Annotations: map[string]string{
+				corev1.LastAppliedConfigAnnotation:            "{}",
+				"rbac.authorization.kubernetes.io/autoupdate": "true",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		}}},
+		Roles: []rbacv1.Role{{ObjectMeta: metav1.ObjectMeta{
+			Name:        "editor",
+			Namespace:   "team-a",
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: "{}"},
+		}}},
+	}
+
+	Compact(&resources)
+
+	want := metav1.ObjectMeta{
+		Name:            "reader",
+		ResourceVersion: "42",
+		Labels:          map[string]string{"app": "demo"},
+		Annotations:     map[string]string{"rbac.authorization.kubernetes.io/autoupdate": "true"},
+	}
+	if got := resources.ClusterRoles[0].ObjectMeta; !reflect.DeepEqual(got, want) {
+		t.Errorf("ClusterRole metadata = %+v, want %+v", got, want)
+	}
+	if got := resources.Roles[0].Annotations; got != nil {
+		t.Errorf("Role annotations = %v, want none", got)
+	}
+}
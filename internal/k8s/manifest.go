@@ -0,0 +1,24 @@
+package k8s
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestYAML renders a Kubernetes object as YAML, without the empty creationTimestamp that
+// typed objects carry
+func ManifestYAML(object any) ([]byte, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	if metadata, ok := manifest["metadata"].(map[string]any); ok && metadata["creationTimestamp"] == nil {
+		delete(metadata, "creationTimestamp")
+	}
+	return yaml.Marshal(manifest)
+}
@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/flushthemoney/RBACLens/internal/version"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetMetadata describes the cluster the client is connected to. If clusterName is empty,
+// the name is taken from the kubeconfig current-context. Lookups that fail (for example
+// because the caller may not read the kube-system namespace) are left empty rather than
+// failing the fetch.
+func (c *Client) GetMetadata(ctx context.Context, clusterName string) types.Metadata {
+	meta := types.Metadata{
+		ClusterName:     clusterName,
+		Context:         c.contextName,
+		Server:          c.config.Host,
+		RBACLensVersion: version.Get(),
+		Timestamp:       time.Now(),
+	}
+	if meta.ClusterName == "" {
+		meta.ClusterName = c.defaultClusterName()
+	}
+
+	if info, err := c.clientset.Discovery().ServerVersion(); err == nil {
+		meta.ServerVersion = info.GitVersion
+	}
+
+	// The kube-system namespace UID does not change for the lifetime of a cluster,
+	// so it is a stable identifier even if the cluster is renamed in kubeconfig.
+	if ns, err := c.clientset.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{}); err == nil {
+		meta.ClusterID = string(ns.UID)
+	}
+
+	review, err := c.clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil {
+		meta.FetchedBy = &types.Identity{
+			Username: review.Status.UserInfo.Username,
+			UID:      review.Status.UserInfo.UID,
+			Groups:   review.Status.UserInfo.Groups,
+		}
+	}
+
+	return meta
+}
+
+// defaultClusterName picks a cluster name when none was given explicitly
+func (c *Client) defaultClusterName() string {
+	switch {
+	case c.clusterName != "":
+		return c.clusterName
+	case c.contextName != "":
+		return c.contextName
+	default:
+		return "in-cluster"
+	}
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// SetObject adds a Role, ClusterRole, RoleBinding or ClusterRoleBinding to resources, replacing
+// the object with the same namespace and name. The slices are copied rather than modified in
+// place, as they may be shared with a cache.
+func SetObject(resources *types.RBACResources, object any) error {
+	switch o := object.(type) {
+	case *rbacv1.Role:
+		resources.Roles = setObject(resources.Roles, *o, func(r rbacv1.Role) bool { return r.Namespace == o.Namespace && r.Name == o.Name })
+	case *rbacv1.ClusterRole:
+		resources.ClusterRoles = setObject(resources.ClusterRoles, *o, func(r rbacv1.ClusterRole) bool { return r.Name == o.Name })
+	case *rbacv1.RoleBinding:
+		resources.RoleBindings = setObject(resources.RoleBindings, *o, func(b rbacv1.RoleBinding) bool { return b.Namespace == o.Namespace && b.Name == o.Name })
+	case *rbacv1.ClusterRoleBinding:
+		resources.ClusterRoleBindings = setObject(resources.ClusterRoleBindings, *o, func(b rbacv1.ClusterRoleBinding) bool { return b.Name == o.Name })
+	default:
+		return fmt.Errorf("unsupported object type %T", object)
+	}
+	return nil
+}
+
+// RemoveObject removes a Role, ClusterRole, RoleBinding or ClusterRoleBinding from resources.
+// Like SetObject, it does not modify the slices in place.
+func RemoveObject(resources *types.RBACResources, object any) error {
+	switch o := object.(type) {
+	case *rbacv1.Role:
+		resources.Roles = slices.DeleteFunc(slices.Clone(resources.Roles), func(r rbacv1.Role) bool { return r.Namespace == o.Namespace && r.Name == o.Name })
+	case *rbacv1.ClusterRole:
+		resources.ClusterRoles = slices.DeleteFunc(slices.Clone(resources.ClusterRoles), func(r rbacv1.ClusterRole) bool { return r.Name == o.Name })
+	case *rbacv1.RoleBinding:
+		resources.RoleBindings = slices.DeleteFunc(slices.Clone(resources.RoleBindings), func(b rbacv1.RoleBinding) bool { return b.Namespace == o.Namespace && b.Name == o.Name })
+	case *rbacv1.ClusterRoleBinding:
+		resources.ClusterRoleBindings = slices.DeleteFunc(slices.Clone(resources.ClusterRoleBindings), func(b rbacv1.ClusterRoleBinding) bool { return b.Name == o.Name })
+	default:
+		return fmt.Errorf("unsupported object type %T", object)
+	}
+	return nil
+}
+
+func setObject[T any](objects []T, object T, same func(T) bool) []T {
+	objects = slices.Clone(objects)
+	if i := slices.IndexFunc(objects, same); i >= 0 {
+		objects[i] = object
+		return objects
+	}
+	return append(objects, object)
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Exporter holds the Prometheus metrics of the latest audit and of the fetches before it
+type Exporter struct {
+	registry *prometheus.Registry
+	audit    *auditCollector
+
+	fetchDuration prometheus.Histogram
+	fetches       prometheus.Counter
+	fetchErrors   prometheus.Counter
+}
+
+// NewExporter returns an exporter with its metrics registered
+func NewExporter() *Exporter {
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+		audit: &auditCollector{
+			findings: prometheus.NewDesc("rbaclens_findings",
+				"Number of audit findings by severity, rule and namespace. Cluster-scoped findings have an empty namespace.",
+				[]string{"severity", "rule", "namespace"}, nil),
+			objects: prometheus.NewDesc("rbaclens_rbac_objects",
+				"Number of RBAC objects in the audited snapshot by kind.",
+				[]string{"kind"}, nil),
+			subjectScores: prometheus.NewDesc("rbaclens_subject_risk_score",
+				"Risk score of each subject with findings, as in the audit report.",
+				[]string{"subject"}, nil),
+			lastAudit: prometheus.NewDesc("rbaclens_last_audit_timestamp_seconds",
+				"Time of the snapshot last audited, as a Unix timestamp.",
+				nil, nil),
+		},
+		fetchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "rbaclens_fetch_duration_seconds",
+			Help:    "Time taken to fetch RBAC resources.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+		}),
+		fetches: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rbaclens_fetches_total",
+			Help: "Number of attempts to fetch RBAC resources.",
+		}),
+		fetchErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rbaclens_fetch_errors_total",
+			Help: "Number of failed attempts to fetch RBAC resources.",
+		}),
+	}
+	e.registry.MustRegister(e.audit, e.fetchDuration, e.fetches, e.fetchErrors)
+	return e
+}
+
+// Update replaces the audit metrics with those of a snapshot and its audit report. Findings
+// and subjects no longer present are dropped. The new values are computed first and swapped
+// in at once, so a concurrent scrape sees either the previous audit or this one.
+func (e *Exporter) Update(resources types.RBACResources, report audit.AuditReport) {
+	values := &auditValues{
+		findings: map[findingLabels]float64{},
+		objects: map[string]float64{
+			"Role":               float64(len(resources.Roles)),
+			"ClusterRole":        float64(len(resources.ClusterRoles)),
+			"RoleBinding":        float64(len(resources.RoleBindings)),
+			"ClusterRoleBinding": float64(len(resources.ClusterRoleBindings)),
+			"ServiceAccount":     float64(len(resources.ServiceAccounts)),
+		},
+		subjectScores: map[string]float64{},
+	}
+	for _, f := range report.Findings {
+		values.findings[findingLabels{string(f.Risk), f.RuleID, f.Namespace}]++
+	}
+	for _, s := range report.SubjectScores {
+		values.subjectScores[s.Subject.String()] = float64(s.Score)
+	}
+	timestamp := resources.Metadata.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	values.lastAudit = float64(timestamp.Unix())
+
+	e.audit.mu.Lock()
+	e.audit.values = values
+	e.audit.mu.Unlock()
+}
+
+// findingLabels are the severity, rule and namespace labels of rbaclens_findings
+type findingLabels struct{ severity, rule, namespace string }
+
+// auditValues are the audit metrics of one snapshot and its report
+type auditValues struct {
+	findings      map[findingLabels]float64
+	objects       map[string]float64
+	subjectScores map[string]float64
+	lastAudit     float64
+}
+
+// auditCollector collects the audit metrics of the latest audit. Before the first audit only
+// rbaclens_last_audit_timestamp_seconds is collected, as 0, so staleness alerts fire.
+type auditCollector struct {
+	findings      *prometheus.Desc
+	objects       *prometheus.Desc
+	subjectScores *prometheus.Desc
+	lastAudit     *prometheus.Desc
+
+	mu     sync.RWMutex
+	values *auditValues
+}
+
+// Describe implements prometheus.Collector
+func (c *auditCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.findings
+	ch <- c.objects
+	ch <- c.subjectScores
+	ch <- c.lastAudit
+}
+
+// Collect implements prometheus.Collector
+func (c *auditCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	values := c.values
+	c.mu.RUnlock()
+	if values == nil {
+		ch <- prometheus.MustNewConstMetric(c.lastAudit, prometheus.GaugeValue, 0)
+		return
+	}
+
+	for l, v := range values.findings {
+		ch <- prometheus.MustNewConstMetric(c.findings, prometheus.GaugeValue, v, l.severity, l.rule, l.namespace)
+	}
+	for kind, v := range values.objects {
+		ch <- prometheus.MustNewConstMetric(c.objects, prometheus.GaugeValue, v, kind)
+	}
+	for subject, v := range values.subjectScores {
+		ch <- prometheus.MustNewConstMetric(c.subjectScores, prometheus.GaugeValue, v, subject)
+	}
+	ch <- prometheus.MustNewConstMetric(c.lastAudit, prometheus.GaugeValue, values.lastAudit)
+}
+
+// ObserveFetch records a fetch that took duration and failed if err is not nil
+func (e *Exporter) ObserveFetch(duration time.Duration, err error) {
+	e.fetches.Inc()
+	e.fetchDuration.Observe(duration.Seconds())
+	if err != nil {
+		e.fetchErrors.Inc()
+	}
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// WriteTextfile writes the metrics to path in the text format read by the node-exporter
+// textfile collector. The file is replaced atomically.
+func (e *Exporter) WriteTextfile(path string) error {
+	return prometheus.WriteToTextfile(path, e.registry)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// gather returns the values of a metric by their label values in label name order, joined
+// with ","
+func gather(t *testing.T, e *Exporter, name string) map[string]float64 {
+	t.Helper()
+	families, err := e.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+	values := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetValue())
+			}
+			values[strings.Join(labels, ",")] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestUpdate(t *testing.T) {
+	e := NewExporter()
+	if got := gather(t, e, "rbaclens_last_audit_timestamp_seconds"); !reflect.DeepEqual(got, map[string]float64{"": 0}) {
+		t.Errorf("last audit before the first audit = %v, want 0", got)
+	}
+
+	resources := types.RBACResources{
+		Roles:    []rbacv1.Role{{}, {}},
+		Metadata: types.Metadata{Timestamp: time.Unix(1700000000, 0)},
+	}
+	report := audit.AuditReport{
+		Findings: []audit.AuditResult{
+			{RuleID: "secrets-read", Risk: audit.RiskHigh, Namespace: "team-a"},
+			{RuleID: "secrets-read", Risk: audit.RiskHigh, Namespace: "team-a"},
+			{RuleID: "wildcard-permissions", Risk: audit.RiskCritical},
+		},
+		SubjectScores: []audit.SubjectScore{{Subject: rbac.Subject{Kind: "User", Name: "alice"}, Score: 80}},
+	}
+	e.Update(resources, report)
+
+	findings := map[string]float64{
+		"team-a,secrets-read," + string(audit.RiskHigh):       2,
+		",wildcard-permissions," + string(audit.RiskCritical): 1,
+	}
+	if got := gather(t, e, "rbaclens_findings"); !reflect.DeepEqual(got, findings) {
+		t.Errorf("rbaclens_findings = %v, want %v", got, findings)
+	}
+	if got := gather(t, e, "rbaclens_rbac_objects")["Role"]; got != 2 {
+		t.Errorf("Role objects = %v, want 2", got)
+	}
+	if got := gather(t, e, "rbaclens_subject_risk_score"); !reflect.DeepEqual(got, map[string]float64{"User:alice": 80}) {
+		t.Errorf("rbaclens_subject_risk_score = %v", got)
+	}
+	if got := gather(t, e, "rbaclens_last_audit_timestamp_seconds")[""]; got != 1700000000 {
+		t.Errorf("last audit = %v, want 1700000000", got)
+	}
+
+	// Findings and subjects that went away are dropped
+	e.Update(resources, audit.AuditReport{})
+	if got := gather(t, e, "rbaclens_findings"); len(got) != 0 {
+		t.Errorf("rbaclens_findings after a clean audit = %v, want none", got)
+	}
+	if got := gather(t, e, "rbaclens_subject_risk_score"); len(got) != 0 {
+		t.Errorf("rbaclens_subject_risk_score after a clean audit = %v, want none", got)
+	}
+}
+
+func TestUpdateDuringScrape(t *testing.T) {
+	e := NewExporter()
+	resources := types.RBACResources{Roles: []rbacv1.Role{{}}}
+	report := audit.AuditReport{Findings: []audit.AuditResult{{RuleID: "secrets-read", Risk: audit.RiskHigh}}}
+	e.Update(resources, report)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			e.Update(resources, report)
+		}
+	}()
+	for range 200 {
+		if got := gather(t, e, "rbaclens_findings"); len(got) != 1 {
+			t.Fatalf("scrape during an update saw findings %v, want the complete audit", got)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,333 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// Input modes for policy evaluation
+const (
+	// InputSnapshot evaluates each policy once with the whole types.RBACResources snapshot as input
+	InputSnapshot = "snapshot"
+	// InputObject evaluates each policy once per Role, ClusterRole, RoleBinding and ClusterRoleBinding.
+	// The input is the object itself, as conftest expects, with the Gatekeeper-style
+	// review.object added alongside.
+	InputObject = "object"
+)
+
+// ruleSeverities are the rule names that produce violations and their default severity
+var ruleSeverities = map[string]audit.RiskLevel{
+	"deny":      audit.RiskHigh,
+	"violation": audit.RiskHigh,
+	"warn":      audit.RiskLow,
+}
+
+// Engine evaluates a set of compiled Rego policies
+type Engine struct {
+	queries []query
+	ids     []string
+}
+
+// query is a prepared deny, violation or warn rule of one package
+type query struct {
+	pkg      string
+	rule     string
+	severity audit.RiskLevel
+	prepared rego.PreparedEvalQuery
+}
+
+// Load compiles the .rego files at the given paths. Directories are searched recursively;
+// files ending in _test.rego are skipped. Modules written for Rego v0 are accepted as well as v1.
+func Load(ctx context.Context, paths ...string) (*Engine, error) {
+	modules := map[string]*ast.Module{}
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(file) != ".rego" || strings.HasSuffix(file, "_test.rego") {
+				return nil
+			}
+			module, err := parseModule(file)
+			if err != nil {
+				return err
+			}
+			modules[file] = module
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load policies: %w", err)
+		}
+	}
+
+	compiler := ast.NewCompiler()
+	compiler.Compile(modules)
+	if compiler.Failed() {
+		return nil, fmt.Errorf("failed to compile policies: %w", compiler.Errors)
+	}
+
+	// Find the violation rules of every package
+	seen := map[string]bool{}
+	ids := map[string]bool{}
+	engine := &Engine{}
+	for _, module := range modules {
+		pkg := strings.TrimPrefix(module.Package.Path.String(), "data.")
+		for _, rule := range module.Rules {
+			name := rule.Head.Ref()[0].Value.String()
+			severity, ok := ruleSeverities[name]
+			if !ok {
+				continue
+			}
+			for _, id := range literalIDs(rule) {
+				ids[id] = true
+			}
+			if seen[pkg+"."+name] {
+				continue
+			}
+			seen[pkg+"."+name] = true
+			ids[pkg+"."+name] = true
+
+			prepared, err := rego.New(
+				rego.Query(fmt.Sprintf("data.%s.%s", pkg, name)),
+				rego.Compiler(compiler),
+			).PrepareForEval(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare %s.%s: %w", pkg, name, err)
+			}
+			engine.queries = append(engine.queries, query{pkg: pkg, rule: name, severity: severity, prepared: prepared})
+		}
+	}
+	sort.Slice(engine.queries, func(i, j int) bool {
+		return engine.queries[i].pkg+"."+engine.queries[i].rule < engine.queries[j].pkg+"."+engine.queries[j].rule
+	})
+	for id := range ids {
+		engine.ids = append(engine.ids, id)
+	}
+	sort.Strings(engine.ids)
+
+	return engine, nil
+}
+
+// RuleIDs returns the rule IDs the policies can report: <package>.<rule> for each violation
+// rule, and the IDs its violations set with a literal "id" string
+func (e *Engine) RuleIDs() []string {
+	return e.ids
+}
+
+// literalIDs returns the literal strings given as "id" in the objects of a rule
+func literalIDs(rule *ast.Rule) []string {
+	var ids []string
+	ast.WalkTerms(rule, func(term *ast.Term) bool {
+		if obj, ok := term.Value.(ast.Object); ok {
+			if v := obj.Get(ast.StringTerm("id")); v != nil {
+				if id, ok := v.Value.(ast.String); ok {
+					ids = append(ids, string(id))
+				}
+			}
+		}
+		return false
+	})
+	return ids
+}
+
+// parseModule parses a policy as Rego v1, falling back to v0 for existing policy libraries
+func parseModule(file string) (*ast.Module, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	module, err := ast.ParseModuleWithOpts(file, string(data), ast.ParserOptions{RegoVersion: ast.RegoV1})
+	if err == nil {
+		return module, nil
+	}
+	if v0, v0err := ast.ParseModuleWithOpts(file, string(data), ast.ParserOptions{RegoVersion: ast.RegoV0}); v0err == nil {
+		return v0, nil
+	}
+	return nil, err
+}
+
+// Evaluate runs the policies against the snapshot and returns their violations as findings
+func (e *Engine) Evaluate(ctx context.Context, resources types.RBACResources, inputMode string) ([]audit.AuditResult, error) {
+	switch inputMode {
+	case InputSnapshot:
+		input, err := toValue(resources)
+		if err != nil {
+			return nil, err
+		}
+		return e.evaluate(ctx, input, audit.AuditResult{ResourceKind: "Cluster", ResourceName: resources.Metadata.ClusterName})
+	case InputObject:
+		var findings []audit.AuditResult
+		for _, obj := range objects(resources) {
+			input, err := toValue(obj.object)
+			if err != nil {
+				return nil, err
+			}
+			review, err := toValue(obj.object)
+			if err != nil {
+				return nil, err
+			}
+			input.(map[string]any)["review"] = map[string]any{
+				"kind":      map[string]any{"group": "rbac.authorization.k8s.io", "version": "v1", "kind": obj.kind},
+				"name":      obj.name,
+				"namespace": obj.namespace,
+				"object":    review,
+			}
+			results, err := e.evaluate(ctx, input, audit.AuditResult{ResourceKind: obj.kind, ResourceName: obj.name, Namespace: obj.namespace})
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, results...)
+		}
+		return findings, nil
+	}
+	return nil, fmt.Errorf("unknown policy input %q (supported: %s, %s)", inputMode, InputSnapshot, InputObject)
+}
+
+// evaluate runs every query against a single input. Violations that do not name a resource
+// are attributed to target.
+func (e *Engine) evaluate(ctx context.Context, input any, target audit.AuditResult) ([]audit.AuditResult, error) {
+	var findings []audit.AuditResult
+	for _, q := range e.queries {
+		rs, err := q.prepared.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s.%s: %w", q.pkg, q.rule, err)
+		}
+		for _, result := range rs {
+			for _, expr := range result.Expressions {
+				violations, err := q.violations(expr.Value)
+				if err != nil {
+					return nil, err
+				}
+				for _, v := range violations {
+					finding, err := q.finding(v, target)
+					if err != nil {
+						return nil, err
+					}
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// violations returns the violations in the value of a rule. Rules are usually sets of
+// violations, like deny contains msg if { ... }; a boolean rule like deny if { ... } is a
+// single violation without a message when true, and a string rule a single violation.
+func (q query) violations(value any) ([]any, error) {
+	switch value := value.(type) {
+	case []any:
+		return value, nil
+	case bool:
+		if value {
+			return []any{map[string]any{}}, nil
+		}
+		return nil, nil
+	case string:
+		return []any{value}, nil
+	}
+	return nil, fmt.Errorf("%s.%s must be a set, a boolean or a string, got %T", q.pkg, q.rule, value)
+}
+
+// finding converts a single violation into a finding. A violation is either a message string
+// or an object with msg (or message) and optional severity, id, kind, name and namespace.
+func (q query) finding(v any, target audit.AuditResult) (audit.AuditResult, error) {
+	finding := target
+	finding.RuleID = q.pkg + "." + q.rule
+	finding.Risk = q.severity
+
+	switch v := v.(type) {
+	case string:
+		finding.Reason = v
+	case map[string]any:
+		finding.Reason = firstString(v, "msg", "message")
+		if id := firstString(v, "id"); id != "" {
+			finding.RuleID = id
+		}
+		if severity := firstString(v, "severity"); severity != "" {
+			level, err := audit.ParseRiskLevel(severity)
+			if err != nil {
+				return finding, fmt.Errorf("%s.%s: %w", q.pkg, q.rule, err)
+			}
+			finding.Risk = level
+		}
+		if kind := firstString(v, "kind"); kind != "" {
+			finding.ResourceKind = kind
+			finding.ResourceName = firstString(v, "name")
+			finding.Namespace = firstString(v, "namespace")
+		}
+		finding.Remediation = firstString(v, "remediation")
+	default:
+		return finding, fmt.Errorf("%s.%s: violation must be a string or an object, got %T", q.pkg, q.rule, v)
+	}
+
+	if finding.Reason == "" {
+		finding.Reason = fmt.Sprintf("Policy %s.%s was violated.", q.pkg, q.rule)
+	}
+	finding.Score = finding.Risk.Score()
+	return finding, nil
+}
+
+// firstString returns the first of the keys that holds a string value
+func firstString(m map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// toValue converts a Go value into the generic JSON form policies see as input
+func toValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy input: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy input: %w", err)
+	}
+	return value, nil
+}
+
+// rbacObject is a single RBAC object, with its kind and apiVersion filled in
+type rbacObject struct {
+	kind      string
+	name      string
+	namespace string
+	object    any
+}
+
+// objects lists the RBAC objects of a snapshot. Objects returned by List calls do not carry
+// their kind, so it is set here for policies that match on it.
+func objects(resources types.RBACResources) []rbacObject {
+	var objs []rbacObject
+	for _, cr := range resources.ClusterRoles {
+		cr.Kind, cr.APIVersion = "ClusterRole", "rbac.authorization.k8s.io/v1"
+		objs = append(objs, rbacObject{"ClusterRole", cr.Name, "", cr})
+	}
+	for _, r := range resources.Roles {
+		r.Kind, r.APIVersion = "Role", "rbac.authorization.k8s.io/v1"
+		objs = append(objs, rbacObject{"Role", r.Name, r.Namespace, r})
+	}
+	for _, crb := range resources.ClusterRoleBindings {
+		crb.Kind, crb.APIVersion = "ClusterRoleBinding", "rbac.authorization.k8s.io/v1"
+		objs = append(objs, rbacObject{"ClusterRoleBinding", crb.Name, "", crb})
+	}
+	for _, rb := range resources.RoleBindings {
+		rb.Kind, rb.APIVersion = "RoleBinding", "rbac.authorization.k8s.io/v1"
+		objs = append(objs, rbacObject{"RoleBinding", rb.Name, rb.Namespace, rb})
+	}
+	return objs
+}
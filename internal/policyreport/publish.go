@@ -0,0 +1,153 @@
+package policyreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	policyReportsResource        = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "policyreports"}
+	clusterPolicyReportsResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "clusterpolicyreports"}
+)
+
+// Publisher writes reports to the cluster. It remembers what it wrote, so reports whose
+// results did not change are left alone and reports no longer built are deleted.
+type Publisher struct {
+	client dynamic.Interface
+	// namespace limits the PolicyReports the publisher owns to one namespace, if not empty
+	namespace string
+	// published maps the kind and namespace of each report in the cluster to its content
+	// when last written, empty for reports found at startup
+	published map[reportKey]string
+}
+
+type reportKey struct{ kind, namespace string }
+
+// NewPublisher returns a publisher writing reports through the client. If namespace is not
+// empty, PolicyReports left over from a previous run are only looked for, and deleted, in
+// that namespace, so instances watching different namespaces do not remove each other's.
+func NewPublisher(client dynamic.Interface, namespace string) *Publisher {
+	return &Publisher{client: client, namespace: namespace}
+}
+
+// Publish makes the RBACLens reports in the cluster match reports. It returns the number of
+// reports created, updated or deleted. A failure on one report does not stop the others.
+func (p *Publisher) Publish(ctx context.Context, reports []Report) (int, error) {
+	if p.published == nil {
+		if err := p.loadPublished(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	var errs []error
+	changed := 0
+	current := map[reportKey]bool{}
+	for _, report := range reports {
+		key := reportKey{report.Kind, report.Namespace}
+		current[key] = true
+		content, err := reportContent(report)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if p.published[key] == content {
+			continue
+		}
+		if err := p.write(ctx, report); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s %s: %w", report.Kind, describe(key), err))
+			continue
+		}
+		p.published[key] = content
+		changed++
+	}
+
+	for key := range p.published {
+		if current[key] {
+			continue
+		}
+		err := p.resource(key.kind, key.namespace).Delete(ctx, Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", key.kind, describe(key), err))
+			continue
+		}
+		delete(p.published, key)
+		changed++
+	}
+	return changed, errors.Join(errs...)
+}
+
+// loadPublished finds the reports RBACLens wrote before it was restarted
+func (p *Publisher) loadPublished(ctx context.Context) error {
+	published := map[reportKey]string{}
+	selector := metav1.ListOptions{LabelSelector: ManagedByLabel + "=" + Source}
+	for _, kind := range []string{KindClusterPolicyReport, KindPolicyReport} {
+		list, err := p.resource(kind, p.namespace).List(ctx, selector)
+		if err != nil {
+			return fmt.Errorf("failed to list %ss: %w", kind, err)
+		}
+		for _, item := range list.Items {
+			if item.GetName() == Name {
+				published[reportKey{kind, item.GetNamespace()}] = ""
+			}
+		}
+	}
+	p.published = published
+	return nil
+}
+
+// write creates the report, or replaces it if it exists
+func (p *Publisher) write(ctx context.Context, report Report) error {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&report)
+	if err != nil {
+		return err
+	}
+	object := &unstructured.Unstructured{Object: data}
+	resource := p.resource(report.Kind, report.Namespace)
+
+	existing, err := resource.Get(ctx, Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = resource.Create(ctx, object, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	object.SetResourceVersion(existing.GetResourceVersion())
+	_, err = resource.Update(ctx, object, metav1.UpdateOptions{})
+	return err
+}
+
+func (p *Publisher) resource(kind, namespace string) dynamic.ResourceInterface {
+	if kind == KindClusterPolicyReport {
+		return p.client.Resource(clusterPolicyReportsResource)
+	}
+	return p.client.Resource(policyReportsResource).Namespace(namespace)
+}
+
+// reportContent returns the results of a report without their timestamps, so a report is
+// only rewritten when its findings change
+func reportContent(report Report) (string, error) {
+	results := make([]Result, len(report.Results))
+	for i, r := range report.Results {
+		r.Timestamp = metav1.Timestamp{}
+		results[i] = r
+	}
+	data, err := json.Marshal(results)
+	return string(data), err
+}
+
+func describe(key reportKey) string {
+	if key.namespace == "" {
+		return Name
+	}
+	return key.namespace + "/" + Name
+}
@@ -0,0 +1,109 @@
+package policyreport
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// fakeClient returns a dynamic client holding the given reports
+func fakeClient(reports ...Report) *dynamicfake.FakeDynamicClient {
+	var objects []runtime.Object
+	for _, r := range reports {
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&r)
+		if err != nil {
+			panic(err)
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: data})
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		policyReportsResource:        "PolicyReportList",
+		clusterPolicyReportsResource: "ClusterPolicyReportList",
+	}, objects...)
+}
+
+// stored lists the namespaces of the reports in the client, "" for the ClusterPolicyReport
+func stored(t *testing.T, client *dynamicfake.FakeDynamicClient) []string {
+	t.Helper()
+	var namespaces []string
+	for _, gvr := range []schema.GroupVersionResource{clusterPolicyReportsResource, policyReportsResource} {
+		list, err := client.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("List(%s) failed: %v", gvr.Resource, err)
+		}
+		for _, item := range list.Items {
+			namespaces = append(namespaces, item.GetNamespace())
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+func TestPublish(t *testing.T) {
+	client := fakeClient(newReport(KindPolicyReport, "team-b"))
+	p := NewPublisher(client, "")
+
+	findings := []audit.AuditResult{
+		{RuleID: "wildcard-permissions", Risk: audit.RiskCritical, ResourceKind: "ClusterRole", ResourceName: "wild"},
+		{RuleID: "secrets-read", Risk: audit.RiskHigh, ResourceKind: "Role", ResourceName: "reader", Namespace: "team-a"},
+	}
+	reports := Build(audit.AuditReport{Findings: findings}, time.Now())
+
+	// The reports are created and the stale team-b report is deleted
+	changed, err := p.Publish(context.Background(), reports)
+	if err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	if changed != 3 {
+		t.Errorf("Publish() changed %d reports, want 3", changed)
+	}
+	if got := stored(t, client); len(got) != 2 || got[0] != "" || got[1] != "team-a" {
+		t.Errorf("stored reports = %q, want the cluster report and team-a", got)
+	}
+
+	// Unchanged results are not written again, even with new timestamps
+	changed, err = p.Publish(context.Background(), Build(audit.AuditReport{Findings: findings}, time.Now().Add(time.Hour)))
+	if err != nil || changed != 0 {
+		t.Errorf("Publish() of the same findings = %d, %v, want 0 changes", changed, err)
+	}
+
+	// A changed report is updated in place
+	findings[1].Reason = "now with a reason"
+	changed, err = p.Publish(context.Background(), Build(audit.AuditReport{Findings: findings}, time.Now()))
+	if err != nil || changed != 1 {
+		t.Errorf("Publish() of a changed finding = %d, %v, want 1 change", changed, err)
+	}
+	object, err := client.Resource(policyReportsResource).Namespace("team-a").Get(context.Background(), Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	results, _, _ := unstructured.NestedSlice(object.Object, "results")
+	if len(results) != 1 || results[0].(map[string]any)["message"] != "now with a reason" {
+		t.Errorf("team-a results = %v, want the updated finding", results)
+	}
+}
+
+func TestPublishNamespace(t *testing.T) {
+	client := fakeClient(newReport(KindPolicyReport, "team-a"), newReport(KindPolicyReport, "team-b"))
+	p := NewPublisher(client, "team-a")
+
+	// Only the report of the publisher's namespace is removed
+	changed, err := p.Publish(context.Background(), Build(audit.AuditReport{}, time.Now()))
+	if err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("Publish() changed %d reports, want 2", changed)
+	}
+	if got := stored(t, client); len(got) != 2 || got[0] != "" || got[1] != "team-b" {
+		t.Errorf("stored reports = %q, want the cluster report and team-b", got)
+	}
+}
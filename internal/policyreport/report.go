@@ -0,0 +1,147 @@
+package policyreport
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Report identity
+const (
+	Group      = "wgpolicyk8s.io"
+	Version    = "v1alpha2"
+	APIVersion = Group + "/" + Version
+
+	KindPolicyReport        = "PolicyReport"
+	KindClusterPolicyReport = "ClusterPolicyReport"
+
+	// Name is the name of every report written by RBACLens, one per namespace and one for
+	// the cluster
+	Name = "rbaclens"
+	// Source is the source of every result
+	Source = "rbaclens"
+	// ManagedByLabel marks reports written by RBACLens, so stale ones can be found and deleted
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+)
+
+// Result statuses
+const (
+	ResultFail = "fail"
+	ResultWarn = "warn"
+)
+
+// Report is a wgpolicyk8s.io PolicyReport or ClusterPolicyReport
+type Report struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Summary           Summary  `json:"summary"`
+	Results           []Result `json:"results,omitempty"`
+}
+
+// Summary counts the results of a report by status
+type Summary struct {
+	Pass  int `json:"pass"`
+	Fail  int `json:"fail"`
+	Warn  int `json:"warn"`
+	Error int `json:"error"`
+	Skip  int `json:"skip"`
+}
+
+// Result is a single finding in a report
+type Result struct {
+	Source    string           `json:"source"`
+	Policy    string           `json:"policy"`
+	Category  string           `json:"category,omitempty"`
+	Severity  string           `json:"severity,omitempty"`
+	Result    string           `json:"result"`
+	Message   string           `json:"message,omitempty"`
+	Scored    bool             `json:"scored"`
+	Timestamp metav1.Timestamp `json:"timestamp"`
+	// Resources is the Role, ClusterRole or binding the finding is about
+	Resources  []corev1.ObjectReference `json:"resources,omitempty"`
+	Properties map[string]string        `json:"properties,omitempty"`
+}
+
+// Build converts the findings of an audit report into a ClusterPolicyReport for cluster-scoped
+// objects and a PolicyReport for each namespace with findings. The ClusterPolicyReport comes
+// first and is returned even when empty.
+func Build(report audit.AuditReport, timestamp time.Time) []Report {
+	cluster := newReport(KindClusterPolicyReport, "")
+	namespaced := map[string]*Report{}
+	for _, f := range report.Findings {
+		r := &cluster
+		if f.Namespace != "" {
+			if namespaced[f.Namespace] == nil {
+				report := newReport(KindPolicyReport, f.Namespace)
+				namespaced[f.Namespace] = &report
+			}
+			r = namespaced[f.Namespace]
+		}
+		r.add(newResult(f, timestamp))
+	}
+
+	reports := []Report{cluster}
+	for _, r := range namespaced {
+		reports = append(reports, *r)
+	}
+	sort.Slice(reports[1:], func(i, j int) bool { return reports[i+1].Namespace < reports[j+1].Namespace })
+	return reports
+}
+
+func newReport(kind, namespace string) Report {
+	return Report{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      Name,
+			Namespace: namespace,
+			Labels:    map[string]string{ManagedByLabel: Source},
+		},
+	}
+}
+
+func (r *Report) add(result Result) {
+	r.Results = append(r.Results, result)
+	switch result.Result {
+	case ResultFail:
+		r.Summary.Fail++
+	case ResultWarn:
+		r.Summary.Warn++
+	}
+}
+
+// newResult converts a finding into a report result. Low and info findings are warnings,
+// the rest failures.
+func newResult(f audit.AuditResult, timestamp time.Time) Result {
+	status := ResultFail
+	if f.Risk.Score() <= audit.RiskLow.Score() {
+		status = ResultWarn
+	}
+	properties := map[string]string{"score": strconv.Itoa(f.Score)}
+	if f.Exposure != "" {
+		properties["exposure"] = f.Exposure
+	}
+	if f.Remediation != "" {
+		properties["remediation"] = f.Remediation
+	}
+	return Result{
+		Source:    Source,
+		Policy:    f.RuleID,
+		Category:  "RBAC",
+		Severity:  string(f.Risk),
+		Result:    status,
+		Message:   f.Reason,
+		Scored:    true,
+		Timestamp: metav1.Timestamp{Seconds: timestamp.Unix()},
+		Resources: []corev1.ObjectReference{{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       f.ResourceKind,
+			Namespace:  f.Namespace,
+			Name:       f.ResourceName,
+		}},
+		Properties: properties,
+	}
+}
@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"slices"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Access is a single resource request, as recorded in an API server audit log
+type Access struct {
+	Verb     string `json:"verb"`
+	APIGroup string `json:"apiGroup"`
+	// Resource includes the subresource, if any, e.g. "pods/log"
+	Resource string `json:"resource"`
+	// Namespace is empty for cluster-scoped resources
+	Namespace string `json:"namespace,omitempty"`
+	// Name is empty for requests on collections, such as list or create
+	Name string `json:"name,omitempty"`
+}
+
+// AuthorizedName returns the object name the RBAC authorizer matches resourceNames against.
+// Audit logs record the name of created objects, but creating an object (other than a
+// subresource of a named object) and deleting a collection are authorized without a name, so
+// rules restricted by resourceNames never allow them.
+func (a Access) AuthorizedName() string {
+	if a.Verb == "deletecollection" || (a.Verb == "create" && !strings.Contains(a.Resource, "/")) {
+		return ""
+	}
+	return a.Name
+}
+
+// RuleAllows reports whether a policy rule permits the access, following the matching
+// rules of the Kubernetes RBAC authorizer
+func RuleAllows(rule rbacv1.PolicyRule, a Access) bool {
+	if !matches(rule.Verbs, a.Verb) || !matches(rule.APIGroups, a.APIGroup) {
+		return false
+	}
+	if !slices.ContainsFunc(rule.Resources, func(r string) bool { return ResourceMatches(r, a.Resource) }) {
+		return false
+	}
+	name := a.AuthorizedName()
+	return len(rule.ResourceNames) == 0 || (name != "" && slices.Contains(rule.ResourceNames, name))
+}
+
+// Allows reports whether the grant permits the access. Grants through RoleBindings only
+// apply within the namespace of the binding.
+func (g Grant) Allows(a Access) bool {
+	if g.Namespace != "" && g.Namespace != a.Namespace {
+		return false
+	}
+	return RuleAllows(g.Rule, a)
+}
+
+// EffectiveGrants returns the rules held by a subject directly and through the groups it
+// belongs to
+func (i *Index) EffectiveGrants(subject Subject, groups []string) []Grant {
+	return SubjectGrants(i.Grants(), subject, groups)
+}
+
+// SubjectGrants returns the grants held by a subject directly and through the groups it
+// belongs to, from a list of grants. Callers looking up many subjects can build the list once
+// with Grants.
+func SubjectGrants(grants []Grant, subject Subject, groups []string) []Grant {
+	var held []Grant
+	for _, g := range grants {
+		if g.Subject == subject || (g.Subject.Kind == "Group" && slices.Contains(groups, g.Subject.Name)) {
+			held = append(held, g)
+		}
+	}
+	return held
+}
+
+// ServiceAccountGroups returns the groups every ServiceAccount in a namespace belongs to
+func ServiceAccountGroups(namespace string) []string {
+	return []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"}
+}
+
+// matches reports whether values contain value or the "*" wildcard
+func matches(values []string, value string) bool {
+	return slices.Contains(values, rbacv1.VerbAll) || slices.Contains(values, value)
+}
+
+// ResourceMatches reports whether a rule resource covers the requested resource. Besides
+// exact matches and "*", "*/sub" covers the subresource sub of any resource.
+func ResourceMatches(ruleResource, resource string) bool {
+	if ruleResource == rbacv1.ResourceAll || ruleResource == resource {
+		return true
+	}
+	if sub, ok := strings.CutPrefix(ruleResource, "*/"); ok {
+		_, requested, found := strings.Cut(resource, "/")
+		return found && requested == sub
+	}
+	return false
+}
+
+// WhoCan returns the grants that permit the access, across all subjects
+func (i *Index) WhoCan(a Access) []Grant {
+	var grants []Grant
+	for _, g := range i.Grants() {
+		if g.Allows(a) {
+			grants = append(grants, g)
+		}
+	}
+	return grants
+}
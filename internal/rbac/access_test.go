@@ -0,0 +1,50 @@
+package rbac
+
+import (
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestRuleAllows(t *testing.T) {
+	named := rbacv1.PolicyRule{
+		APIGroups:     []string{""},
+		Resources:     []string{"configmaps", "pods/eviction"},
+		Verbs:         []string{"get", "create", "deletecollection"},
+		ResourceNames: []string{"foo"},
+	}
+	tests := []struct {
+		name   string
+		rule   rbacv1.PolicyRule
+		access Access
+		want   bool
+	}{
+		{"exact match", named, Access{Verb: "get", Resource: "configmaps", Name: "foo"}, true},
+		{"other name", named, Access{Verb: "get", Resource: "configmaps", Name: "bar"}, false},
+		{"no name with resourceNames", named, Access{Verb: "get", Resource: "configmaps"}, false},
+		{"named create", named, Access{Verb: "create", Resource: "configmaps", Name: "foo"}, false},
+		{"named deletecollection", named, Access{Verb: "deletecollection", Resource: "configmaps", Name: "foo"}, false},
+		{"named create of a subresource", named, Access{Verb: "create", Resource: "pods/eviction", Name: "foo"}, true},
+		{"other verb", named, Access{Verb: "delete", Resource: "configmaps", Name: "foo"}, false},
+		{"other group", named, Access{Verb: "get", APIGroup: "apps", Resource: "configmaps", Name: "foo"}, false},
+		{
+			"wildcards",
+			rbacv1.PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			Access{Verb: "create", APIGroup: "apps", Resource: "deployments", Name: "web"},
+			true,
+		},
+		{
+			"subresource wildcard",
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"*/log"}, Verbs: []string{"get"}},
+			Access{Verb: "get", Resource: "pods/log", Name: "web"},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RuleAllows(tt.rule, tt.access); got != tt.want {
+				t.Errorf("RuleAllows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
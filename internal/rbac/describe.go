@@ -0,0 +1,32 @@
+package rbac
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// String returns the binding as Kind/name, or Kind/namespace/name for RoleBindings
+func (b Binding) String() string {
+	if b.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", b.Kind, b.Namespace, b.Name)
+	}
+	return fmt.Sprintf("%s/%s", b.Kind, b.Name)
+}
+
+// DescribeRule returns a policy rule in short form, e.g. "get,list pods,services (apps) [web]"
+func DescribeRule(rule rbacv1.PolicyRule) string {
+	if len(rule.NonResourceURLs) > 0 {
+		return fmt.Sprintf("%s %s", strings.Join(rule.Verbs, ","), strings.Join(rule.NonResourceURLs, ","))
+	}
+	s := fmt.Sprintf("%s %s", strings.Join(rule.Verbs, ","), strings.Join(rule.Resources, ","))
+	if groups := slices.DeleteFunc(slices.Clone(rule.APIGroups), func(g string) bool { return g == "" }); len(groups) > 0 {
+		s += fmt.Sprintf(" (%s)", strings.Join(groups, ","))
+	}
+	if len(rule.ResourceNames) > 0 {
+		s += fmt.Sprintf(" [%s]", strings.Join(rule.ResourceNames, ","))
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Binding is a RoleBinding or ClusterRoleBinding in a common shape
+type Binding struct {
+	Kind      string           `json:"kind"`
+	Name      string           `json:"name"`
+	Namespace string           `json:"namespace,omitempty"`
+	RoleRef   rbacv1.RoleRef   `json:"roleRef"`
+	Subjects  []rbacv1.Subject `json:"subjects,omitempty"`
+}
+
+// roleKey identifies a Role or ClusterRole. Namespace is empty for ClusterRoles.
+type roleKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// Index answers which bindings refer to a role and which rules a binding grants
+type Index struct {
+	bindings []Binding
+	byRole   map[roleKey][]Binding
+	rules    map[roleKey][]rbacv1.PolicyRule
+}
+
+// NewIndex builds an index over the roles and bindings of a snapshot
+func NewIndex(resources types.RBACResources) *Index {
+	index := &Index{
+		byRole: map[roleKey][]Binding{},
+		rules:  map[roleKey][]rbacv1.PolicyRule{},
+	}
+
+	for _, cr := range resources.ClusterRoles {
+		index.rules[roleKey{"ClusterRole", "", cr.Name}] = cr.Rules
+	}
+	for _, r := range resources.Roles {
+		index.rules[roleKey{"Role", r.Namespace, r.Name}] = r.Rules
+	}
+
+	for _, crb := range resources.ClusterRoleBindings {
+		index.add(Binding{
+			Kind:     "ClusterRoleBinding",
+			Name:     crb.Name,
+			RoleRef:  crb.RoleRef,
+			Subjects: crb.Subjects,
+		})
+	}
+	for _, rb := range resources.RoleBindings {
+		index.add(Binding{
+			Kind:      "RoleBinding",
+			Name:      rb.Name,
+			Namespace: rb.Namespace,
+			RoleRef:   rb.RoleRef,
+			Subjects:  rb.Subjects,
+		})
+	}
+
+	return index
+}
+
+func (i *Index) add(b Binding) {
+	i.bindings = append(i.bindings, b)
+	key := refKey(b)
+	i.byRole[key] = append(i.byRole[key], b)
+}
+
+// Bindings returns all bindings in the snapshot, ClusterRoleBindings first
+func (i *Index) Bindings() []Binding {
+	return i.bindings
+}
+
+// BindingsFor returns the bindings that refer to a role. Namespace is ignored for ClusterRoles,
+// which may be referenced by ClusterRoleBindings and by RoleBindings in any namespace.
+func (i *Index) BindingsFor(kind string, namespace string, name string) []Binding {
+	if kind == "ClusterRole" {
+		namespace = ""
+	}
+	return i.byRole[roleKey{kind, namespace, name}]
+}
+
+// Rules returns the rules of the role a binding refers to. The second return value is false
+// if the role does not exist in the snapshot.
+func (i *Index) Rules(b Binding) ([]rbacv1.PolicyRule, bool) {
+	rules, ok := i.rules[refKey(b)]
+	return rules, ok
+}
+
+// refKey returns the key of the role a binding refers to. A RoleBinding may refer to
+// a Role in its own namespace or to a ClusterRole.
+func refKey(b Binding) roleKey {
+	if b.RoleRef.Kind == "ClusterRole" {
+		return roleKey{"ClusterRole", "", b.RoleRef.Name}
+	}
+	return roleKey{b.RoleRef.Kind, b.Namespace, b.RoleRef.Name}
+}
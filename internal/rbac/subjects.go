@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Subject identifies a User, Group or ServiceAccount. Namespace is only set for ServiceAccounts.
+type Subject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// NewSubject normalises a binding subject. ServiceAccount subjects without a namespace
+// default to the namespace of the binding.
+func NewSubject(s rbacv1.Subject, bindingNamespace string) Subject {
+	subject := Subject{Kind: s.Kind, Name: s.Name}
+	if s.Kind == "ServiceAccount" {
+		subject.Namespace = s.Namespace
+		if subject.Namespace == "" {
+			subject.Namespace = bindingNamespace
+		}
+	}
+	return subject
+}
+
+// String returns the subject as Kind:name, or ServiceAccount:namespace/name
+func (s Subject) String() string {
+	if s.Namespace != "" {
+		return fmt.Sprintf("%s:%s/%s", s.Kind, s.Namespace, s.Name)
+	}
+	return fmt.Sprintf("%s:%s", s.Kind, s.Name)
+}
+
+// ParseSubject parses a subject in the form returned by String
+func ParseSubject(s string) (Subject, error) {
+	kind, name, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return Subject{}, fmt.Errorf("invalid subject %q, expected Kind:name or ServiceAccount:namespace/name", s)
+	}
+	switch kind {
+	case "User", "Group":
+		return Subject{Kind: kind, Name: name}, nil
+	case "ServiceAccount":
+		namespace, name, ok := strings.Cut(name, "/")
+		if !ok || namespace == "" || name == "" {
+			return Subject{}, fmt.Errorf("invalid subject %q, expected ServiceAccount:namespace/name", s)
+		}
+		return Subject{Kind: kind, Name: name, Namespace: namespace}, nil
+	}
+	return Subject{}, fmt.Errorf("invalid subject kind %q, expected User, Group or ServiceAccount", kind)
+}
+
+// Grant is a single policy rule held by a subject through a binding
+type Grant struct {
+	Subject Subject `json:"subject"`
+	Binding Binding `json:"binding"`
+	// Namespace is where the rule applies; empty for cluster-wide grants from ClusterRoleBindings
+	Namespace string            `json:"namespace,omitempty"`
+	Rule      rbacv1.PolicyRule `json:"rule"`
+}
+
+// Grants returns every rule held by every subject. Bindings to roles that do not exist
+// in the snapshot grant nothing.
+func (i *Index) Grants() []Grant {
+	var grants []Grant
+	for _, b := range i.bindings {
+		rules, ok := i.Rules(b)
+		if !ok {
+			continue
+		}
+		for _, s := range b.Subjects {
+			subject := NewSubject(s, b.Namespace)
+			for _, rule := range rules {
+				grants = append(grants, Grant{
+					Subject:   subject,
+					Binding:   b,
+					Namespace: b.Namespace,
+					Rule:      rule,
+				})
+			}
+		}
+	}
+	return grants
+}
+
+// GrantsFor returns the effective permissions of a single subject
+func (i *Index) GrantsFor(subject Subject) []Grant {
+	var grants []Grant
+	for _, g := range i.Grants() {
+		if g.Subject == subject {
+			grants = append(grants, g)
+		}
+	}
+	return grants
+}
+
+// Subjects returns all subjects that appear in a binding, sorted by their string form
+func (i *Index) Subjects() []Subject {
+	seen := map[Subject]bool{}
+	var subjects []Subject
+	for _, b := range i.bindings {
+		for _, s := range b.Subjects {
+			subject := NewSubject(s, b.Namespace)
+			if !seen[subject] {
+				seen[subject] = true
+				subjects = append(subjects, subject)
+			}
+		}
+	}
+	sort.Slice(subjects, func(a, b int) bool {
+		return subjects[a].String() < subjects[b].String()
+	})
+	return subjects
+}
@@ -0,0 +1,271 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	serviceAccountUserPrefix  = "system:serviceaccount:"
+	serviceAccountGroupPrefix = "system:serviceaccounts:"
+	nodeUserPrefix            = "system:node:"
+)
+
+// Redactor replaces user, group, ServiceAccount, namespace, role, binding and workload names
+// with pseudonyms derived with HMAC-SHA256, so the same name always gets the same pseudonym
+// under the same key.
+//
+// Names the audit treats specially keep their names, so a redacted snapshot audits the same
+// as the original: system namespaces, the default ServiceAccount, system: users and groups
+// and built-in roles and bindings. Names embedded in system:serviceaccount: and
+// system:serviceaccounts: identities are replaced consistently with the subjects they refer
+// to, and role references with the roles they refer to.
+type Redactor struct {
+	key     []byte
+	options Options
+}
+
+// Options configures what a Redactor keeps
+type Options struct {
+	// KeepLabels keeps object labels, which are removed by default
+	KeepLabels bool
+}
+
+// New returns a Redactor deriving pseudonyms with key
+func New(key []byte, options Options) *Redactor {
+	return &Redactor{key: key, options: options}
+}
+
+// Snapshot redacts a snapshot in place. Besides replacing names, it removes labels and
+// aggregation label selectors unless labels are kept, annotations (including kubectl.kubernetes.io/last-applied-configuration),
+// owner references and managedFields from every object, and the cluster server, ID and
+// fetching user's UID from the metadata.
+func (r *Redactor) Snapshot(resources *types.RBACResources) {
+	meta := &resources.Metadata
+	meta.ClusterName = r.pseudonym("cluster", meta.ClusterName)
+	meta.Context = r.pseudonym("context", meta.Context)
+	meta.Server = ""
+	meta.ClusterID = ""
+	if meta.FetchedBy != nil {
+		meta.FetchedBy.Username = r.User(meta.FetchedBy.Username)
+		meta.FetchedBy.UID = ""
+		for i, group := range meta.FetchedBy.Groups {
+			meta.FetchedBy.Groups[i] = r.Group(group)
+		}
+	}
+	for i, ns := range meta.Namespaces {
+		meta.Namespaces[i] = r.Namespace(ns)
+	}
+
+	for i := range resources.Roles {
+		r.objectMeta(&resources.Roles[i].ObjectMeta, r.Role)
+		r.rules(resources.Roles[i].Rules)
+	}
+	for i := range resources.ClusterRoles {
+		r.objectMeta(&resources.ClusterRoles[i].ObjectMeta, r.Role)
+		r.rules(resources.ClusterRoles[i].Rules)
+		// The label selectors of an aggregated ClusterRole go with the labels. The rules
+		// they aggregated are already in the ClusterRole.
+		if !r.options.KeepLabels {
+			resources.ClusterRoles[i].AggregationRule = nil
+		}
+	}
+	for i := range resources.RoleBindings {
+		r.objectMeta(&resources.RoleBindings[i].ObjectMeta, r.Binding)
+		r.roleRef(&resources.RoleBindings[i].RoleRef)
+		r.subjects(resources.RoleBindings[i].Subjects)
+	}
+	for i := range resources.ClusterRoleBindings {
+		r.objectMeta(&resources.ClusterRoleBindings[i].ObjectMeta, r.Binding)
+		r.roleRef(&resources.ClusterRoleBindings[i].RoleRef)
+		r.subjects(resources.ClusterRoleBindings[i].Subjects)
+	}
+	for i := range resources.ServiceAccounts {
+		sa := &resources.ServiceAccounts[i]
+		sa.Namespace = r.Namespace(sa.Namespace)
+		sa.Name = r.ServiceAccount(sa.Name)
+	}
+	for i := range resources.Workloads {
+		w := &resources.Workloads[i]
+		w.Namespace = r.Namespace(w.Namespace)
+		w.Name = r.pseudonym("workload", w.Name)
+		w.ServiceAccount = r.ServiceAccount(w.ServiceAccount)
+	}
+}
+
+// Namespace returns the pseudonym of a namespace. System namespaces keep their names.
+func (r *Redactor) Namespace(name string) string {
+	if name == "" || audit.IsSystemNamespace(name) || strings.HasPrefix(name, "kube-") {
+		return name
+	}
+	return r.pseudonym("ns", name)
+}
+
+// ServiceAccount returns the pseudonym of a ServiceAccount name. The default ServiceAccount
+// keeps its name.
+func (r *Redactor) ServiceAccount(name string) string {
+	if name == "" || name == "default" {
+		return name
+	}
+	return r.pseudonym("sa", name)
+}
+
+// User returns the pseudonym of a user name. ServiceAccount and node users keep their
+// prefix, and other system: users keep their names.
+func (r *Redactor) User(name string) string {
+	if rest, ok := strings.CutPrefix(name, serviceAccountUserPrefix); ok {
+		if ns, sa, ok := strings.Cut(rest, ":"); ok {
+			return serviceAccountUserPrefix + r.Namespace(ns) + ":" + r.ServiceAccount(sa)
+		}
+	}
+	if node, ok := strings.CutPrefix(name, nodeUserPrefix); ok {
+		return nodeUserPrefix + r.pseudonym("node", node)
+	}
+	if strings.HasPrefix(name, "system:") {
+		return name
+	}
+	return r.pseudonym("user", name)
+}
+
+// Group returns the pseudonym of a group name. Namespace ServiceAccount groups keep their
+// prefix, and other system: groups keep their names.
+func (r *Redactor) Group(name string) string {
+	if ns, ok := strings.CutPrefix(name, serviceAccountGroupPrefix); ok {
+		return serviceAccountGroupPrefix + r.Namespace(ns)
+	}
+	if strings.HasPrefix(name, "system:") {
+		return name
+	}
+	return r.pseudonym("group", name)
+}
+
+// Role returns the pseudonym of a Role or ClusterRole name. Roles and ClusterRoles share
+// pseudonyms, so role references resolve whichever kind they refer to.
+func (r *Redactor) Role(name string) string {
+	return r.objectName("role", name)
+}
+
+// Binding returns the pseudonym of a RoleBinding or ClusterRoleBinding name
+func (r *Redactor) Binding(name string) string {
+	return r.objectName("binding", name)
+}
+
+// objectName returns the pseudonym of a role or binding name. Names the audit treats as
+// system components keep the prefix that marks them, and built-in names such as
+// cluster-admin and system: and kubeadm: names are kept whole.
+func (r *Redactor) objectName(kind, name string) string {
+	prefix := audit.SystemResourcePrefix(name)
+	if name == prefix || strings.HasSuffix(prefix, ":") {
+		return name
+	}
+	if prefix != "" {
+		return prefix + "-" + r.pseudonym(kind, name)
+	}
+	return r.pseudonym(kind, name)
+}
+
+func (r *Redactor) objectMeta(meta *metav1.ObjectMeta, name func(string) string) {
+	meta.Name = name(meta.Name)
+	meta.Namespace = r.Namespace(meta.Namespace)
+	if !r.options.KeepLabels {
+		meta.Labels = nil
+	}
+	meta.Annotations = nil
+	meta.OwnerReferences = nil
+	meta.ManagedFields = nil
+}
+
+func (r *Redactor) roleRef(ref *rbacv1.RoleRef) {
+	ref.Name = r.Role(ref.Name)
+}
+
+func (r *Redactor) subjects(subjects []rbacv1.Subject) {
+	for i := range subjects {
+		s := &subjects[i]
+		switch s.Kind {
+		case rbacv1.UserKind:
+			s.Name = r.User(s.Name)
+		case rbacv1.GroupKind:
+			s.Name = r.Group(s.Name)
+		case rbacv1.ServiceAccountKind:
+			s.Namespace = r.Namespace(s.Namespace)
+			s.Name = r.ServiceAccount(s.Name)
+		}
+	}
+}
+
+// rules redacts resourceNames of rules on users, groups, ServiceAccounts or namespaces, such
+// as impersonation rules. Names of rules on several kinds of resource, one of which is an
+// identity or namespace, cannot be attributed to a kind and get generic name- pseudonyms.
+func (r *Redactor) rules(rules []rbacv1.PolicyRule) {
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.ResourceNames) == 0 {
+			continue
+		}
+		redact := r.resourceNameFunc(rule.Resources)
+		if redact == nil {
+			continue
+		}
+		names := make([]string, len(rule.ResourceNames))
+		for j, name := range rule.ResourceNames {
+			names[j] = redact(name)
+		}
+		rule.ResourceNames = names
+	}
+}
+
+// resourceNameFunc returns the function redacting resourceNames of resources, or nil if
+// none of the resources is an identity or namespace
+func (r *Redactor) resourceNameFunc(resources []string) func(string) string {
+	kinds := map[string]func(string) string{
+		"users":           r.User,
+		"groups":          r.Group,
+		"serviceaccounts": r.ServiceAccount,
+		"namespaces":      r.Namespace,
+		"*":               r.name,
+	}
+	var kind string
+	var redact func(string) string
+	mixed := false
+	for i, resource := range resources {
+		resource, _, _ = strings.Cut(resource, "/")
+		if i > 0 && resource != kind {
+			mixed = true
+		}
+		kind = resource
+		if fn, ok := kinds[resource]; ok && redact == nil {
+			redact = fn
+		}
+	}
+	if mixed && redact != nil {
+		return r.name
+	}
+	return redact
+}
+
+// name returns the generic pseudonym of a name that cannot be attributed to one kind.
+// system: names keep their names.
+func (r *Redactor) name(name string) string {
+	if strings.HasPrefix(name, "system:") {
+		return name
+	}
+	return r.pseudonym("name", name)
+}
+
+// pseudonym returns kind-<hex>, from the HMAC of the kind and name, or "" if name is empty
+func (r *Redactor) pseudonym(kind, name string) string {
+	if name == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(kind + ":" + name))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
@@ -0,0 +1,201 @@
+package redact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNames(t *testing.T) {
+	r := New([]byte("key"), Options{})
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"namespace", r.Namespace, "team-a", r.pseudonym("ns", "team-a")},
+		{"system namespace", r.Namespace, "kube-system", "kube-system"},
+		{"kube- namespace", r.Namespace, "kube-node-lease", "kube-node-lease"},
+		{"empty namespace", r.Namespace, "", ""},
+		{"serviceaccount", r.ServiceAccount, "deployer", r.pseudonym("sa", "deployer")},
+		{"default serviceaccount", r.ServiceAccount, "default", "default"},
+		{"user", r.User, "alice@example.com", r.pseudonym("user", "alice@example.com")},
+		{"system user", r.User, "system:kube-scheduler", "system:kube-scheduler"},
+		{"serviceaccount user", r.User, "system:serviceaccount:team-a:deployer", "system:serviceaccount:" + r.pseudonym("ns", "team-a") + ":" + r.pseudonym("sa", "deployer")},
+		{"system serviceaccount user", r.User, "system:serviceaccount:kube-system:default", "system:serviceaccount:kube-system:default"},
+		{"node user", r.User, "system:node:worker-1", "system:node:" + r.pseudonym("node", "worker-1")},
+		{"group", r.Group, "devs", r.pseudonym("group", "devs")},
+		{"system group", r.Group, "system:authenticated", "system:authenticated"},
+		{"serviceaccount group", r.Group, "system:serviceaccounts:team-a", "system:serviceaccounts:" + r.pseudonym("ns", "team-a")},
+		{"role", r.Role, "payments-deployer", r.pseudonym("role", "payments-deployer")},
+		{"built-in role", r.Role, "cluster-admin", "cluster-admin"},
+		{"system role", r.Role, "system:controller:job-controller", "system:controller:job-controller"},
+		{"kubeadm role", r.Role, "kubeadm:get-nodes", "kubeadm:get-nodes"},
+		{"role with a system prefix", r.Role, "edit-payments", "edit-" + r.pseudonym("role", "edit-payments")},
+		{"binding", r.Binding, "alice-admin", r.pseudonym("binding", "alice-admin")},
+		{"binding with a system prefix", r.Binding, "admin-alice", "admin-" + r.pseudonym("binding", "admin-alice")},
+		{"empty binding", r.Binding, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("redacting %q = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPseudonym(t *testing.T) {
+	a, b := New([]byte("a"), Options{}), New([]byte("b"), Options{})
+	if got := a.User("alice"); got != a.User("alice") || !strings.HasPrefix(got, "user-") || len(got) != len("user-")+12 {
+		t.Errorf("User(alice) = %q, want a stable user-<12 hex digits> pseudonym", got)
+	}
+	if a.User("alice") == a.User("bob") {
+		t.Error("different names got the same pseudonym")
+	}
+	if a.User("alice") == b.User("alice") {
+		t.Error("different keys gave the same pseudonym")
+	}
+	if strings.TrimPrefix(a.Namespace("x"), "ns-") == strings.TrimPrefix(a.ServiceAccount("x"), "sa-") {
+		t.Error("the same name of different kinds got the same pseudonym")
+	}
+}
+
+func TestRules(t *testing.T) {
+	r := New([]byte("key"), Options{})
+	rules := []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"users"}, Verbs: []string{"impersonate"}, ResourceNames: []string{"alice"}},
+		{APIGroups: []string{""}, Resources: []string{"serviceaccounts", "serviceaccounts/token"}, Verbs: []string{"create"}, ResourceNames: []string{"deployer"}},
+		{APIGroups: []string{""}, Resources: []string{"users", "groups"}, Verbs: []string{"impersonate"}, ResourceNames: []string{"alice"}},
+		{APIGroups: []string{""}, Resources: []string{"users", "secrets"}, Verbs: []string{"impersonate", "get"}, ResourceNames: []string{"alice", "system:admin"}},
+		{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"impersonate"}, ResourceNames: []string{"alice"}},
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"settings"}},
+	}
+	original := rules[0].ResourceNames
+	r.rules(rules)
+
+	name := r.pseudonym("name", "alice")
+	want := [][]string{{r.User("alice")}, {r.ServiceAccount("deployer")}, {name}, {name, "system:admin"}, {name}, {"settings"}}
+	for i, rule := range rules {
+		if !reflect.DeepEqual(rule.ResourceNames, want[i]) {
+			t.Errorf("rule %d resourceNames = %q, want %q", i, rule.ResourceNames, want[i])
+		}
+	}
+	if original[0] != "alice" {
+		t.Error("rules() modified the original resourceNames slice")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	resources := types.RBACResources{
+		Metadata: types.Metadata{
+			ClusterName: "prod",
+			Server:      "https://prod.example.com",
+			FetchedBy:   &types.Identity{Username: "alice", UID: "1234", Groups: []string{"devs", "system:authenticated"}},
+			Namespaces:  []string{"team-a"},
+		},
+		Roles: []rbacv1.Role{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "secrets", Namespace: "team-a",
+				Annotations:   map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+			},
+			Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}}},
+		}},
+		ClusterRoles: []rbacv1.ClusterRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "wild", Labels: map[string]string{"owner": "alice"}},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			},
+			{
+				// The audit skips roles with an edit prefix, so the prefix is kept
+				ObjectMeta: metav1.ObjectMeta{Name: "edit-payments"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+				AggregationRule: &rbacv1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+					{MatchLabels: map[string]string{"aggregate-to-payments": "true"}},
+				}},
+			},
+		},
+		RoleBindings: []rbacv1.RoleBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "secrets", Namespace: "team-a"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secrets"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "deployer", Namespace: "team-a"}},
+		}},
+		ClusterRoleBindings: []rbacv1.ClusterRoleBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "wild"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "wild"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "devs"},
+				{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:authenticated"},
+			},
+		}},
+		ServiceAccounts: []types.ServiceAccount{{Namespace: "team-a", Name: "deployer"}},
+		Workloads:       []types.Workload{{Kind: "Deployment", Namespace: "team-a", Name: "api", ServiceAccount: "deployer"}},
+	}
+	original := types.RBACResources{ClusterRoles: []rbacv1.ClusterRole{*resources.ClusterRoles[0].DeepCopy()}}
+	before := audit.AuditRBACResourcesWithOptions(resources, audit.AuditOptions{})
+
+	r := New([]byte("key"), Options{})
+	r.Snapshot(&resources)
+
+	meta := resources.Metadata
+	if meta.ClusterName != r.pseudonym("cluster", "prod") || meta.Server != "" {
+		t.Errorf("metadata = %+v, want a pseudonymous cluster and no server", meta)
+	}
+	if meta.FetchedBy.Username != r.User("alice") || meta.FetchedBy.UID != "" || !reflect.DeepEqual(meta.FetchedBy.Groups, []string{r.Group("devs"), "system:authenticated"}) {
+		t.Errorf("FetchedBy = %+v, want pseudonyms without a UID", meta.FetchedBy)
+	}
+	ns := r.Namespace("team-a")
+	role := resources.Roles[0]
+	if role.Namespace != ns || role.Annotations != nil || role.ManagedFields != nil {
+		t.Errorf("Role metadata = %+v, want the namespace pseudonym without annotations or managedFields", role.ObjectMeta)
+	}
+	wantSubject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: r.ServiceAccount("deployer"), Namespace: ns}
+	if got := resources.RoleBindings[0].Subjects[0]; got != wantSubject {
+		t.Errorf("RoleBinding subject = %+v, want %+v", got, wantSubject)
+	}
+	if got := resources.ClusterRoleBindings[0].Subjects[1].Name; got != "system:authenticated" {
+		t.Errorf("system group redacted to %q", got)
+	}
+	if sa, w := resources.ServiceAccounts[0], resources.Workloads[0]; sa.Namespace != ns || sa.Name != wantSubject.Name || w.Namespace != ns || w.ServiceAccount != wantSubject.Name {
+		t.Errorf("ServiceAccount %+v and Workload %+v do not match the binding subject", sa, w)
+	}
+	if got := resources.Workloads[0].Name; got != r.pseudonym("workload", "api") {
+		t.Errorf("workload name = %q, want a pseudonym", got)
+	}
+
+	// Role and binding names are replaced, and role references still resolve
+	if rb := resources.RoleBindings[0]; role.Name != r.Role("secrets") || rb.Name != r.Binding("secrets") || rb.RoleRef.Name != role.Name {
+		t.Errorf("Role %q bound by %q to %q, want pseudonyms referring to the Role", role.Name, rb.Name, rb.RoleRef.Name)
+	}
+	if crb := resources.ClusterRoleBindings[0]; crb.RoleRef.Name != resources.ClusterRoles[0].Name || crb.Name == "wild" {
+		t.Errorf("ClusterRoleBinding %q refers to %q, want a pseudonym referring to ClusterRole %q", crb.Name, crb.RoleRef.Name, resources.ClusterRoles[0].Name)
+	}
+	if cr := resources.ClusterRoles[1]; !strings.HasPrefix(cr.Name, "edit-role-") || cr.AggregationRule != nil {
+		t.Errorf("ClusterRole %q with aggregation rule %+v, want an edit- pseudonym without label selectors", cr.Name, cr.AggregationRule)
+	}
+	if labels := resources.ClusterRoles[0].Labels; labels != nil {
+		t.Errorf("labels = %v, want them removed", labels)
+	}
+	New([]byte("key"), Options{KeepLabels: true}).Snapshot(&original)
+	if labels := original.ClusterRoles[0].Labels; !reflect.DeepEqual(labels, map[string]string{"owner": "alice"}) {
+		t.Errorf("labels with KeepLabels = %v, want them kept", labels)
+	}
+
+	// The redacted snapshot audits the same, apart from the names
+	after := audit.AuditRBACResourcesWithOptions(resources, audit.AuditOptions{})
+	if len(before.Findings) == 0 || len(after.Findings) != len(before.Findings) {
+		t.Fatalf("redacted snapshot has %d findings, want %d", len(after.Findings), len(before.Findings))
+	}
+	for i, f := range after.Findings {
+		if f.RuleID != before.Findings[i].RuleID || f.Risk != before.Findings[i].Risk {
+			t.Errorf("finding %d = %s %s, want %s %s", i, f.RuleID, f.Risk, before.Findings[i].RuleID, before.Findings[i].Risk)
+		}
+	}
+}
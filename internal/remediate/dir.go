@@ -0,0 +1,77 @@
+package remediate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"sigs.k8s.io/yaml"
+)
+
+// IndexFile lists the fixes in a fix directory
+const IndexFile = "fixes.yaml"
+
+// index is the content of IndexFile
+type index struct {
+	Fixes []Fix `json:"fixes"`
+}
+
+// WriteDir writes each fix to its own file in dir, along with an index of all fixes.
+// Manifests are written as YAML and JSON patches as JSON, so both can also be used with
+// kubectl replace and kubectl patch --type=json.
+func WriteDir(dir string, fixes []Fix) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create fix directory: %w", err)
+	}
+	for _, fix := range fixes {
+		var data []byte
+		var err error
+		if fix.Type == TypeJSONPatch {
+			data, err = json.MarshalIndent(fix.Patch, "", "  ")
+			data = append(data, '\n')
+		} else {
+			data, err = k8s.ManifestYAML(fix.Object)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode fix for %s %s: %w", fix.Kind, fix.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fix.File), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write fix: %w", err)
+		}
+	}
+
+	data, err := yaml.Marshal(index{Fixes: fixes})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, IndexFile), data, 0o644)
+}
+
+// ReadDir reads the fixes written by WriteDir, with the current content of their files
+func ReadDir(dir string) ([]Fix, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fix index: %w", err)
+	}
+	var idx index
+	if err := yaml.UnmarshalStrict(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", IndexFile, err)
+	}
+	for i, fix := range idx.Fixes {
+		if fix.Type != TypeManifest && fix.Type != TypeJSONPatch {
+			return nil, fmt.Errorf("%s: unknown fix type %q", fix.File, fix.Type)
+		}
+		if idx.Fixes[i].Data, err = os.ReadFile(filepath.Join(dir, fix.File)); err != nil {
+			return nil, fmt.Errorf("failed to read fix: %w", err)
+		}
+	}
+	return idx.Fixes, nil
+}
+
+// HasPlaceholder reports whether the fix still contains Placeholder values
+func (f Fix) HasPlaceholder() bool {
+	return bytes.Contains(f.Data, []byte(Placeholder))
+}
@@ -0,0 +1,284 @@
+package remediate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Fix types
+const (
+	// TypeManifest fixes replace the object with a full manifest
+	TypeManifest = "manifest"
+	// TypeJSONPatch fixes are RFC 6902 JSON patches applied to the live object
+	TypeJSONPatch = "json-patch"
+)
+
+// Placeholder stands in for values that RBACLens cannot know, such as the names of the secrets
+// a workload reads. Fixes containing it must be edited before they are applied.
+const Placeholder = "REPLACE-WITH-SECRET-NAME"
+
+// explicitVerbs replace the '*' verb. They are the standard resource verbs, leaving out
+// escalation verbs such as bind, escalate and impersonate.
+var explicitVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}
+
+// nonResourceVerbs replace the '*' verb on rules for nonResourceURLs, which take the HTTP
+// methods of the request as verbs
+var nonResourceVerbs = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+// readVerbs are the verbs that expose the contents of secrets
+var readVerbs = []string{"get", "list", "watch"}
+
+// Fix is a proposed change to a single RBAC object, covering all its fixable findings
+type Fix struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	// File is the name of the manifest or patch file, relative to the fix directory
+	File string `json:"file"`
+	// Checks are the IDs of the checks whose findings the fix addresses
+	Checks []string `json:"checks"`
+	// Changes describe the fix for review
+	Changes []string `json:"changes"`
+	// RequiresInput is set when the fix contains Placeholder values
+	RequiresInput bool `json:"requiresInput,omitempty"`
+
+	// Object is the replacement object of manifest fixes
+	Object any `json:"-"`
+	// Patch holds the operations of JSON patch fixes
+	Patch []PatchOperation `json:"-"`
+	// Data is the content of File, set when fixes are read back with ReadDir
+	Data []byte `json:"-"`
+}
+
+// PatchOperation is a single RFC 6902 JSON patch operation
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// fixable lists the checks that have automatic fixes
+var fixable = []string{"wildcard-permissions", "secrets-read", "unauthenticated-binding"}
+
+// Generate proposes fixes for the findings of a report against the snapshot it was produced
+// from. Findings without an automatic fix are returned separately.
+func Generate(resources types.RBACResources, findings []audit.AuditResult) ([]Fix, []audit.AuditResult) {
+	type objectKey struct{ kind, namespace, name string }
+	checks := map[objectKey][]string{}
+	var order []objectKey
+	var unfixed []audit.AuditResult
+	for _, f := range findings {
+		if !slices.Contains(fixable, f.RuleID) {
+			unfixed = append(unfixed, f)
+			continue
+		}
+		key := objectKey{f.ResourceKind, f.Namespace, f.ResourceName}
+		if _, ok := checks[key]; !ok {
+			order = append(order, key)
+		}
+		if !slices.Contains(checks[key], f.RuleID) {
+			checks[key] = append(checks[key], f.RuleID)
+		}
+	}
+
+	var fixes []Fix
+	for _, key := range order {
+		fix := Fix{Kind: key.kind, Namespace: key.namespace, Name: key.name, Checks: checks[key]}
+		ok := false
+		switch key.kind {
+		case "ClusterRole":
+			ok = fixClusterRole(resources, &fix)
+		case "Role":
+			ok = fixRole(resources, &fix)
+		case "ClusterRoleBinding", "RoleBinding":
+			ok = fixBinding(resources, &fix)
+		}
+		if ok {
+			fix.File = fileName(fix)
+			fixes = append(fixes, fix)
+		}
+	}
+	return fixes, unfixed
+}
+
+func fixClusterRole(resources types.RBACResources, fix *Fix) bool {
+	for _, cr := range resources.ClusterRoles {
+		if cr.Name != fix.Name {
+			continue
+		}
+		rules, changes, input := fixRules(cr.Rules, fix.Checks)
+		if len(changes) == 0 {
+			return false
+		}
+		fix.Type, fix.Changes, fix.RequiresInput = TypeManifest, changes, input
+		fix.Object = rbacv1.ClusterRole{
+			TypeMeta:        metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta:      objectMeta(cr.ObjectMeta),
+			Rules:           rules,
+			AggregationRule: cr.AggregationRule,
+		}
+		return true
+	}
+	return false
+}
+
+func fixRole(resources types.RBACResources, fix *Fix) bool {
+	for _, r := range resources.Roles {
+		if r.Name != fix.Name || r.Namespace != fix.Namespace {
+			continue
+		}
+		rules, changes, input := fixRules(r.Rules, fix.Checks)
+		if len(changes) == 0 {
+			return false
+		}
+		fix.Type, fix.Changes, fix.RequiresInput = TypeManifest, changes, input
+		fix.Object = rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+			ObjectMeta: objectMeta(r.ObjectMeta),
+			Rules:      rules,
+		}
+		return true
+	}
+	return false
+}
+
+// fixBinding removes system:unauthenticated subjects from a binding. Each removal is guarded
+// by a test operation, so the patch fails if the binding changed since the snapshot.
+func fixBinding(resources types.RBACResources, fix *Fix) bool {
+	if !slices.Contains(fix.Checks, "unauthenticated-binding") {
+		return false
+	}
+	var subjects []rbacv1.Subject
+	found := false
+	for _, crb := range resources.ClusterRoleBindings {
+		if fix.Kind == "ClusterRoleBinding" && crb.Name == fix.Name {
+			subjects, found = crb.Subjects, true
+		}
+	}
+	for _, rb := range resources.RoleBindings {
+		if fix.Kind == "RoleBinding" && rb.Name == fix.Name && rb.Namespace == fix.Namespace {
+			subjects, found = rb.Subjects, true
+		}
+	}
+	if !found {
+		return false
+	}
+
+	// Remove from the end, so earlier indices stay valid
+	for i := len(subjects) - 1; i >= 0; i-- {
+		s := subjects[i]
+		if s.Kind != "Group" || s.Name != "system:unauthenticated" {
+			continue
+		}
+		path := fmt.Sprintf("/subjects/%d", i)
+		fix.Patch = append(fix.Patch,
+			PatchOperation{Op: "test", Path: path + "/name", Value: s.Name},
+			PatchOperation{Op: "remove", Path: path},
+		)
+		fix.Changes = append(fix.Changes, fmt.Sprintf("subjects[%d]: removed group system:unauthenticated", i))
+	}
+	fix.Type = TypeJSONPatch
+	return len(fix.Patch) > 0
+}
+
+// fixRules rewrites the rules of a role for the given checks. It returns the new rules, a
+// description of each change and whether the rules contain placeholders. When no rule
+// changed there are no changes, even if some were flagged for review.
+//
+// Rules reading secrets, including those whose '*' verbs were just replaced, are always
+// restricted: the audit reports only the most severe check matching a rule, so such rules
+// may have a wildcard finding and no secrets-read finding.
+func fixRules(rules []rbacv1.PolicyRule, checks []string) ([]rbacv1.PolicyRule, []string, bool) {
+	var fixed []rbacv1.PolicyRule
+	var changes []string
+	changed, input := false, false
+	for i, rule := range rules {
+		rule = *rule.DeepCopy()
+
+		if slices.Contains(checks, "wildcard-permissions") && slices.Contains(rule.Verbs, rbacv1.VerbAll) {
+			verbs := explicitVerbs
+			if len(rule.NonResourceURLs) > 0 {
+				verbs = nonResourceVerbs
+			}
+			rule.Verbs = slices.Clone(verbs)
+			changed = true
+			changes = append(changes, fmt.Sprintf("rules[%d]: replaced '*' verbs with %s", i, strings.Join(verbs, ", ")))
+		}
+		if slices.Contains(checks, "wildcard-permissions") && slices.Contains(rule.Resources, rbacv1.ResourceAll) {
+			changes = append(changes, fmt.Sprintf("rules[%d]: '*' resources left unchanged, list the resources the role needs", i))
+		}
+
+		if readsSecrets(rule) {
+			split := splitSecrets(rule)
+			fixed = append(fixed, split...)
+			changes = append(changes, fmt.Sprintf("rules[%d]: restricted secret access to get on named secrets (edit %s)", i, Placeholder))
+			changed, input = true, true
+			continue
+		}
+		fixed = append(fixed, rule)
+	}
+	if !changed {
+		return rules, nil, false
+	}
+	return fixed, changes, input
+}
+
+// readsSecrets reports whether a rule reads any secret, without naming them
+func readsSecrets(rule rbacv1.PolicyRule) bool {
+	return slices.Contains(rule.Resources, "secrets") && len(rule.ResourceNames) == 0 &&
+		slices.ContainsFunc(rule.Verbs, func(v string) bool { return slices.Contains(readVerbs, v) })
+}
+
+// splitSecrets splits a rule that reads secrets into the rule for its other resources, a
+// rule for its other verbs on secrets and a get rule restricted to named secrets
+func splitSecrets(rule rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	if others := slices.DeleteFunc(slices.Clone(rule.Resources), func(r string) bool { return r == "secrets" }); len(others) > 0 {
+		rest := *rule.DeepCopy()
+		rest.Resources = others
+		rules = append(rules, rest)
+	}
+	if verbs := slices.DeleteFunc(slices.Clone(rule.Verbs), func(v string) bool { return slices.Contains(readVerbs, v) }); len(verbs) > 0 {
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: rule.APIGroups, Resources: []string{"secrets"}, Verbs: verbs})
+	}
+	return append(rules, rbacv1.PolicyRule{
+		APIGroups:     rule.APIGroups,
+		Resources:     []string{"secrets"},
+		Verbs:         []string{"get"},
+		ResourceNames: []string{Placeholder},
+	})
+}
+
+// objectMeta returns the metadata of an object for a replacement manifest. Labels,
+// annotations, owner references and finalizers are kept, as is the resourceVersion of the
+// snapshot, so applying the manifest fails if the object changed since. Fields managed by
+// the API server are dropped.
+func objectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	meta = *meta.DeepCopy()
+	meta.UID = ""
+	meta.Generation = 0
+	meta.CreationTimestamp = metav1.Time{}
+	meta.ManagedFields = nil
+	return meta
+}
+
+// fileName returns the file name of a fix, e.g. rolebinding-team-a-readers.patch.json
+func fileName(fix Fix) string {
+	parts := []string{strings.ToLower(fix.Kind)}
+	if fix.Namespace != "" {
+		parts = append(parts, fix.Namespace)
+	}
+	parts = append(parts, strings.ReplaceAll(fix.Name, ":", "-"))
+	name := strings.Join(parts, "-")
+	if fix.Type == TypeJSONPatch {
+		return name + ".patch.json"
+	}
+	return name + ".yaml"
+}
@@ -0,0 +1,166 @@
+package remediate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGenerate(t *testing.T) {
+	resources := types.RBACResources{
+		ClusterRoles: []rbacv1.ClusterRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "wild",
+					Labels:          map[string]string{"app": "demo"},
+					Annotations:     map[string]string{"rbac.authorization.kubernetes.io/autoupdate": "true"},
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "1234"}},
+					ResourceVersion: "42",
+					UID:             "5678",
+				},
+				Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"*"}}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "all-resources"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get"}}},
+			},
+		},
+		ClusterRoleBindings: []rbacv1.ClusterRoleBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "anon"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "wild"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.GroupKind, Name: "system:unauthenticated"},
+				{Kind: rbacv1.UserKind, Name: "alice"},
+			},
+		}},
+	}
+	findings := []audit.AuditResult{
+		{RuleID: "wildcard-permissions", ResourceKind: "ClusterRole", ResourceName: "wild"},
+		{RuleID: "wildcard-permissions", ResourceKind: "ClusterRole", ResourceName: "all-resources"},
+		{RuleID: "unauthenticated-binding", ResourceKind: "ClusterRoleBinding", ResourceName: "anon"},
+		{RuleID: "cluster-admin-binding", ResourceKind: "ClusterRoleBinding", ResourceName: "anon"},
+	}
+
+	fixes, unfixed := Generate(resources, findings)
+
+	if len(unfixed) != 1 || unfixed[0].RuleID != "cluster-admin-binding" {
+		t.Errorf("unfixed = %+v, want the cluster-admin-binding finding", unfixed)
+	}
+	// all-resources only has '*' resources, which are flagged but not changed
+	if len(fixes) != 2 {
+		t.Fatalf("got %d fixes, want 2: %+v", len(fixes), fixes)
+	}
+
+	role := fixes[0]
+	if role.Type != TypeManifest || role.File != "clusterrole-wild.yaml" {
+		t.Errorf("role fix = %s %s, want a manifest in clusterrole-wild.yaml", role.Type, role.File)
+	}
+	object, ok := role.Object.(rbacv1.ClusterRole)
+	if !ok {
+		t.Fatalf("Object is %T, want ClusterRole", role.Object)
+	}
+	if !reflect.DeepEqual(object.Rules[0].Verbs, explicitVerbs) {
+		t.Errorf("Verbs = %v, want %v", object.Rules[0].Verbs, explicitVerbs)
+	}
+	meta := resources.ClusterRoles[0].ObjectMeta
+	if !reflect.DeepEqual(object.Annotations, meta.Annotations) || !reflect.DeepEqual(object.Labels, meta.Labels) ||
+		!reflect.DeepEqual(object.OwnerReferences, meta.OwnerReferences) {
+		t.Errorf("metadata = %+v, want the labels, annotations and owner references of %+v", object.ObjectMeta, meta)
+	}
+	if object.ResourceVersion != "42" || object.UID != "" {
+		t.Errorf("ResourceVersion = %q, UID = %q, want the snapshot resourceVersion and no UID", object.ResourceVersion, object.UID)
+	}
+
+	binding := fixes[1]
+	want := []PatchOperation{
+		{Op: "test", Path: "/subjects/0/name", Value: "system:unauthenticated"},
+		{Op: "remove", Path: "/subjects/0"},
+	}
+	if binding.Type != TypeJSONPatch || !reflect.DeepEqual(binding.Patch, want) {
+		t.Errorf("binding fix = %s %+v, want %+v", binding.Type, binding.Patch, want)
+	}
+}
+
+func TestFixRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       []rbacv1.PolicyRule
+		checks      []string
+		want        []rbacv1.PolicyRule
+		wantChanges int
+		wantInput   bool
+	}{
+		{
+			name:   "wildcard verbs",
+			rules:  []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			checks: []string{"wildcard-permissions"},
+			want:   []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"*"}, Verbs: explicitVerbs}},
+			// The verbs are replaced and the resources flagged
+			wantChanges: 2,
+		},
+		{
+			name:   "only wildcard resources",
+			rules:  []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get"}}},
+			checks: []string{"wildcard-permissions"},
+			want:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get"}}},
+		},
+		{
+			name:   "secrets are split out",
+			rules:  []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets", "configmaps"}, Verbs: []string{"get", "list", "update"}}},
+			checks: []string{"secrets-read"},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list", "update"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"update"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}, ResourceNames: []string{Placeholder}},
+			},
+			wantChanges: 1,
+			wantInput:   true,
+		},
+		{
+			name:   "wildcard verbs on secrets",
+			rules:  []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}},
+			checks: []string{"wildcard-permissions"},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"create", "update", "patch", "delete", "deletecollection"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}, ResourceNames: []string{Placeholder}},
+			},
+			// The verbs are replaced and the reads restricted, without a secrets-read finding
+			wantChanges: 2,
+			wantInput:   true,
+		},
+		{
+			name:   "wildcard verbs on nonResourceURLs",
+			rules:  []rbacv1.PolicyRule{{NonResourceURLs: []string{"/metrics", "/healthz/*"}, Verbs: []string{"*"}}},
+			checks: []string{"wildcard-permissions"},
+			want: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/metrics", "/healthz/*"},
+				Verbs:           []string{"get", "post", "put", "patch", "delete", "head", "options"},
+			}},
+			wantChanges: 1,
+		},
+		{
+			name:   "named secrets are left alone",
+			rules:  []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}, ResourceNames: []string{"db"}}},
+			checks: []string{"secrets-read"},
+			want:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}, ResourceNames: []string{"db"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes, input := fixRules(tt.rules, tt.checks)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %+v, want %+v", got, tt.want)
+			}
+			if len(changes) != tt.wantChanges {
+				t.Errorf("changes = %q, want %d", changes, tt.wantChanges)
+			}
+			if input != tt.wantInput {
+				t.Errorf("input = %v, want %v", input, tt.wantInput)
+			}
+		})
+	}
+}
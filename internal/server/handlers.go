@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/diff"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+)
+
+var (
+	errNoSnapshots      = errors.New("no snapshots loaded yet")
+	errSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// SnapshotInfo describes a snapshot in the snapshot list
+type SnapshotInfo struct {
+	ID       string             `json:"id"`
+	Metadata types.Metadata     `json:"metadata"`
+	Summary  audit.AuditSummary `json:"summary"`
+}
+
+// WhoCanResponse lists the subjects allowed an access
+type WhoCanResponse struct {
+	Snapshot string        `json:"snapshot"`
+	Access   rbac.Access   `json:"access"`
+	Subjects []SubjectPath `json:"subjects"`
+}
+
+// SubjectPath is a subject and the grants that give it an access
+type SubjectPath struct {
+	Subject rbac.Subject `json:"subject"`
+	Grants  []rbac.Grant `json:"grants"`
+}
+
+// PermissionsResponse holds the effective permissions of a subject
+type PermissionsResponse struct {
+	Snapshot string              `json:"snapshot"`
+	Subject  rbac.Subject        `json:"subject"`
+	Groups   []string            `json:"groups,omitempty"`
+	Score    *audit.SubjectScore `json:"score,omitempty"`
+	Grants   []rbac.Grant        `json:"grants"`
+	// Findings are the findings on the bindings and roles the grants come from
+	Findings []audit.AuditResult `json:"findings"`
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /v1/snapshots", s.handleSnapshots)
+	mux.HandleFunc("GET /v1/audit", s.handleAudit)
+	mux.HandleFunc("GET /v1/who-can", s.handleWhoCan)
+	mux.HandleFunc("GET /v1/subjects/{id}/permissions", s.handlePermissions)
+	mux.HandleFunc("GET /v1/diff", s.handleDiff)
+	return mux
+}
+
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	infos := []SnapshotInfo{}
+	for _, snapshot := range s.Snapshots() {
+		infos = append(infos, SnapshotInfo{ID: snapshot.ID, Metadata: snapshot.Resources.Metadata, Summary: snapshot.Report.Summary})
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.snapshot(r.URL.Query().Get("snapshot"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot.Report)
+}
+
+// handleWhoCan answers which subjects may perform an access. verb and resource are required;
+// apiGroup, namespace and name narrow the access. Without a namespace, only cluster-wide
+// grants count.
+func (s *Server) handleWhoCan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	snapshot, err := s.snapshot(query.Get("snapshot"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	access := rbac.Access{
+		Verb:      query.Get("verb"),
+		APIGroup:  query.Get("apiGroup"),
+		Resource:  query.Get("resource"),
+		Namespace: query.Get("namespace"),
+		Name:      query.Get("name"),
+	}
+	if access.Verb == "" || access.Resource == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"verb and resource are required"})
+		return
+	}
+
+	response := WhoCanResponse{Snapshot: snapshot.ID, Access: access, Subjects: []SubjectPath{}}
+	for _, g := range snapshot.index.WhoCan(access) {
+		i := slices.IndexFunc(response.Subjects, func(p SubjectPath) bool { return p.Subject == g.Subject })
+		if i < 0 {
+			response.Subjects = append(response.Subjects, SubjectPath{Subject: g.Subject})
+			i = len(response.Subjects) - 1
+		}
+		response.Subjects[i].Grants = append(response.Subjects[i].Grants, g)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handlePermissions returns the effective permissions of a subject. The id is the subject in
+// Kind:name form, with the slash of ServiceAccount:namespace/name escaped as %2F. The groups
+// parameter adds the grants of groups the subject belongs to.
+func (s *Server) handlePermissions(w http.ResponseWriter, r *http.Request) {
+	subject, err := rbac.ParseSubject(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	snapshot, err := s.snapshot(r.URL.Query().Get("snapshot"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var groups []string
+	if g := r.URL.Query().Get("groups"); g != "" {
+		groups = strings.Split(g, ",")
+	}
+
+	response := PermissionsResponse{
+		Snapshot: snapshot.ID,
+		Subject:  subject,
+		Groups:   groups,
+		Grants:   snapshot.index.EffectiveGrants(subject, groups),
+		Findings: []audit.AuditResult{},
+	}
+	if response.Grants == nil {
+		response.Grants = []rbac.Grant{}
+	}
+	for _, score := range snapshot.Report.SubjectScores {
+		if score.Subject == subject {
+			response.Score = &score
+		}
+	}
+	for _, f := range snapshot.Report.Findings {
+		if slices.ContainsFunc(response.Grants, func(g rbac.Grant) bool { return grantFinding(g, f) }) {
+			response.Findings = append(response.Findings, f)
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// grantFinding reports whether a finding is about the binding or role of a grant
+func grantFinding(g rbac.Grant, f audit.AuditResult) bool {
+	b := g.Binding
+	if f.ResourceKind == b.Kind && f.Namespace == b.Namespace && f.ResourceName == b.Name {
+		return true
+	}
+	roleNamespace := b.Namespace
+	if b.RoleRef.Kind == "ClusterRole" {
+		roleNamespace = ""
+	}
+	return f.ResourceKind == b.RoleRef.Kind && f.Namespace == roleNamespace && f.ResourceName == b.RoleRef.Name
+}
+
+// handleDiff compares two snapshots. to defaults to the latest snapshot and from to the one
+// before to.
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	to, err := s.snapshot(query.Get("to"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var from *Snapshot
+	if id := query.Get("from"); id != "" {
+		if from, err = s.snapshot(id); err != nil {
+			writeError(w, err)
+			return
+		}
+	} else {
+		snapshots := s.Snapshots()
+		i := slices.Index(snapshots, to)
+		if i < 1 {
+			writeJSON(w, http.StatusNotFound, errorResponse{fmt.Sprintf("no snapshot before %s to compare with", to.ID)})
+			return
+		}
+		from = snapshots[i-1]
+	}
+	writeJSON(w, http.StatusOK, diff.Compare(from.Resources, from.Report, to.Resources, to.Report))
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes a snapshot lookup error with the matching status code
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errSnapshotNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errNoSnapshots):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, errorResponse{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/diff"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testSnapshot returns a snapshot where the ServiceAccount team-a/ci and the group devs can
+// read secrets in team-a and, if bindAlice is set, alice can do anything through the wild
+// ClusterRole
+func testSnapshot(timestamp time.Time, bindAlice bool) types.RBACResources {
+	resources := types.RBACResources{
+		Metadata: types.Metadata{Timestamp: timestamp},
+		ClusterRoles: []rbacv1.ClusterRole{{
+			ObjectMeta: metav1.ObjectMeta{Name: "wild"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		}},
+		Roles: []rbacv1.Role{{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader", Namespace: "team-a"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+		}},
+		RoleBindings: []rbacv1.RoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ci-secrets", Namespace: "team-a"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secret-reader"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "ci", Namespace: "team-a"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "devs-secrets", Namespace: "team-a"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secret-reader"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "devs"}},
+			},
+		},
+	}
+	if bindAlice {
+		resources.ClusterRoleBindings = []rbacv1.ClusterRoleBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice-wild"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "wild"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}},
+		}}
+	}
+	return resources
+}
+
+// newTestServer returns a server holding two snapshots, the later one binding alice
+func newTestServer() *Server {
+	s := New(audit.AuditOptions{})
+	start := time.Date(2025, 8, 21, 12, 0, 0, 0, time.UTC)
+	s.Add(testSnapshot(start, false), 0)
+	s.Add(testSnapshot(start.Add(time.Hour), true), 0)
+	return s
+}
+
+// get requests path from the handler and decodes the JSON response into v, if not nil
+func get(t *testing.T, handler http.Handler, path string, v any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if v != nil && recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s returned invalid JSON: %v", path, err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestStatusCodes(t *testing.T) {
+	handler := newTestServer().Handler()
+	empty := New(audit.AuditOptions{}).Handler()
+	tests := []struct {
+		name    string
+		handler http.Handler
+		path    string
+		want    int
+	}{
+		{"health", handler, "/healthz", http.StatusOK},
+		{"snapshots", handler, "/v1/snapshots", http.StatusOK},
+		{"no snapshots listed", empty, "/v1/snapshots", http.StatusOK},
+		{"latest audit", handler, "/v1/audit", http.StatusOK},
+		{"audit by ID", handler, "/v1/audit?snapshot=20250821T120000Z", http.StatusOK},
+		{"unknown snapshot", handler, "/v1/audit?snapshot=20200101T000000Z", http.StatusNotFound},
+		{"no snapshots loaded", empty, "/v1/audit", http.StatusServiceUnavailable},
+		{"who-can without resource", handler, "/v1/who-can?verb=get", http.StatusBadRequest},
+		{"who-can in unknown snapshot", handler, "/v1/who-can?verb=get&resource=secrets&snapshot=nope", http.StatusNotFound},
+		{"invalid subject", handler, "/v1/subjects/alice/permissions", http.StatusBadRequest},
+		{"permissions in unknown snapshot", handler, "/v1/subjects/User:alice/permissions?snapshot=nope", http.StatusNotFound},
+		{"diff with unknown from", handler, "/v1/diff?from=nope", http.StatusNotFound},
+		{"diff without an earlier snapshot", handler, "/v1/diff?to=20250821T120000Z", http.StatusNotFound},
+		{"diff without snapshots", empty, "/v1/diff", http.StatusServiceUnavailable},
+		{"wrong method", handler, "/v1/audit", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodGet
+			if tt.want == http.StatusMethodNotAllowed {
+				method = http.MethodPost
+			}
+			recorder := httptest.NewRecorder()
+			tt.handler.ServeHTTP(recorder, httptest.NewRequest(method, tt.path, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d: %s", method, tt.path, recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	var infos []SnapshotInfo
+	get(t, newTestServer().Handler(), "/v1/snapshots", &infos)
+	if len(infos) != 2 || infos[0].ID != "20250821T120000Z" || infos[1].ID != "20250821T130000Z" {
+		t.Fatalf("snapshots = %+v, want both snapshots oldest first", infos)
+	}
+	if infos[0].Summary.TotalRoleBindings != 2 || infos[1].Summary.TotalClusterRoleBindings != 1 {
+		t.Errorf("summaries = %+v, %+v, want the audit summary of each snapshot", infos[0].Summary, infos[1].Summary)
+	}
+}
+
+func TestWhoCan(t *testing.T) {
+	handler := newTestServer().Handler()
+
+	var response WhoCanResponse
+	if code := get(t, handler, "/v1/who-can?verb=get&resource=secrets&namespace=team-a", &response); code != http.StatusOK {
+		t.Fatalf("who-can = %d, want 200", code)
+	}
+	var subjects []string
+	for _, p := range response.Subjects {
+		subjects = append(subjects, p.Subject.String())
+		for _, g := range p.Grants {
+			if g.Subject != p.Subject {
+				t.Errorf("grant of %s listed under %s", g.Subject, p.Subject)
+			}
+		}
+	}
+	sort.Strings(subjects)
+	want := []string{"Group:devs", "ServiceAccount:team-a/ci", "User:alice"}
+	if !reflect.DeepEqual(subjects, want) {
+		t.Errorf("who-can subjects = %q, want %q", subjects, want)
+	}
+
+	// Without a namespace only cluster-wide grants count, and older snapshots can be queried
+	var first WhoCanResponse
+	get(t, handler, "/v1/who-can?verb=get&resource=secrets&snapshot=20250821T120000Z", &first)
+	if first.Snapshot != "20250821T120000Z" || first.Subjects == nil || len(first.Subjects) != 0 {
+		t.Errorf("cluster-wide who-can in the first snapshot = %+v, want no subjects", first)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	handler := newTestServer().Handler()
+
+	// The slash of a ServiceAccount is escaped in the path
+	var response PermissionsResponse
+	if code := get(t, handler, "/v1/subjects/ServiceAccount:team-a%2Fci/permissions", &response); code != http.StatusOK {
+		t.Fatalf("permissions = %d, want 200", code)
+	}
+	want := rbac.Subject{Kind: "ServiceAccount", Namespace: "team-a", Name: "ci"}
+	if response.Subject != want || len(response.Grants) != 1 || response.Grants[0].Binding.Name != "ci-secrets" {
+		t.Errorf("permissions = %+v, want ci's grant through ci-secrets", response)
+	}
+	if len(response.Findings) != 1 || response.Findings[0].RuleID != "secrets-read" {
+		t.Errorf("findings = %+v, want the secrets-read finding of the role", response.Findings)
+	}
+
+	// Groups add their grants
+	var bob PermissionsResponse
+	get(t, handler, "/v1/subjects/User:bob/permissions?groups=devs", &bob)
+	if len(bob.Grants) != 1 || bob.Grants[0].Binding.Name != "devs-secrets" || !reflect.DeepEqual(bob.Groups, []string{"devs"}) {
+		t.Errorf("permissions of bob in devs = %+v, want the grant of devs", bob)
+	}
+
+	// A subject without grants has empty lists, not nulls
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/subjects/User:nobody/permissions", nil))
+	var raw map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(raw["grants"], []any{}) || !reflect.DeepEqual(raw["findings"], []any{}) {
+		t.Errorf("permissions of nobody = %s, want empty grants and findings", recorder.Body)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	handler := newTestServer().Handler()
+
+	// Without parameters, the latest snapshot is compared with the one before it
+	var report diff.Report
+	if code := get(t, handler, "/v1/diff", &report); code != http.StatusOK {
+		t.Fatalf("diff = %d, want 200", code)
+	}
+	want := []diff.ObjectChange{{Kind: "ClusterRoleBinding", Name: "alice-wild", Change: diff.Added}}
+	if !reflect.DeepEqual(report.Objects, want) {
+		t.Errorf("diff objects = %+v, want %+v", report.Objects, want)
+	}
+	if report.Summary.SubjectsWidened != 1 {
+		t.Errorf("SubjectsWidened = %d, want 1", report.Summary.SubjectsWidened)
+	}
+
+	// Swapping the snapshots reverses the change
+	var reversed diff.Report
+	get(t, handler, "/v1/diff?from=20250821T130000Z&to=20250821T120000Z", &reversed)
+	if len(reversed.Objects) != 1 || reversed.Objects[0].Change != diff.Removed {
+		t.Errorf("reversed diff objects = %+v, want alice-wild removed", reversed.Objects)
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+)
+
+// Snapshot is an RBAC snapshot held by the server, with its audit report
+type Snapshot struct {
+	ID        string
+	Resources types.RBACResources
+	Report    audit.AuditReport
+	index     *rbac.Index
+}
+
+// Server answers API requests from the snapshots it holds. Snapshots are replaced or added
+// while the server runs, so all access goes through the mutex.
+type Server struct {
+	options audit.AuditOptions
+
+	mu        sync.RWMutex
+	snapshots []*Snapshot
+}
+
+// New returns a server that audits snapshots with the given options
+func New(options audit.AuditOptions) *Server {
+	return &Server{options: options}
+}
+
+// snapshotExtensions are the file extensions LoadDir reads
+var snapshotExtensions = []string{".json", ".yaml", ".yml"}
+
+// LoadDir replaces the snapshots of the server with the snapshot files in dir. Each file's
+// ID is its name without the extension. load reads a single file.
+func (s *Server) LoadDir(dir string, load func(path string) (*types.RBACResources, error)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !hasExtension(ext) {
+			continue
+		}
+		resources, err := load(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		snapshots = append(snapshots, s.newSnapshot(strings.TrimSuffix(entry.Name(), ext), *resources))
+	}
+	sortSnapshots(snapshots)
+
+	s.mu.Lock()
+	s.snapshots = snapshots
+	s.mu.Unlock()
+	return nil
+}
+
+// Add audits a snapshot and adds it as the latest, keeping at most keep snapshots. The ID is
+// the snapshot time, e.g. 20250821T120000Z.
+func (s *Server) Add(resources types.RBACResources, keep int) *Snapshot {
+	timestamp := resources.Metadata.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	snapshot := s.newSnapshot(timestamp.UTC().Format("20060102T150405Z"), resources)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, snapshot)
+	if keep > 0 && len(s.snapshots) > keep {
+		s.snapshots = s.snapshots[len(s.snapshots)-keep:]
+	}
+	return snapshot
+}
+
+func (s *Server) newSnapshot(id string, resources types.RBACResources) *Snapshot {
+	return &Snapshot{
+		ID:        id,
+		Resources: resources,
+		Report:    audit.AuditRBACResourcesWithOptions(resources, s.options),
+		index:     rbac.NewIndex(resources),
+	}
+}
+
+// Snapshots returns the snapshots of the server, oldest first
+func (s *Server) Snapshots() []*Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshots
+}
+
+// snapshot returns the snapshot with the given ID, or the latest when id is empty
+func (s *Server) snapshot(id string) (*Snapshot, error) {
+	snapshots := s.Snapshots()
+	if len(snapshots) == 0 {
+		return nil, errNoSnapshots
+	}
+	if id == "" {
+		return snapshots[len(snapshots)-1], nil
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.ID == id {
+			return snapshot, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errSnapshotNotFound, id)
+}
+
+func hasExtension(ext string) bool {
+	for _, e := range snapshotExtensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortSnapshots orders snapshots by their time, then by ID
+func sortSnapshots(snapshots []*Snapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		a, b := snapshots[i].Resources.Metadata.Timestamp, snapshots[j].Resources.Metadata.Timestamp
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+}
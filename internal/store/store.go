@@ -0,0 +1,174 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+)
+
+// IDFormat is the time layout of entry IDs, so IDs sort in time order
+const IDFormat = "20060102T150405Z"
+
+// Store is a directory of snapshots and audit reports, one entry per run:
+//
+//	<dir>/snapshots/<id>.json
+//	<dir>/reports/<id>.json
+//
+// The ID of an entry is the snapshot time, so a snapshot and its audit share an entry and
+// storing the same snapshot twice replaces it.
+type Store struct {
+	dir string
+}
+
+// Entry is a single run in the store. Report is nil if only the snapshot was stored.
+type Entry struct {
+	ID          string
+	Time        time.Time
+	HasSnapshot bool
+	Report      *audit.AuditReport
+}
+
+// Open opens the store in dir, creating the directory if needed
+func Open(dir string) (*Store, error) {
+	for _, sub := range []string{"snapshots", "reports"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create store: %w", err)
+		}
+	}
+	return &Store{dir: dir}, nil
+}
+
+// ID returns the entry ID for a snapshot time, or for now if t is zero
+func ID(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(IDFormat)
+}
+
+// AddSnapshot stores a snapshot and returns its entry ID
+func (s *Store) AddSnapshot(resources types.RBACResources) (string, error) {
+	id := ID(resources.Metadata.Timestamp)
+	return id, writeJSON(s.path("snapshots", id), resources)
+}
+
+// AddReport stores an audit report and returns its entry ID
+func (s *Store) AddReport(report audit.AuditReport) (string, error) {
+	id := ID(report.Metadata.Timestamp)
+	return id, writeJSON(s.path("reports", id), report)
+}
+
+// LoadSnapshot reads the snapshot of an entry
+func (s *Store) LoadSnapshot(id string) (*types.RBACResources, error) {
+	var resources types.RBACResources
+	if err := readJSON(s.path("snapshots", id), &resources); err != nil {
+		return nil, err
+	}
+	if err := resources.CheckSchemaVersion(); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", id, err)
+	}
+	return &resources, nil
+}
+
+// Entries returns the entries of the store, oldest first, with their reports loaded
+func (s *Store) Entries() ([]Entry, error) {
+	entries := map[string]*Entry{}
+	entry := func(id string) (*Entry, error) {
+		if entries[id] == nil {
+			t, err := time.Parse(IDFormat, id)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected file %s in store: %w", id, err)
+			}
+			entries[id] = &Entry{ID: id, Time: t}
+		}
+		return entries[id], nil
+	}
+
+	snapshots, err := s.ids("snapshots")
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range snapshots {
+		e, err := entry(id)
+		if err != nil {
+			return nil, err
+		}
+		e.HasSnapshot = true
+	}
+	reports, err := s.ids("reports")
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range reports {
+		e, err := entry(id)
+		if err != nil {
+			return nil, err
+		}
+		var report audit.AuditReport
+		if err := readJSON(s.path("reports", id), &report); err != nil {
+			return nil, err
+		}
+		e.Report = &report
+	}
+
+	list := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// ids lists the entry IDs of the files in a subdirectory of the store
+func (s *Store) ids(sub string) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	var ids []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(f.Name(), ".json"))
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) path(sub, id string) string {
+	return filepath.Join(s.dir, sub, id+".json")
+}
+
+// writeJSON writes v to path through a temporary file, so readers never see a partial file
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write to store: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Join(fmt.Errorf("failed to write to store: %w", err), os.Remove(tmp))
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read from store: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
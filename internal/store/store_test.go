@@ -0,0 +1,106 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStore(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history"))
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	first := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	snapshot := types.RBACResources{
+		SchemaVersion: types.SchemaVersion,
+		Metadata:      types.Metadata{Timestamp: first},
+		ClusterRoles:  []rbacv1.ClusterRole{{ObjectMeta: metav1.ObjectMeta{Name: "reader"}}},
+	}
+	id, err := s.AddSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("AddSnapshot() failed: %v", err)
+	}
+	if id != "20250801T120000Z" {
+		t.Errorf("AddSnapshot() ID = %q, want 20250801T120000Z", id)
+	}
+
+	// The audit of the snapshot shares its entry; a later report gets an entry of its own
+	findings := []audit.AuditResult{{RuleID: "wildcard-permissions", Risk: audit.RiskCritical, ResourceKind: "ClusterRole", ResourceName: "wild"}}
+	for _, ts := range []time.Time{first, second} {
+		report := audit.AuditReport{Findings: findings}
+		report.Metadata.Timestamp = ts
+		if _, err := s.AddReport(report); err != nil {
+			t.Fatalf("AddReport() failed: %v", err)
+		}
+	}
+
+	entries, err := s.Entries()
+	if err != nil {
+		t.Fatalf("Entries() failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Entries() returned %d entries, want 2", len(entries))
+	}
+	for i, want := range []struct {
+		id          string
+		time        time.Time
+		hasSnapshot bool
+	}{
+		{"20250801T120000Z", first, true},
+		{"20250802T120000Z", second, false},
+	} {
+		e := entries[i]
+		if e.ID != want.id || !e.Time.Equal(want.time) || e.HasSnapshot != want.hasSnapshot {
+			t.Errorf("entry %d = %s at %v with snapshot %v, want %s at %v with snapshot %v", i, e.ID, e.Time, e.HasSnapshot, want.id, want.time, want.hasSnapshot)
+		}
+		if e.Report == nil || !reflect.DeepEqual(e.Report.Findings, findings) {
+			t.Errorf("entry %d report = %+v, want the stored findings", i, e.Report)
+		}
+	}
+
+	loaded, err := s.LoadSnapshot(id)
+	if err != nil {
+		t.Fatalf("LoadSnapshot() failed: %v", err)
+	}
+	if len(loaded.ClusterRoles) != 1 || loaded.ClusterRoles[0].Name != "reader" {
+		t.Errorf("LoadSnapshot() = %+v, want the stored snapshot", loaded)
+	}
+}
+
+func TestLoadNewerSnapshot(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	id, err := s.AddSnapshot(types.RBACResources{SchemaVersion: types.SchemaVersion + 1})
+	if err != nil {
+		t.Fatalf("AddSnapshot() failed: %v", err)
+	}
+	if _, err := s.LoadSnapshot(id); err == nil {
+		t.Error("LoadSnapshot() of a newer schema succeeded, want an error")
+	}
+}
+
+func TestEntriesUnexpectedFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "reports", "latest.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Entries(); err == nil {
+		t.Error("Entries() with a file not named by ID succeeded, want an error")
+	}
+}
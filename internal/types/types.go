@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"time"
 
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -8,16 +9,62 @@ import (
 
 // Metadata holds metadata about the fetch
 type Metadata struct {
-	ClusterName string    `json:"clusterName"`
-	Timestamp   time.Time `json:"timestamp"`
-	Namespaces  []string  `json:"namespaces,omitempty"`
+	ClusterName     string    `json:"clusterName"`
+	ClusterID       string    `json:"clusterID,omitempty"`
+	Context         string    `json:"context,omitempty"`
+	Server          string    `json:"server,omitempty"`
+	ServerVersion   string    `json:"serverVersion,omitempty"`
+	FetchedBy       *Identity `json:"fetchedBy,omitempty"`
+	RBACLensVersion string    `json:"rbaclensVersion,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+	Namespaces      []string  `json:"namespaces,omitempty"`
 }
 
+// Identity describes the user that performed the fetch, as reported by the API server
+type Identity struct {
+	Username string   `json:"username"`
+	UID      string   `json:"uid,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// SchemaVersion is the version of the snapshot schema written by this version of RBACLens.
+// Snapshots without a schemaVersion predate it and are read as version 1.
+const SchemaVersion = 2
+
 // RBACResources holds all the RBAC resources.
 type RBACResources struct {
+	SchemaVersion       int                         `json:"schemaVersion,omitempty"`
 	Metadata            Metadata                    `json:"metadata"`
 	Roles               []rbacv1.Role               `json:"roles,omitempty"`
 	ClusterRoles        []rbacv1.ClusterRole        `json:"clusterRoles,omitempty"`
 	RoleBindings        []rbacv1.RoleBinding        `json:"roleBindings,omitempty"`
 	ClusterRoleBindings []rbacv1.ClusterRoleBinding `json:"clusterRoleBindings,omitempty"`
+	ServiceAccounts     []ServiceAccount            `json:"serviceAccounts,omitempty"`
+	Workloads           []Workload                  `json:"workloads,omitempty"`
+}
+
+// CheckSchemaVersion returns an error if the snapshot was written with a newer schema than this
+// version of RBACLens reads
+func (r *RBACResources) CheckSchemaVersion() error {
+	if r.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("snapshot schema version %d is newer than the supported version %d, upgrade RBACLens to read it", r.SchemaVersion, SchemaVersion)
+	}
+	return nil
+}
+
+// ServiceAccount holds the parts of a ServiceAccount relevant to RBAC analysis
+type ServiceAccount struct {
+	Namespace      string `json:"namespace"`
+	Name           string `json:"name"`
+	AutomountToken *bool  `json:"automountServiceAccountToken,omitempty"`
+}
+
+// Workload records which ServiceAccount a workload runs as. Pods are grouped by their
+// controlling owner, so a Deployment's ReplicaSet appears once regardless of replica count.
+type Workload struct {
+	Kind           string `json:"kind"`
+	Namespace      string `json:"namespace"`
+	Name           string `json:"name"`
+	ServiceAccount string `json:"serviceAccount"`
+	AutomountToken bool   `json:"automountServiceAccountToken"`
 }
@@ -0,0 +1,223 @@
+package usage
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namePrefix is prepended to the names of suggested roles and bindings
+const namePrefix = "rbaclens-"
+
+// Suggestion is a least-privilege replacement for a subject's current permissions, covering
+// exactly its observed usage
+type Suggestion struct {
+	Subject             rbac.Subject                `json:"subject"`
+	Requests            int                         `json:"requests"`
+	Roles               []rbacv1.Role               `json:"roles,omitempty"`
+	ClusterRoles        []rbacv1.ClusterRole        `json:"clusterRoles,omitempty"`
+	RoleBindings        []rbacv1.RoleBinding        `json:"roleBindings,omitempty"`
+	ClusterRoleBindings []rbacv1.ClusterRoleBinding `json:"clusterRoleBindings,omitempty"`
+	Comparison          Comparison                  `json:"comparison"`
+}
+
+// Comparison sets the suggestion against the subject's current effective permissions
+type Comparison struct {
+	// GrantedRules is the number of rules the subject currently holds, directly or through groups
+	GrantedRules int `json:"grantedRules"`
+	// SuggestedRules is the number of rules in the suggested roles
+	SuggestedRules int `json:"suggestedRules"`
+	// Unused are the currently held rules that no observed request needed
+	Unused []rbac.Grant `json:"unused,omitempty"`
+	// Uncovered are observed requests that no current rule allows, e.g. because they were
+	// authorized by another authorizer or the snapshot is older than the audit log
+	Uncovered []rbac.Access `json:"uncovered,omitempty"`
+}
+
+// Suggest builds the least-privilege roles and bindings for a subject's usage and compares
+// them with the permissions the subject holds in the index
+func Suggest(index *rbac.Index, u Usage) Suggestion {
+	s := Suggestion{Subject: u.Subject, Requests: u.Requests}
+
+	byNamespace := map[string][]rbac.Access{}
+	for _, a := range u.Accesses {
+		byNamespace[a.Namespace] = append(byNamespace[a.Namespace], a)
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	subject := bindingSubject(u.Subject)
+	roleRef := func(kind, name string) rbacv1.RoleRef {
+		return rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: kind, Name: name}
+	}
+	for _, ns := range namespaces {
+		rules := MinimalRules(byNamespace[ns])
+		s.Comparison.SuggestedRules += len(rules)
+		if ns == "" {
+			name := suggestedName(u.Subject, true)
+			s.ClusterRoles = append(s.ClusterRoles, rbacv1.ClusterRole{
+				TypeMeta:   typeMeta("ClusterRole"),
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Rules:      rules,
+			})
+			s.ClusterRoleBindings = append(s.ClusterRoleBindings, rbacv1.ClusterRoleBinding{
+				TypeMeta:   typeMeta("ClusterRoleBinding"),
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Subjects:   []rbacv1.Subject{subject},
+				RoleRef:    roleRef("ClusterRole", name),
+			})
+			continue
+		}
+		name := suggestedName(u.Subject, false)
+		s.Roles = append(s.Roles, rbacv1.Role{
+			TypeMeta:   typeMeta("Role"),
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Rules:      rules,
+		})
+		s.RoleBindings = append(s.RoleBindings, rbacv1.RoleBinding{
+			TypeMeta:   typeMeta("RoleBinding"),
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Subjects:   []rbacv1.Subject{subject},
+			RoleRef:    roleRef("Role", name),
+		})
+	}
+
+	grants := index.EffectiveGrants(u.Subject, u.Groups)
+	s.Comparison.GrantedRules = len(grants)
+	for _, g := range grants {
+		if !slices.ContainsFunc(u.Accesses, g.Allows) {
+			s.Comparison.Unused = append(s.Comparison.Unused, g)
+		}
+	}
+	for _, a := range u.Accesses {
+		if !slices.ContainsFunc(grants, func(g rbac.Grant) bool { return g.Allows(a) }) {
+			s.Comparison.Uncovered = append(s.Comparison.Uncovered, a)
+		}
+	}
+	return s
+}
+
+// MinimalRules returns the fewest policy rules that allow exactly the given accesses. Verbs
+// only used on named objects are restricted to those names with resourceNames, except create
+// and deletecollection, which RBAC cannot restrict by name.
+func MinimalRules(accesses []rbac.Access) []rbacv1.PolicyRule {
+	type resourceKey struct{ group, resource string }
+	type verbUse struct {
+		unrestricted bool
+		names        []string
+	}
+
+	uses := map[resourceKey]map[string]*verbUse{}
+	for _, a := range accesses {
+		key := resourceKey{a.APIGroup, a.Resource}
+		if uses[key] == nil {
+			uses[key] = map[string]*verbUse{}
+		}
+		use := uses[key][a.Verb]
+		if use == nil {
+			use = &verbUse{}
+			uses[key][a.Verb] = use
+		}
+		if name := a.AuthorizedName(); name == "" {
+			use.unrestricted = true
+		} else if !slices.Contains(use.names, name) {
+			use.names = append(use.names, name)
+		}
+	}
+
+	// Rules are merged when they differ only in their resources
+	type ruleKey struct{ group, verbs, names string }
+	merged := map[ruleKey]*rbacv1.PolicyRule{}
+	var order []ruleKey
+	add := func(group, resource string, verbs, names []string) {
+		sort.Strings(verbs)
+		sort.Strings(names)
+		key := ruleKey{group, strings.Join(verbs, ","), strings.Join(names, ",")}
+		if rule, ok := merged[key]; ok {
+			rule.Resources = append(rule.Resources, resource)
+			return
+		}
+		merged[key] = &rbacv1.PolicyRule{
+			APIGroups:     []string{group},
+			Resources:     []string{resource},
+			Verbs:         verbs,
+			ResourceNames: names,
+		}
+		order = append(order, key)
+	}
+
+	keys := make([]resourceKey, 0, len(uses))
+	for key := range uses {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].group+"/"+keys[i].resource < keys[j].group+"/"+keys[j].resource
+	})
+	for _, key := range keys {
+		var unrestricted []string
+		byNames := map[string][]string{}
+		var namesOrder []string
+		for verb, use := range uses[key] {
+			if use.unrestricted {
+				unrestricted = append(unrestricted, verb)
+				continue
+			}
+			sort.Strings(use.names)
+			names := strings.Join(use.names, ",")
+			if _, ok := byNames[names]; !ok {
+				namesOrder = append(namesOrder, names)
+			}
+			byNames[names] = append(byNames[names], verb)
+		}
+		if len(unrestricted) > 0 {
+			add(key.group, key.resource, unrestricted, nil)
+		}
+		sort.Strings(namesOrder)
+		for _, names := range namesOrder {
+			add(key.group, key.resource, byNames[names], strings.Split(names, ","))
+		}
+	}
+
+	rules := make([]rbacv1.PolicyRule, 0, len(order))
+	for _, key := range order {
+		rules = append(rules, *merged[key])
+	}
+	return rules
+}
+
+// bindingSubject returns the binding subject for a subject observed in an audit log
+func bindingSubject(s rbac.Subject) rbacv1.Subject {
+	if s.Kind == "ServiceAccount" {
+		return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: s.Name, Namespace: s.Namespace}
+	}
+	return rbacv1.Subject{Kind: s.Kind, APIGroup: rbacv1.GroupName, Name: s.Name}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// suggestedName derives a valid object name from a subject. ServiceAccount names are qualified
+// by their namespace for cluster-scoped objects.
+func suggestedName(s rbac.Subject, clusterScoped bool) string {
+	name := s.Name
+	if s.Kind == "ServiceAccount" && clusterScoped {
+		name = s.Namespace + "-" + s.Name
+	}
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	name = namePrefix + name
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return name
+}
+
+func typeMeta(kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: kind}
+}
@@ -0,0 +1,149 @@
+package usage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMinimalRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		accesses []rbac.Access
+		want     []rbacv1.PolicyRule
+	}{
+		{
+			name: "verbs on the same resource are merged",
+			accesses: []rbac.Access{
+				{Verb: "list", Resource: "pods"},
+				{Verb: "get", Resource: "pods"},
+			},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			name: "resources with the same verbs are merged",
+			accesses: []rbac.Access{
+				{Verb: "get", Resource: "pods"},
+				{Verb: "get", Resource: "services"},
+			},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name: "verbs only used on named objects are restricted to the names",
+			accesses: []rbac.Access{
+				{Verb: "get", Resource: "configmaps", Name: "b"},
+				{Verb: "get", Resource: "configmaps", Name: "a"},
+				{Verb: "list", Resource: "configmaps"},
+			},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"list"}},
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a", "b"}},
+			},
+		},
+		{
+			name: "a verb used with and without names is unrestricted",
+			accesses: []rbac.Access{
+				{Verb: "get", Resource: "configmaps", Name: "a"},
+				{Verb: "get", Resource: "configmaps"},
+			},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name: "create and deletecollection are not restricted by name",
+			accesses: []rbac.Access{
+				{Verb: "create", Resource: "configmaps", Name: "foo"},
+				{Verb: "deletecollection", Resource: "configmaps", Name: "foo"},
+			},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create", "deletecollection"}},
+			},
+		},
+		{
+			name: "create on a subresource of a named object is restricted by name",
+			accesses: []rbac.Access{
+				{Verb: "create", Resource: "pods/eviction", Name: "web-0"},
+			},
+			want: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods/eviction"}, Verbs: []string{"create"}, ResourceNames: []string{"web-0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MinimalRules(tt.accesses)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MinimalRules() = %+v, want %+v", got, tt.want)
+			}
+			for _, a := range tt.accesses {
+				if !allowedByAny(got, a) {
+					t.Errorf("MinimalRules() does not allow %+v", a)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	resources := types.RBACResources{
+		Roles: []rbacv1.Role{{
+			ObjectMeta: metav1.ObjectMeta{Name: "editor", Namespace: "team-a"},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+			},
+		}},
+		RoleBindings: []rbacv1.RoleBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "editor", Namespace: "team-a"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "editor"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "app", Namespace: "team-a"}},
+		}},
+	}
+	u := Usage{
+		Subject: rbac.Subject{Kind: "ServiceAccount", Name: "app", Namespace: "team-a"},
+		Accesses: []rbac.Access{
+			{Verb: "get", Resource: "configmaps", Namespace: "team-a"},
+			{Verb: "list", Resource: "pods", Namespace: "team-a"},
+		},
+		Requests: 2,
+	}
+
+	s := Suggest(rbac.NewIndex(resources), u)
+
+	if len(s.Roles) != 1 || s.Roles[0].Namespace != "team-a" || s.Roles[0].Name != "rbaclens-app" {
+		t.Fatalf("Roles = %+v, want one Role rbaclens-app in team-a", s.Roles)
+	}
+	if len(s.RoleBindings) != 1 || s.RoleBindings[0].Subjects[0].Name != "app" {
+		t.Errorf("RoleBindings = %+v, want one binding to app", s.RoleBindings)
+	}
+	if len(s.ClusterRoles) != 0 || len(s.ClusterRoleBindings) != 0 {
+		t.Errorf("suggested cluster-scoped objects for namespaced usage")
+	}
+	if s.Comparison.GrantedRules != 2 {
+		t.Errorf("GrantedRules = %d, want 2", s.Comparison.GrantedRules)
+	}
+	if len(s.Comparison.Unused) != 1 || s.Comparison.Unused[0].Rule.Resources[0] != "secrets" {
+		t.Errorf("Unused = %+v, want the secrets rule", s.Comparison.Unused)
+	}
+	if len(s.Comparison.Uncovered) != 1 || s.Comparison.Uncovered[0].Resource != "pods" {
+		t.Errorf("Uncovered = %+v, want the pods access", s.Comparison.Uncovered)
+	}
+}
+
+func allowedByAny(rules []rbacv1.PolicyRule, a rbac.Access) bool {
+	for _, rule := range rules {
+		if rbac.RuleAllows(rule, a) {
+			return true
+		}
+	}
+	return false
+}
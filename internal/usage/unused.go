@@ -0,0 +1,265 @@
+package usage
+
+import (
+	"slices"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/auditlog"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Usage statuses of bindings and rules
+const (
+	StatusUsed = "used"
+	// StatusPartial marks rules where only some verbs, resources or names were used, and
+	// bindings where some rules were not fully used
+	StatusPartial = "partial"
+	StatusUnused  = "unused"
+	// StatusMissingRole marks bindings whose role does not exist in the snapshot
+	StatusMissingRole = "missing-role"
+)
+
+// UnusedReport tells which bindings and rules were exercised in an audit log
+type UnusedReport struct {
+	Window auditlog.Window `json:"window"`
+	// First and Last are the times of the first and last request observed
+	First    time.Time      `json:"first,omitempty"`
+	Last     time.Time      `json:"last,omitempty"`
+	Requests int            `json:"requests"`
+	Subjects int            `json:"subjects"`
+	Bindings []BindingUsage `json:"bindings"`
+	Summary  UnusedSummary  `json:"summary"`
+}
+
+// UnusedSummary counts bindings by usage status
+type UnusedSummary struct {
+	Used        int `json:"used"`
+	Partial     int `json:"partial"`
+	Unused      int `json:"unused"`
+	MissingRole int `json:"missingRole"`
+}
+
+// BindingUsage is the observed usage of a single binding
+type BindingUsage struct {
+	Binding rbac.Binding `json:"binding"`
+	Status  string       `json:"status"`
+	// Subjects are the subjects seen using the binding
+	Subjects []rbac.Subject `json:"subjects,omitempty"`
+	Rules    []RuleUsage    `json:"rules,omitempty"`
+}
+
+// RuleUsage is the observed usage of a single rule of a binding's role
+type RuleUsage struct {
+	Rule   rbacv1.PolicyRule `json:"rule"`
+	Status string            `json:"status"`
+	// Accesses is the number of distinct requests the rule allowed
+	Accesses int `json:"accesses"`
+	// UnusedVerbs, UnusedResources and UnusedNames are the entries of the rule no request needed
+	UnusedVerbs     []string `json:"unusedVerbs,omitempty"`
+	UnusedResources []string `json:"unusedResources,omitempty"`
+	UnusedNames     []string `json:"unusedNames,omitempty"`
+}
+
+// FindUnused attributes the observed usage to the bindings of the index. The subject may hold
+// a binding directly or through a group it was seen with. When several bindings allow the same
+// requests, each subject's requests are credited to the fewest bindings that cover them, so a
+// binding whose permissions are all available through other bindings shows up as unused.
+// Bindings are skipped when skip returns true; they are still credited, so they can make
+// other bindings redundant.
+func FindUnused(index *rbac.Index, usages []Usage, window auditlog.Window, skip func(rbac.Binding) bool) UnusedReport {
+	report := UnusedReport{Window: window, Subjects: len(usages), Bindings: []BindingUsage{}}
+	for _, u := range usages {
+		report.Requests += u.Requests
+		if report.First.IsZero() || u.First.Before(report.First) {
+			report.First = u.First
+		}
+		if u.Last.After(report.Last) {
+			report.Last = u.Last
+		}
+	}
+
+	credited := creditAccesses(index, usages)
+	for _, b := range index.Bindings() {
+		if skip != nil && skip(b) {
+			continue
+		}
+		bu := bindingUsage(index, b, credited[b.String()])
+		switch bu.Status {
+		case StatusUsed:
+			report.Summary.Used++
+		case StatusPartial:
+			report.Summary.Partial++
+		case StatusUnused:
+			report.Summary.Unused++
+		case StatusMissingRole:
+			report.Summary.MissingRole++
+		}
+		report.Bindings = append(report.Bindings, bu)
+	}
+	return report
+}
+
+// credit is an access of a subject attributed to a binding
+type credit struct {
+	subject rbac.Subject
+	access  rbac.Access
+}
+
+// creditAccesses attributes every access of every subject to a single binding that allows it.
+// For each subject, bindings are picked greedily: the binding allowing the most accesses not
+// yet attributed comes first, ties going to the earlier binding of the index. The result is
+// keyed by the binding's String.
+func creditAccesses(index *rbac.Index, usages []Usage) map[string][]credit {
+	type candidate struct {
+		binding rbac.Binding
+		allowed []int
+	}
+
+	credited := map[string][]credit{}
+	for _, u := range usages {
+		var candidates []candidate
+		for _, b := range index.Bindings() {
+			rules, ok := index.Rules(b)
+			if !ok || !HoldsBinding(u, b) {
+				continue
+			}
+			c := candidate{binding: b}
+			for i, a := range u.Accesses {
+				if slices.ContainsFunc(rules, func(rule rbacv1.PolicyRule) bool {
+					return rbac.Grant{Binding: b, Namespace: b.Namespace, Rule: rule}.Allows(a)
+				}) {
+					c.allowed = append(c.allowed, i)
+				}
+			}
+			if len(c.allowed) > 0 {
+				candidates = append(candidates, c)
+			}
+		}
+
+		covered := make([]bool, len(u.Accesses))
+		for {
+			best, most := -1, 0
+			for i, c := range candidates {
+				n := 0
+				for _, j := range c.allowed {
+					if !covered[j] {
+						n++
+					}
+				}
+				if n > most {
+					best, most = i, n
+				}
+			}
+			if best < 0 {
+				break
+			}
+			key := candidates[best].binding.String()
+			for _, j := range candidates[best].allowed {
+				if !covered[j] {
+					covered[j] = true
+					credited[key] = append(credited[key], credit{subject: u.Subject, access: u.Accesses[j]})
+				}
+			}
+		}
+	}
+	return credited
+}
+
+// bindingUsage attributes the accesses credited to a binding to its rules
+func bindingUsage(index *rbac.Index, b rbac.Binding, credits []credit) BindingUsage {
+	bu := BindingUsage{Binding: b}
+	rules, ok := index.Rules(b)
+	if !ok {
+		bu.Status = StatusMissingRole
+		return bu
+	}
+	for _, c := range credits {
+		if !slices.Contains(bu.Subjects, c.subject) {
+			bu.Subjects = append(bu.Subjects, c.subject)
+		}
+	}
+
+	used, partial := 0, false
+	for _, rule := range rules {
+		grant := rbac.Grant{Binding: b, Namespace: b.Namespace, Rule: rule}
+		var accesses []rbac.Access
+		for _, c := range credits {
+			if grant.Allows(c.access) {
+				accesses = append(accesses, c.access)
+			}
+		}
+
+		ru := ruleUsage(rule, accesses)
+		if ru.Status != StatusUnused {
+			used++
+		}
+		if ru.Status != StatusUsed {
+			partial = true
+		}
+		bu.Rules = append(bu.Rules, ru)
+	}
+
+	switch {
+	case used == 0:
+		bu.Status = StatusUnused
+	case partial:
+		bu.Status = StatusPartial
+	default:
+		bu.Status = StatusUsed
+	}
+	return bu
+}
+
+// HoldsBinding reports whether a subject was seen with the permissions of a binding
+func HoldsBinding(u Usage, b rbac.Binding) bool {
+	for _, s := range b.Subjects {
+		subject := rbac.NewSubject(s, b.Namespace)
+		if subject == u.Subject || (subject.Kind == "Group" && slices.Contains(u.Groups, subject.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleUsage works out which entries of a rule the accesses it allowed needed. Wildcard rules
+// that were used are always partial, as no set of requests needs every verb or resource.
+func ruleUsage(rule rbacv1.PolicyRule, accesses []rbac.Access) RuleUsage {
+	ru := RuleUsage{Rule: rule, Accesses: len(accesses)}
+	if len(accesses) == 0 {
+		ru.Status = StatusUnused
+		return ru
+	}
+
+	var verbs, resources, names []string
+	for _, a := range accesses {
+		verbs = append(verbs, a.Verb)
+		for _, r := range rule.Resources {
+			if rbac.ResourceMatches(r, a.Resource) {
+				resources = append(resources, r)
+			}
+		}
+		names = append(names, a.AuthorizedName())
+	}
+	unused := func(entries, used []string) []string {
+		var out []string
+		for _, e := range entries {
+			if e != rbacv1.VerbAll && !slices.Contains(used, e) {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+	ru.UnusedVerbs = unused(rule.Verbs, verbs)
+	ru.UnusedResources = unused(rule.Resources, resources)
+	ru.UnusedNames = unused(rule.ResourceNames, names)
+
+	wildcard := slices.Contains(rule.Verbs, rbacv1.VerbAll) || slices.Contains(rule.Resources, rbacv1.ResourceAll) ||
+		slices.Contains(rule.APIGroups, rbacv1.APIGroupAll)
+	if wildcard || len(ru.UnusedVerbs) > 0 || len(ru.UnusedResources) > 0 || len(ru.UnusedNames) > 0 {
+		ru.Status = StatusPartial
+	} else {
+		ru.Status = StatusUsed
+	}
+	return ru
+}
@@ -0,0 +1,168 @@
+package usage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/auditlog"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindUnused(t *testing.T) {
+	clusterRole := func(name string, rules ...rbacv1.PolicyRule) rbacv1.ClusterRole {
+		return rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}, Rules: rules}
+	}
+	binding := func(name, role string, subjects ...rbacv1.Subject) rbacv1.ClusterRoleBinding {
+		return rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: role},
+			Subjects:   subjects,
+		}
+	}
+	alice := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}
+	devs := rbacv1.Subject{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "devs"}
+
+	resources := types.RBACResources{
+		ClusterRoles: []rbacv1.ClusterRole{
+			clusterRole("pod-reader", rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}),
+			clusterRole("pod-getter", rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}),
+			clusterRole("cm-editor", rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "update"}}),
+		},
+		ClusterRoleBindings: []rbacv1.ClusterRoleBinding{
+			// pod-getter comes first, but everything it allows is also allowed by pod-reader
+			binding("alice-getter", "pod-getter", alice),
+			binding("alice-reader", "pod-reader", alice),
+			binding("devs-cm", "cm-editor", devs),
+			binding("devs-gone", "gone", devs),
+		},
+		RoleBindings: []rbacv1.RoleBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice-cm", Namespace: "team-b"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cm-editor"},
+			Subjects:   []rbacv1.Subject{alice},
+		}},
+	}
+	usages := []Usage{{
+		Subject: rbac.Subject{Kind: "User", Name: "alice"},
+		Groups:  []string{"devs"},
+		Accesses: []rbac.Access{
+			{Verb: "get", Resource: "pods", Namespace: "team-a", Name: "web"},
+			{Verb: "list", Resource: "pods", Namespace: "team-a"},
+			{Verb: "get", Resource: "configmaps", Namespace: "team-a", Name: "settings"},
+		},
+		Requests: 3,
+	}}
+
+	report := FindUnused(rbac.NewIndex(resources), usages, auditlog.Window{}, nil)
+
+	status := map[string]string{}
+	for _, bu := range report.Bindings {
+		status[bu.Binding.String()] = bu.Status
+	}
+	want := map[string]string{
+		"ClusterRoleBinding/alice-getter": StatusUnused,
+		"ClusterRoleBinding/alice-reader": StatusUsed,
+		"ClusterRoleBinding/devs-cm":      StatusPartial,
+		"ClusterRoleBinding/devs-gone":    StatusMissingRole,
+		"RoleBinding/team-b/alice-cm":     StatusUnused,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("binding statuses = %v, want %v", status, want)
+	}
+	if report.Summary != (UnusedSummary{Used: 1, Partial: 1, Unused: 2, MissingRole: 1}) {
+		t.Errorf("Summary = %+v", report.Summary)
+	}
+
+	for _, bu := range report.Bindings {
+		if bu.Binding.Name != "devs-cm" {
+			continue
+		}
+		if !reflect.DeepEqual(bu.Subjects, []rbac.Subject{usages[0].Subject}) {
+			t.Errorf("devs-cm Subjects = %v, want alice through the devs group", bu.Subjects)
+		}
+		if got := bu.Rules[0].UnusedVerbs; !reflect.DeepEqual(got, []string{"update"}) {
+			t.Errorf("devs-cm UnusedVerbs = %v, want [update]", got)
+		}
+	}
+}
+
+func TestFindUnusedSkip(t *testing.T) {
+	resources := types.RBACResources{
+		ClusterRoles: []rbacv1.ClusterRole{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+		}},
+		ClusterRoleBindings: []rbacv1.ClusterRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "system:readers"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "pod-reader"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:authenticated"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "bob"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "pod-reader"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "bob"}},
+			},
+		},
+	}
+	usages := []Usage{{
+		Subject:  rbac.Subject{Kind: "User", Name: "bob"},
+		Groups:   []string{"system:authenticated"},
+		Accesses: []rbac.Access{{Verb: "get", Resource: "pods", Namespace: "default"}},
+		Requests: 1,
+	}}
+
+	// The skipped binding still takes the credit, so bob's own binding is redundant
+	report := FindUnused(rbac.NewIndex(resources), usages, auditlog.Window{}, func(b rbac.Binding) bool {
+		return b.Name == "system:readers"
+	})
+	if len(report.Bindings) != 1 || report.Bindings[0].Binding.Name != "bob" {
+		t.Fatalf("Bindings = %+v, want only bob", report.Bindings)
+	}
+	if report.Bindings[0].Status != StatusUnused {
+		t.Errorf("bob Status = %s, want %s", report.Bindings[0].Status, StatusUnused)
+	}
+}
+
+func TestRuleUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     rbacv1.PolicyRule
+		accesses []rbac.Access
+		want     RuleUsage
+	}{
+		{
+			name: "no accesses",
+			rule: rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			want: RuleUsage{Status: StatusUnused},
+		},
+		{
+			name:     "every entry used",
+			rule:     rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			accesses: []rbac.Access{{Verb: "get", Resource: "pods"}},
+			want:     RuleUsage{Status: StatusUsed, Accesses: 1},
+		},
+		{
+			name:     "unused verbs, resources and names",
+			rule:     rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets", "configmaps"}, Verbs: []string{"get", "list"}, ResourceNames: []string{"a", "b"}},
+			accesses: []rbac.Access{{Verb: "get", Resource: "secrets", Name: "a"}},
+			want:     RuleUsage{Status: StatusPartial, Accesses: 1, UnusedVerbs: []string{"list"}, UnusedResources: []string{"configmaps"}, UnusedNames: []string{"b"}},
+		},
+		{
+			name:     "wildcards are always partial",
+			rule:     rbacv1.PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			accesses: []rbac.Access{{Verb: "get", Resource: "pods"}},
+			want:     RuleUsage{Status: StatusPartial, Accesses: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Rule = tt.rule
+			if got := ruleUsage(tt.rule, tt.accesses); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ruleUsage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
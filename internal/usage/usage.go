@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/auditlog"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+)
+
+// Usage is the observed API usage of a single subject
+type Usage struct {
+	Subject rbac.Subject `json:"subject"`
+	// Groups are all groups the subject was seen with
+	Groups []string `json:"groups,omitempty"`
+	// Accesses are the distinct requests the subject made
+	Accesses []rbac.Access `json:"accesses"`
+	Requests int           `json:"requests"`
+	First    time.Time     `json:"first"`
+	Last     time.Time     `json:"last"`
+}
+
+// BySubject groups requests per subject, sorted by the subject's string form
+func BySubject(requests []auditlog.Request) []Usage {
+	bySubject := map[rbac.Subject]*Usage{}
+	seen := map[rbac.Subject]map[rbac.Access]bool{}
+	for _, r := range requests {
+		u, ok := bySubject[r.Subject]
+		if !ok {
+			u = &Usage{Subject: r.Subject, First: r.Time, Last: r.Time}
+			bySubject[r.Subject] = u
+			seen[r.Subject] = map[rbac.Access]bool{}
+		}
+		u.Requests++
+		if r.Time.Before(u.First) {
+			u.First = r.Time
+		}
+		if r.Time.After(u.Last) {
+			u.Last = r.Time
+		}
+		for _, g := range r.Groups {
+			if !slices.Contains(u.Groups, g) {
+				u.Groups = append(u.Groups, g)
+			}
+		}
+		if !seen[r.Subject][r.Access] {
+			seen[r.Subject][r.Access] = true
+			u.Accesses = append(u.Accesses, r.Access)
+		}
+	}
+
+	usages := make([]Usage, 0, len(bySubject))
+	for _, u := range bySubject {
+		sort.Strings(u.Groups)
+		sortAccesses(u.Accesses)
+		usages = append(usages, *u)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Subject.String() < usages[j].Subject.String()
+	})
+	return usages
+}
+
+// sortAccesses orders accesses by namespace, API group, resource, verb and name
+func sortAccesses(accesses []rbac.Access) {
+	sort.Slice(accesses, func(i, j int) bool {
+		a, b := accesses[i], accesses[j]
+		return strings.Join([]string{a.Namespace, a.APIGroup, a.Resource, a.Verb, a.Name}, "\x00") <
+			strings.Join([]string{b.Namespace, b.APIGroup, b.Resource, b.Verb, b.Name}, "\x00")
+	})
+}
@@ -0,0 +1,19 @@
+package version
+
+import "runtime/debug"
+
+// Version is the RBACLens release version. It is set at build time with
+// -ldflags "-X github.com/flushthemoney/RBACLens/internal/version.Version=v1.2.3".
+var Version = ""
+
+// Get returns the RBACLens version, falling back to the module version
+// recorded by `go install` and finally to "dev".
+func Get() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
@@ -0,0 +1,58 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// JSONLines returns an emit function writing each event to w as a line of JSON
+func JSONLines(w io.Writer) func(Event) error {
+	enc := json.NewEncoder(w)
+	return func(e Event) error {
+		return enc.Encode(e)
+	}
+}
+
+// webhookRetries are the delays before each retry of a failed webhook delivery
+var webhookRetries = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+
+// Webhook returns an emit function posting each event to url as JSON. Connection errors,
+// 429 and 5xx responses are retried with backoff; any other response than 2xx, or a failure
+// after the last retry, is an error.
+func Webhook(url string) func(Event) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(e Event) error {
+		body, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		retry, err := post(client, url, body)
+		for _, delay := range webhookRetries {
+			if !retry {
+				break
+			}
+			time.Sleep(delay)
+			retry, err = post(client, url, body)
+		}
+		return err
+	}
+}
+
+// post sends body to url once. It reports whether a failure is worth retrying.
+func post(client *http.Client, url string, body []byte) (bool, error) {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, nil
+}
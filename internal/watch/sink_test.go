@@ -0,0 +1,91 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	emit := JSONLines(&buf)
+	for _, name := range []string{"a", "b"} {
+		if err := emit(Event{Kind: "Role", Name: name}); err != nil {
+			t.Fatalf("emit() failed: %v", err)
+		}
+	}
+	want := `{"time":"0001-01-01T00:00:00Z","change":"","kind":"Role","name":"a","diff":""}` + "\n" +
+		`{"time":"0001-01-01T00:00:00Z","change":"","kind":"Role","name":"b","diff":""}` + "\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	retries := webhookRetries
+	webhookRetries = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { webhookRetries = retries }()
+
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantPosts int
+	}{
+		{"delivered", []int{http.StatusOK}, false, 1},
+		{"retried after a server error", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusAccepted}, false, 3},
+		{"client error is not retried", []int{http.StatusBadRequest}, true, 1},
+		{"retries exhausted", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts atomic.Int32
+			var received []Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(posts.Add(1))
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("request = %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+				}
+				var e Event
+				if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+					t.Errorf("decoding the body failed: %v", err)
+				}
+				received = append(received, e)
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			event := Event{Time: time.Date(2025, 8, 21, 12, 0, 0, 0, time.UTC), Change: "added", Kind: "Role", Namespace: "team-a", Name: "cm"}
+			err := Webhook(server.URL)(event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("emit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if int(posts.Load()) != tt.wantPosts {
+				t.Errorf("webhook received %d posts, want %d", posts.Load(), tt.wantPosts)
+			}
+			for _, e := range received {
+				if !reflect.DeepEqual(e, event) {
+					t.Errorf("webhook received %+v, want %+v", e, event)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookUnreachable(t *testing.T) {
+	retries := webhookRetries
+	webhookRetries = []time.Duration{time.Millisecond}
+	defer func() { webhookRetries = retries }()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	if err := Webhook(url)(Event{}); err == nil {
+		t.Error("emit() to a closed server succeeded, want an error")
+	}
+}
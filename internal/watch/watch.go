@@ -0,0 +1,432 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/diff"
+	"github.com/flushthemoney/RBACLens/internal/k8s"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+// Event reports a change to an RBAC object that introduced findings or widened the effective
+// permissions of a subject
+type Event struct {
+	Time time.Time `json:"time"`
+	// Change is added, modified or removed
+	Change    string `json:"change"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// ChangedBy is the field manager that last wrote the object, taken from its managedFields.
+	// It is empty for removed objects.
+	ChangedBy *Manager `json:"changedBy,omitempty"`
+	// Diff is a line diff of the object's rules, or role reference and subjects, as YAML
+	Diff string `json:"diff"`
+	// NewFindings are the findings the change introduced or made more severe
+	NewFindings []audit.AuditResult `json:"newFindings,omitempty"`
+	// Widened lists the subjects that gained rules, with the rules gained
+	Widened []diff.SubjectChange `json:"widened,omitempty"`
+}
+
+// Manager is an entry of an object's managedFields
+type Manager struct {
+	Manager   string     `json:"manager"`
+	Operation string     `json:"operation,omitempty"`
+	Time      *time.Time `json:"time,omitempty"`
+}
+
+// Change is a change to a Role, ClusterRole, RoleBinding or ClusterRoleBinding. Old is nil
+// for added objects and New is nil for removed ones.
+type Change struct {
+	Old any
+	New any
+}
+
+// Watcher tracks the RBAC objects of a cluster and reports changes that make it riskier
+type Watcher struct {
+	emit    func(Event) error
+	options audit.AuditOptions
+	auditor *audit.Auditor
+
+	resources types.RBACResources
+	report    audit.AuditReport
+	index     *rbac.Index
+
+	// undelivered are the events that could not be emitted yet, oldest first
+	undelivered []Event
+}
+
+// maxUndelivered is the number of undelivered events kept for retrying. Older events are
+// dropped beyond it.
+const maxUndelivered = 1000
+
+// New returns a watcher starting from resources. emit is called with each event.
+func New(resources types.RBACResources, options audit.AuditOptions, emit func(Event) error) *Watcher {
+	auditor := audit.NewAuditor(options)
+	return &Watcher{
+		emit:      emit,
+		options:   options,
+		auditor:   auditor,
+		resources: resources,
+		report:    auditor.Audit(resources, nil),
+		index:     rbac.NewIndex(resources),
+	}
+}
+
+// Apply records a batch of changes and emits an event for each changed object that
+// introduced findings or widened permissions. The batch is audited once, re-evaluating only
+// the changed objects and those bound to them. Successive changes to the same object are
+// merged into one. New findings and grants are attributed to the object they are on or,
+// when that object did not change, to the changed role or binding they depend on.
+func (w *Watcher) Apply(changes ...Change) error {
+	changes, err := coalesce(changes)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	resources := w.resources
+	var changed []audit.ObjectRef
+	for _, c := range changes {
+		if c.New == nil {
+			err = k8s.RemoveObject(&resources, c.Old)
+		} else {
+			err = k8s.SetObject(&resources, c.New)
+		}
+		if err != nil {
+			return err
+		}
+		changed = append(changed, audit.ObjectRefs(c.Old)...)
+		changed = append(changed, audit.ObjectRefs(c.New)...)
+	}
+	report := w.auditor.Audit(resources, changed)
+	index := rbac.NewIndex(resources)
+	introduced := diff.Introduced(w.report, report)
+	widened := w.widened(index)
+	w.resources, w.report, w.index = resources, report, index
+
+	now := time.Now()
+	events := make([]Event, len(changes))
+	owners := map[audit.ObjectRef]int{}
+	for i, c := range changes {
+		events[i] = newEvent(now, c)
+		owners[audit.ObjectRef{Kind: events[i].Kind, Namespace: events[i].Namespace, Name: events[i].Name}] = i
+	}
+	// owner returns the event of an object, or of the role or binding it depends on
+	owner := func(ref audit.ObjectRef) int {
+		if i, ok := owners[ref]; ok {
+			return i
+		}
+		for _, b := range index.Bindings() {
+			bindingRef := audit.ObjectRef{Kind: b.Kind, Namespace: b.Namespace, Name: b.Name}
+			role := roleRef(b)
+			if i, ok := owners[role]; ok && bindingRef == ref {
+				return i
+			}
+			if i, ok := owners[bindingRef]; ok && role == ref {
+				return i
+			}
+		}
+		return 0
+	}
+
+	for _, f := range introduced {
+		i := owner(audit.ObjectRef{Kind: f.ResourceKind, Namespace: f.Namespace, Name: f.ResourceName})
+		events[i].NewFindings = append(events[i].NewFindings, f)
+	}
+	for _, c := range widened {
+		for _, g := range c.Added {
+			e := &events[owner(audit.ObjectRef{Kind: g.Binding.Kind, Namespace: g.Binding.Namespace, Name: g.Binding.Name})]
+			j := slices.IndexFunc(e.Widened, func(sc diff.SubjectChange) bool { return sc.Subject == c.Subject })
+			if j < 0 {
+				e.Widened = append(e.Widened, diff.SubjectChange{Subject: c.Subject})
+				j = len(e.Widened) - 1
+			}
+			e.Widened[j].Added = append(e.Widened[j].Added, g)
+		}
+	}
+
+	var reportable []Event
+	for _, e := range events {
+		if len(e.NewFindings) > 0 || len(e.Widened) > 0 {
+			reportable = append(reportable, e)
+		}
+	}
+	return w.deliver(reportable)
+}
+
+// Retry emits the events that could not be delivered earlier
+func (w *Watcher) Retry() error {
+	return w.deliver(nil)
+}
+
+// Undelivered returns the number of events waiting to be retried
+func (w *Watcher) Undelivered() int {
+	return len(w.undelivered)
+}
+
+// deliver emits the undelivered events followed by events, in order. It stops at the first
+// event that cannot be emitted, as the sink is likely to reject the rest as well, and keeps it
+// and those after it for the next attempt.
+func (w *Watcher) deliver(events []Event) error {
+	queue := append(w.undelivered, events...)
+	for i, e := range queue {
+		if err := w.emit(e); err != nil {
+			queue = queue[i:]
+			var dropped int
+			if len(queue) > maxUndelivered {
+				dropped = len(queue) - maxUndelivered
+				queue = queue[dropped:]
+			}
+			w.undelivered = slices.Clone(queue)
+			err = fmt.Errorf("failed to emit event for %s %s, %d events queued: %w", e.Kind, e.Name, len(w.undelivered), err)
+			if dropped > 0 {
+				err = fmt.Errorf("%w, dropped %d oldest events", err, dropped)
+			}
+			return err
+		}
+	}
+	w.undelivered = nil
+	return nil
+}
+
+// widened returns the grants subjects gained between the watcher's index and index, leaving
+// out system subjects and bindings unless they are included in the audit
+func (w *Watcher) widened(index *rbac.Index) []diff.SubjectChange {
+	var widened []diff.SubjectChange
+	for _, c := range diff.CompareGrants(w.index, index) {
+		if !w.options.IncludeSystemComponents && audit.IsSystemSubject(c.Subject) {
+			continue
+		}
+		var added []rbac.Grant
+		for _, g := range c.Added {
+			if w.options.IncludeSystemComponents || !audit.IsSystemBinding(g.Binding) {
+				added = append(added, g)
+			}
+		}
+		if len(added) > 0 {
+			widened = append(widened, diff.SubjectChange{Subject: c.Subject, Added: added})
+		}
+	}
+	return widened
+}
+
+// coalesce merges the changes to each object into one, from its first old version to its
+// last new version, in the order the objects first changed. Objects that were added and
+// removed again are dropped.
+func coalesce(changes []Change) ([]Change, error) {
+	var merged []Change
+	seen := map[audit.ObjectRef]int{}
+	for _, c := range changes {
+		object := c.New
+		if object == nil {
+			object = c.Old
+		}
+		refs := audit.ObjectRefs(object)
+		if len(refs) == 0 {
+			return nil, fmt.Errorf("unsupported object type %T", object)
+		}
+		if i, ok := seen[refs[0]]; ok {
+			merged[i].New = c.New
+			continue
+		}
+		seen[refs[0]] = len(merged)
+		merged = append(merged, c)
+	}
+	return slices.DeleteFunc(merged, func(c Change) bool { return c.Old == nil && c.New == nil }), nil
+}
+
+// newEvent returns the event of a change, without findings or widened permissions
+func newEvent(now time.Time, c Change) Event {
+	event := Event{Time: now, Change: diff.Modified}
+	object := c.New
+	switch {
+	case c.Old == nil:
+		event.Change = diff.Added
+	case c.New == nil:
+		event.Change, object = diff.Removed, c.Old
+	}
+	meta := object.(metav1.Object)
+	event.Kind = kind(object)
+	event.Namespace, event.Name = meta.GetNamespace(), meta.GetName()
+	if c.New != nil {
+		event.ChangedBy = lastManager(meta)
+	}
+	event.Diff = diff.Lines(specYAML(c.Old), specYAML(c.New))
+	return event
+}
+
+// roleRef returns the role a binding refers to
+func roleRef(b rbac.Binding) audit.ObjectRef {
+	if b.RoleRef.Kind == "Role" {
+		return audit.ObjectRef{Kind: "Role", Namespace: b.Namespace, Name: b.RoleRef.Name}
+	}
+	return audit.ObjectRef{Kind: b.RoleRef.Kind, Name: b.RoleRef.Name}
+}
+
+// retryInterval is how often Run retries undelivered events when no changes arrive
+const retryInterval = time.Minute
+
+// Run watches the RBAC objects in the cache and emits events until ctx is done. It starts
+// the cache; objects already in the cluster are the starting point and produce no events.
+// Changes are queued without blocking the informers, and applied together once no change
+// has arrived for the debounce period. Events that cannot be emitted are logged and retried
+// with the next batch, or after retryInterval, so an unavailable sink does not stop the watch.
+func Run(ctx context.Context, rbacCache *k8s.RBACCache, options audit.AuditOptions, debounce time.Duration, emit func(Event) error) error {
+	var mu sync.Mutex
+	var pending []Change
+	notify := make(chan struct{}, 1)
+	queue := func(c Change) {
+		mu.Lock()
+		pending = append(pending, c)
+		mu.Unlock()
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+	err := rbacCache.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj any, isInInitialList bool) {
+			if !isInInitialList {
+				queue(Change{New: obj})
+			}
+		},
+		UpdateFunc: func(old, new any) {
+			if old.(metav1.Object).GetResourceVersion() != new.(metav1.Object).GetResourceVersion() {
+				queue(Change{Old: old, New: new})
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			queue(Change{Old: obj})
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := rbacCache.Start(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	watcher := New(rbacCache.Resources(), options, emit)
+	retry := time.NewTicker(retryInterval)
+	defer retry.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+			if !waitQuiet(ctx, notify, debounce) {
+				return nil
+			}
+			mu.Lock()
+			changes := pending
+			pending = nil
+			mu.Unlock()
+			if err := watcher.Apply(changes...); err != nil {
+				log.Printf("Applying changes failed: %v", err)
+			}
+		case <-retry.C:
+			if watcher.Undelivered() == 0 {
+				continue
+			}
+			if err := watcher.Retry(); err != nil {
+				log.Printf("Delivering events failed: %v", err)
+			}
+		}
+	}
+}
+
+// waitQuiet waits until nothing has arrived on notify for the debounce period. It returns
+// false if ctx is done first.
+func waitQuiet(ctx context.Context, notify <-chan struct{}, debounce time.Duration) bool {
+	timer := time.NewTimer(debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-notify:
+			timer.Reset(debounce)
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+func kind(object any) string {
+	switch object.(type) {
+	case *rbacv1.Role:
+		return "Role"
+	case *rbacv1.ClusterRole:
+		return "ClusterRole"
+	case *rbacv1.RoleBinding:
+		return "RoleBinding"
+	case *rbacv1.ClusterRoleBinding:
+		return "ClusterRoleBinding"
+	}
+	return ""
+}
+
+// specYAML returns the parts of an object that grant permissions as YAML, empty for nil
+func specYAML(object any) string {
+	var spec any
+	switch o := object.(type) {
+	case *rbacv1.Role:
+		spec = struct {
+			Rules []rbacv1.PolicyRule `json:"rules"`
+		}{o.Rules}
+	case *rbacv1.ClusterRole:
+		spec = struct {
+			Rules           []rbacv1.PolicyRule     `json:"rules"`
+			AggregationRule *rbacv1.AggregationRule `json:"aggregationRule,omitempty"`
+		}{o.Rules, o.AggregationRule}
+	case *rbacv1.RoleBinding:
+		spec = struct {
+			RoleRef  rbacv1.RoleRef   `json:"roleRef"`
+			Subjects []rbacv1.Subject `json:"subjects"`
+		}{o.RoleRef, o.Subjects}
+	case *rbacv1.ClusterRoleBinding:
+		spec = struct {
+			RoleRef  rbacv1.RoleRef   `json:"roleRef"`
+			Subjects []rbacv1.Subject `json:"subjects"`
+		}{o.RoleRef, o.Subjects}
+	default:
+		return ""
+	}
+	data, _ := yaml.Marshal(spec)
+	return string(data)
+}
+
+// lastManager returns the managedFields entry written most recently
+func lastManager(meta metav1.Object) *Manager {
+	var last *metav1.ManagedFieldsEntry
+	for i, entry := range meta.GetManagedFields() {
+		if last == nil || entry.Time != nil && (last.Time == nil || !entry.Time.Before(last.Time)) {
+			last = &meta.GetManagedFields()[i]
+		}
+	}
+	if last == nil {
+		return nil
+	}
+	manager := &Manager{Manager: last.Manager, Operation: string(last.Operation)}
+	if last.Time != nil {
+		manager.Time = &last.Time.Time
+	}
+	return manager
+}
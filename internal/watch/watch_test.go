@@ -0,0 +1,246 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/flushthemoney/RBACLens/internal/audit"
+	"github.com/flushthemoney/RBACLens/internal/diff"
+	"github.com/flushthemoney/RBACLens/internal/rbac"
+	"github.com/flushthemoney/RBACLens/internal/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	wildRole = &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "wild"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+	}
+	configMapRole = &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "team-a", ResourceVersion: "1"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+	}
+	bobBinding = &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "bob-cm", Namespace: "team-a"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "cm"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "bob"}},
+	}
+	everyoneBinding = &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "everyone-wild"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "wild"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:authenticated"}},
+	}
+)
+
+// withRule returns a copy of configMapRole with an extra rule and a new resourceVersion
+func withRule(rules ...rbacv1.PolicyRule) *rbacv1.Role {
+	role := configMapRole.DeepCopy()
+	role.ResourceVersion = "2"
+	role.Rules = append(role.Rules, rules...)
+	return role
+}
+
+// eventSummary is the part of an event the tests check
+type eventSummary struct {
+	Change, Kind, Name string
+	Findings           []string
+	Widened            map[string][]rbacv1.PolicyRule
+}
+
+func summarize(e Event) eventSummary {
+	s := eventSummary{Change: e.Change, Kind: e.Kind, Name: e.Name}
+	for _, f := range e.NewFindings {
+		s.Findings = append(s.Findings, f.RuleID+" "+f.ResourceKind+"/"+f.ResourceName+" "+string(f.Risk))
+	}
+	for _, c := range e.Widened {
+		if s.Widened == nil {
+			s.Widened = map[string][]rbacv1.PolicyRule{}
+		}
+		for _, g := range c.Added {
+			s.Widened[c.Subject.Name] = append(s.Widened[c.Subject.Name], g.Rule)
+		}
+	}
+	return s
+}
+
+func TestApply(t *testing.T) {
+	podRule := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}
+	tests := []struct {
+		name    string
+		changes []Change
+		want    []eventSummary
+	}{
+		{
+			name:    "new finding",
+			changes: []Change{{New: everyoneBinding}},
+			want: []eventSummary{{
+				Change: diff.Added, Kind: "ClusterRoleBinding", Name: "everyone-wild",
+				// The finding is on the unchanged ClusterRole, made critical by the binding
+				Findings: []string{"wildcard-permissions ClusterRole/wild critical"},
+				Widened:  map[string][]rbacv1.PolicyRule{"system:authenticated": wildRole.Rules},
+			}},
+		},
+		{
+			name:    "widened permission",
+			changes: []Change{{Old: configMapRole, New: withRule(podRule)}},
+			want: []eventSummary{{
+				Change: diff.Modified, Kind: "Role", Name: "cm",
+				Widened: map[string][]rbacv1.PolicyRule{"bob": {podRule}},
+			}},
+		},
+		{
+			name:    "no-op update",
+			changes: []Change{{Old: configMapRole, New: withRule()}},
+		},
+		{
+			name:    "removal",
+			changes: []Change{{Old: bobBinding}},
+		},
+		{
+			name:    "added and removed again",
+			changes: []Change{{New: everyoneBinding}, {Old: everyoneBinding}},
+		},
+		{
+			name: "changes to one object are merged",
+			changes: []Change{
+				{Old: configMapRole, New: withRule(podRule)},
+				{Old: withRule(podRule), New: withRule()},
+			},
+		},
+		{
+			name:    "batch",
+			changes: []Change{{New: everyoneBinding}, {Old: configMapRole, New: withRule(podRule)}},
+			want: []eventSummary{
+				{
+					Change: diff.Added, Kind: "ClusterRoleBinding", Name: "everyone-wild",
+					Findings: []string{"wildcard-permissions ClusterRole/wild critical"},
+					Widened:  map[string][]rbacv1.PolicyRule{"system:authenticated": wildRole.Rules},
+				},
+				{
+					Change: diff.Modified, Kind: "Role", Name: "cm",
+					Widened: map[string][]rbacv1.PolicyRule{"bob": {podRule}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := types.RBACResources{
+				ClusterRoles: []rbacv1.ClusterRole{*wildRole},
+				Roles:        []rbacv1.Role{*configMapRole},
+				RoleBindings: []rbacv1.RoleBinding{*bobBinding},
+			}
+			var got []eventSummary
+			w := New(resources, audit.AuditOptions{}, func(e Event) error {
+				got = append(got, summarize(e))
+				return nil
+			})
+			if err := w.Apply(tt.changes...); err != nil {
+				t.Fatalf("Apply() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEvent(t *testing.T) {
+	var events []Event
+	w := New(types.RBACResources{Roles: []rbacv1.Role{*configMapRole}, RoleBindings: []rbacv1.RoleBinding{*bobBinding}}, audit.AuditOptions{}, func(e Event) error {
+		events = append(events, e)
+		return nil
+	})
+	updated := withRule(rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}})
+	updated.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "helm", Operation: metav1.ManagedFieldsOperationUpdate},
+		{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{}},
+	}
+	if err := w.Apply(Change{Old: configMapRole, New: updated}); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Apply() emitted %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Namespace != "team-a" || e.ChangedBy == nil || e.ChangedBy.Manager != "kubectl-edit" {
+		t.Errorf("event = %+v, want team-a changed by kubectl-edit", e)
+	}
+	wantDiff := `  rules:
+  - apiGroups:
+    - ""
+    resources:
+    - configmaps
+    verbs:
+    - get
++ - apiGroups:
++   - ""
++   resources:
++   - pods
++   verbs:
++   - get
+`
+	if e.Diff != wantDiff {
+		t.Errorf("Diff = %q, want %q", e.Diff, wantDiff)
+	}
+	want := rbac.Subject{Kind: rbacv1.UserKind, Name: "bob"}
+	if len(e.Widened) != 1 || e.Widened[0].Subject != want || e.Widened[0].Added[0].Binding.Name != "bob-cm" {
+		t.Errorf("Widened = %+v, want bob through bob-cm", e.Widened)
+	}
+}
+
+func TestApplyUnsupportedObject(t *testing.T) {
+	w := New(types.RBACResources{}, audit.AuditOptions{}, func(Event) error { return nil })
+	if err := w.Apply(Change{New: &metav1.ObjectMeta{Name: "x"}}); err == nil {
+		t.Error("Apply() of an unsupported object succeeded, want an error")
+	}
+}
+
+func TestApplyUndelivered(t *testing.T) {
+	var delivered []string
+	failing := true
+	w := New(types.RBACResources{ClusterRoles: []rbacv1.ClusterRole{*wildRole}, Roles: []rbacv1.Role{*configMapRole}, RoleBindings: []rbacv1.RoleBinding{*bobBinding}}, audit.AuditOptions{}, func(e Event) error {
+		if failing {
+			return errors.New("webhook unavailable")
+		}
+		delivered = append(delivered, e.Name)
+		return nil
+	})
+
+	// The change is recorded even though its event cannot be delivered
+	if err := w.Apply(Change{New: everyoneBinding}); err == nil {
+		t.Fatal("Apply() with a failing sink succeeded, want an error")
+	}
+	if w.Undelivered() != 1 {
+		t.Fatalf("Undelivered() = %d, want 1", w.Undelivered())
+	}
+	if err := w.Retry(); err == nil || w.Undelivered() != 1 {
+		t.Fatalf("Retry() = %v with %d undelivered, want an error and the event kept", err, w.Undelivered())
+	}
+
+	// Queued events are delivered before those of the next change
+	failing = false
+	if err := w.Apply(Change{Old: configMapRole, New: withRule(rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}})}); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if want := []string{"everyone-wild", "cm"}; !reflect.DeepEqual(delivered, want) || w.Undelivered() != 0 {
+		t.Errorf("delivered %q with %d undelivered, want %q and none left", delivered, w.Undelivered(), want)
+	}
+}
+
+func TestDeliverDropsOldest(t *testing.T) {
+	w := New(types.RBACResources{}, audit.AuditOptions{}, func(Event) error { return errors.New("webhook unavailable") })
+	events := make([]Event, maxUndelivered+5)
+	for i := range events {
+		events[i].Name = fmt.Sprint(i)
+	}
+	if err := w.deliver(events); err == nil {
+		t.Fatal("deliver() with a failing sink succeeded, want an error")
+	}
+	if w.Undelivered() != maxUndelivered || w.undelivered[0].Name != "5" {
+		t.Errorf("kept %d events starting at %s, want %d starting at 5", w.Undelivered(), w.undelivered[0].Name, maxUndelivered)
+	}
+}